@@ -0,0 +1,234 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptItem is one entry of a comma-separated header with quality values,
+// such as Accept or Accept-Language.
+type acceptItem struct {
+	value  string
+	params map[string]string // parameters other than q, keys lowercased
+	q      float64
+}
+
+// parseAccept parses a header value like "fr-CH, fr;q=0.9, *;q=0.5" into
+// items ordered by descending quality. Items with equal quality keep their
+// header order. Malformed q values are treated as 1; entries with q=0 are
+// kept so callers can honor explicit exclusions.
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.TrimSpace(fields[0])
+		if value == "" {
+			continue
+		}
+		item := acceptItem{value: value, q: 1}
+		for _, f := range fields[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(f), "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			if k == "q" {
+				if qv, err := strconv.ParseFloat(v, 64); err == nil && qv >= 0 && qv <= 1 {
+					item.q = qv
+				}
+				continue
+			}
+			if item.params == nil {
+				item.params = make(map[string]string)
+			}
+			item.params[k] = v
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	return items
+}
+
+// PreferredLanguage returns the entry of supported that best matches the
+// request's Accept-Language header, honoring quality values. A range matches
+// a tag exactly or as a prefix ("en" matches "en-US"), and a regional range
+// falls back to its base language ("en-GB" matches "en"). Comparison is
+// case-insensitive. When nothing matches, or the header is absent, the first
+// supported tag is returned; with no supported tags it returns "".
+func (c *Context) PreferredLanguage(supported ...string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	items := parseAccept(c.R.Header.Get("Accept-Language"))
+	excluded := map[string]bool{}
+	for _, item := range items {
+		if item.q == 0 {
+			excluded[strings.ToLower(item.value)] = true
+		}
+	}
+	for _, item := range items {
+		if item.q == 0 {
+			continue
+		}
+		if item.value == "*" {
+			for _, tag := range supported {
+				if !excluded[strings.ToLower(tag)] {
+					return tag
+				}
+			}
+			continue
+		}
+		if tag, ok := matchLanguage(item.value, supported, excluded); ok {
+			return tag
+		}
+	}
+	return supported[0]
+}
+
+// matchLanguage finds the supported tag for a language range, preferring an
+// exact match, then a more specific tag, then the range's base language.
+func matchLanguage(lang string, supported []string, excluded map[string]bool) (string, bool) {
+	lang = strings.ToLower(lang)
+	candidates := []func(tag string) bool{
+		func(tag string) bool { return tag == lang },
+		func(tag string) bool { return strings.HasPrefix(tag, lang+"-") },
+	}
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		candidates = append(candidates, func(tag string) bool { return tag == base })
+	}
+	for _, match := range candidates {
+		for _, tag := range supported {
+			lt := strings.ToLower(tag)
+			if !excluded[lt] && match(lt) {
+				return tag, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Accepts returns the entry of offers that best matches the request's Accept
+// header, or "" when none is acceptable. Each offer takes the quality of the
+// most specific matching range (type/subtype, then type/*, then */*); ties
+// go to the earlier offer. With no Accept header the first offer is returned.
+// Malformed quality values are treated as 1.
+func (c *Context) Accepts(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	header := c.R.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	items := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := mediaQuality(strings.ToLower(offer), items); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// AcceptsCharset returns the entry of offers that best matches the request's
+// Accept-Charset header, or "" when none is acceptable. Charsets compare
+// case-insensitively, "*" matches any charset not listed explicitly, and
+// unlisted charsets are unacceptable; ties go to the earlier offer. With no
+// Accept-Charset header the first offer is returned.
+func (c *Context) AcceptsCharset(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	header := c.R.Header.Get("Accept-Charset")
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	items := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := charsetQuality(offer, items); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// charsetQuality returns the quality the Accept-Charset items assign to
+// offer: its explicit entry, else the "*" entry, else 0.
+func charsetQuality(offer string, items []acceptItem) float64 {
+	q := 0.0
+	for _, item := range items {
+		if strings.EqualFold(item.value, offer) {
+			return item.q
+		}
+		if item.value == "*" {
+			q = item.q
+		}
+	}
+	return q
+}
+
+// mediaQuality returns the quality the Accept items assign to offer, taken
+// from the most specific matching media range, or 0 when none matches.
+func mediaQuality(offer string, items []acceptItem) float64 {
+	typ, _, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for _, item := range items {
+		rng := strings.ToLower(item.value)
+		s := -1
+		switch {
+		case rng == offer:
+			s = 2
+		case rng == typ+"/*":
+			s = 1
+		case rng == "*/*" || rng == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = item.q, s
+		}
+	}
+	return q
+}
+
+// Negotiate writes v as JSON or XML, whichever the Accept header prefers.
+// For 4xx and 5xx codes a client preferring application/problem+json gets
+// the JSON body with that Content-Type. Responses are UTF-8, so an
+// Accept-Charset excluding utf-8 is also unacceptable. When nothing is
+// acceptable Negotiate writes JSON, or 406 Not Acceptable when
+// Router.StrictNegotiation is set.
+func (c *Context) Negotiate(code int, v any) {
+	offers := []string{"application/json", "application/xml"}
+	if code >= http.StatusBadRequest {
+		offers = append(offers, "application/problem+json")
+	}
+	media := c.Accepts(offers...)
+	if (media == "" || c.AcceptsCharset("utf-8") == "") && c.router != nil && c.router.StrictNegotiation {
+		c.Fail(http.StatusNotAcceptable, "not_acceptable", "no acceptable representation")
+		return
+	}
+	switch media {
+	case "application/xml":
+		c.XML(code, v)
+	case "application/problem+json":
+		c.writeJSON(code, v, "application/problem+json")
+	default:
+		c.JSON(code, v)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Accept-Language", func() {
+	preferred := func(header string, supported ...string) string {
+		r := q.New()
+		var got string
+		r.GET("/", func(c *q.Context) { got = c.PreferredLanguage(supported...); c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Accept-Language", header)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	It("honors quality values", func() {
+		Expect(preferred("fr;q=0.5, de;q=0.9, en;q=0.1", "en", "fr", "de")).To(Equal("de"))
+		Expect(preferred("es, fr;q=0.8", "en", "fr")).To(Equal("fr"))
+	})
+
+	It("matches regional tags and base languages case-insensitively", func() {
+		Expect(preferred("en", "fr", "en-US")).To(Equal("en-US"))
+		Expect(preferred("EN-gb", "fr", "en")).To(Equal("en"))
+		Expect(preferred("pt-BR, pt;q=0.8", "pt-PT", "pt-BR")).To(Equal("pt-BR"))
+	})
+
+	It("falls back to the first supported tag", func() {
+		Expect(preferred("", "en", "fr")).To(Equal("en"))
+		Expect(preferred("ja, zh;q=0.5", "en", "fr")).To(Equal("en"))
+		Expect(preferred("fr")).To(Equal(""))
+	})
+
+	It("handles wildcards and explicit exclusions", func() {
+		Expect(preferred("*", "de", "en")).To(Equal("de"))
+		Expect(preferred("de;q=0, *;q=0.5", "de", "en")).To(Equal("en"))
+	})
+
+	Describe("Accepts", func() {
+		accepts := func(header string, offers ...string) string {
+			var got string
+			r := q.New()
+			r.GET("/", func(c *q.Context) { got = c.Accepts(offers...) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if header != "" {
+				req.Header.Set("Accept", header)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			return got
+		}
+
+		It("returns the first offer when Accept is missing", func() {
+			Expect(accepts("", "application/json", "application/xml")).To(Equal("application/json"))
+		})
+
+		It("orders by q-value", func() {
+			Expect(accepts("application/json;q=0.5, application/xml", "application/json", "application/xml")).To(Equal("application/xml"))
+			Expect(accepts("application/xml;q=0.2, application/json;q=0.8", "application/xml", "application/json")).To(Equal("application/json"))
+		})
+
+		It("matches */* and type wildcards", func() {
+			Expect(accepts("*/*", "application/xml", "application/json")).To(Equal("application/xml"))
+			Expect(accepts("text/*, application/json;q=0.1", "application/json", "text/html")).To(Equal("text/html"))
+		})
+
+		It("prefers the most specific range for an offer", func() {
+			Expect(accepts("application/*;q=0.9, application/xml;q=0", "application/xml", "application/json")).To(Equal("application/json"))
+		})
+
+		It("returns empty when nothing matches", func() {
+			Expect(accepts("image/png", "application/json", "application/xml")).To(BeEmpty())
+		})
+
+		It("treats malformed q-values as 1", func() {
+			Expect(accepts("application/json;q=0.4, application/xml;q=abc", "application/json", "application/xml")).To(Equal("application/xml"))
+		})
+	})
+
+	Describe("Negotiate", func() {
+		type item struct {
+			Name string `json:"name" xml:"name"`
+		}
+		serve := func(accept string) *httptest.ResponseRecorder {
+			r := q.New()
+			r.GET("/", func(c *q.Context) { c.Negotiate(http.StatusOK, item{Name: "x"}) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("renders XML when preferred", func() {
+			rr := serve("application/xml, application/json;q=0.5")
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/xml"))
+			Expect(rr.Body.String()).To(ContainSubstring("<name>x</name>"))
+		})
+
+		It("defaults to JSON", func() {
+			Expect(serve("").Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			Expect(serve("image/png").Header().Get("Content-Type")).To(HavePrefix("application/json"))
+		})
+
+		Context("with status codes and strict mode", func() {
+			negotiate := func(strict bool, code int, headers map[string]string) *httptest.ResponseRecorder {
+				r := q.New()
+				r.StrictNegotiation = strict
+				r.GET("/", func(c *q.Context) { c.Negotiate(code, item{Name: "x"}) })
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				for k, v := range headers {
+					req.Header.Set(k, v)
+				}
+				rr := httptest.NewRecorder()
+				r.ServeHTTP(rr, req)
+				return rr
+			}
+			problem := map[string]string{"Accept": "application/problem+json, application/json;q=0.8"}
+
+			It("serves problem+json for errors when the client prefers it", func() {
+				rr := negotiate(false, http.StatusNotFound, problem)
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+				Expect(rr.Body.String()).To(MatchJSON(`{"name":"x"}`))
+
+				rr = negotiate(false, http.StatusNotFound, map[string]string{"Accept": "application/json, application/problem+json;q=0.5"})
+				Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			})
+
+			It("does not offer problem+json for successful responses", func() {
+				rr := negotiate(false, http.StatusOK, problem)
+				Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json;"))
+				rr = negotiate(true, http.StatusOK, map[string]string{"Accept": "application/problem+json"})
+				Expect(rr.Code).To(Equal(http.StatusNotAcceptable))
+			})
+
+			It("answers 406 in strict mode when no media type or charset is acceptable", func() {
+				rr := negotiate(true, http.StatusOK, map[string]string{"Accept": "image/png"})
+				Expect(rr.Code).To(Equal(http.StatusNotAcceptable))
+				Expect(rr.Body.String()).To(ContainSubstring(`"code":"not_acceptable"`))
+
+				rr = negotiate(true, http.StatusOK, map[string]string{"Accept-Charset": "iso-8859-1"})
+				Expect(rr.Code).To(Equal(http.StatusNotAcceptable))
+
+				rr = negotiate(true, http.StatusOK, map[string]string{"Accept": "application/xml", "Accept-Charset": "iso-8859-1, UTF-8;q=0.5"})
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/xml"))
+			})
+
+			It("falls back to JSON without strict mode", func() {
+				rr := negotiate(false, http.StatusOK, map[string]string{"Accept": "image/png", "Accept-Charset": "iso-8859-1"})
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			})
+		})
+	})
+
+	Describe("AcceptsCharset", func() {
+		acceptsCharset := func(header string, offers ...string) string {
+			var got string
+			r := q.New()
+			r.GET("/", func(c *q.Context) { got = c.AcceptsCharset(offers...); c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if header != "" {
+				req.Header.Set("Accept-Charset", header)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			return got
+		}
+
+		It("returns the first offer without a header", func() {
+			Expect(acceptsCharset("", "utf-8", "iso-8859-1")).To(Equal("utf-8"))
+		})
+
+		It("honors quality values case-insensitively", func() {
+			Expect(acceptsCharset("iso-8859-1;q=0.9, UTF-8", "iso-8859-1", "utf-8")).To(Equal("utf-8"))
+			Expect(acceptsCharset("utf-8;q=0.2, iso-8859-1", "utf-8", "iso-8859-1")).To(Equal("iso-8859-1"))
+		})
+
+		It("applies the wildcard to unlisted charsets only", func() {
+			Expect(acceptsCharset("*", "utf-8")).To(Equal("utf-8"))
+			Expect(acceptsCharset("utf-8;q=0, *", "utf-8", "utf-16")).To(Equal("utf-16"))
+			Expect(acceptsCharset("iso-8859-1", "utf-8")).To(BeEmpty())
+		})
+	})
+})
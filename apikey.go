@@ -0,0 +1,88 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"context"
+	"net/http"
+)
+
+// apiKeyIdentityKey is the context key for the identity resolved by APIKey.
+type apiKeyIdentityKey struct{}
+
+// APIKeyIdentity returns the identity resolved by the APIKey middleware, if
+// any.
+func APIKeyIdentity(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyIdentityKey{}).(string)
+	return id, ok
+}
+
+// APIKeyConfig configures the APIKey middleware.
+// Validator is required. The key is read from Header first, then from
+// QueryParam when set. If Optional is true, requests without a key pass
+// through unmodified; a key that is present but invalid is still rejected.
+type APIKeyConfig struct {
+	// Validator resolves a key to an identity. It should compare keys in
+	// constant time or look them up by hash.
+	Validator func(key string) (identity string, ok bool)
+
+	// Header is the request header carrying the key. Default: "X-API-Key".
+	Header string
+
+	// QueryParam, when set, is a query parameter consulted if the header is
+	// absent. Keys in URLs tend to end up in logs, so prefer the header.
+	QueryParam string
+
+	// Optional lets requests without a key through without an identity. A
+	// key that is present but invalid is still rejected. Default: false.
+	Optional bool
+}
+
+// APIKey creates a middleware that authenticates requests by API key and
+// stores the resolved identity in the request context (see APIKeyIdentity).
+// Missing or invalid keys respond 401. It panics if cfg.Validator is nil.
+func APIKey(cfg APIKeyConfig) Middleware {
+	if cfg.Validator == nil {
+		panic("quokka: APIKeyConfig.Validator is required")
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			key := c.R.Header.Get(cfg.Header)
+			if key == "" && cfg.QueryParam != "" {
+				key = c.Query(cfg.QueryParam)
+			}
+			if key == "" {
+				if cfg.Optional {
+					next(c)
+					return
+				}
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "missing API key"})
+				return
+			}
+			id, ok := cfg.Validator(key)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "invalid API key"})
+				return
+			}
+			c.R = c.R.WithContext(context.WithValue(c.R.Context(), apiKeyIdentityKey{}, id))
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("APIKey Middleware", func() {
+	keys := map[string]string{"k-123": "svc-billing"}
+	validator := func(key string) (string, bool) {
+		id, ok := keys[key]
+		return id, ok
+	}
+	newRouter := func(cfg q.APIKeyConfig) *q.Router {
+		cfg.Validator = validator
+		r := q.New()
+		r.Use(q.APIKey(cfg))
+		r.GET("/data", func(c *q.Context) {
+			id, ok := q.APIKeyIdentity(c.Context())
+			if !ok {
+				id = "anonymous"
+			}
+			c.Text(http.StatusOK, id)
+		})
+		return r
+	}
+	do := func(r *q.Router, target, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set("X-API-Key", header)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("accepts a valid key from the default header and exposes the identity", func() {
+		rr := do(newRouter(q.APIKeyConfig{}), "/data", "k-123")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("svc-billing"))
+	})
+
+	It("reads a custom header", func() {
+		r := newRouter(q.APIKeyConfig{Header: "Authorization-Key"})
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		req.Header.Set("Authorization-Key", "k-123")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("falls back to the query parameter when configured", func() {
+		r := newRouter(q.APIKeyConfig{QueryParam: "api_key"})
+		rr := do(r, "/data?api_key=k-123", "")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("svc-billing"))
+
+		Expect(do(newRouter(q.APIKeyConfig{}), "/data?api_key=k-123", "").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects an invalid key with 401", func() {
+		rr := do(newRouter(q.APIKeyConfig{}), "/data", "wrong")
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"unauthorized","message":"invalid API key"}`))
+	})
+
+	It("rejects a missing key with 401", func() {
+		rr := do(newRouter(q.APIKeyConfig{}), "/data", "")
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"unauthorized","message":"missing API key"}`))
+	})
+
+	It("passes anonymous requests through when Optional", func() {
+		r := newRouter(q.APIKeyConfig{Optional: true})
+		rr := do(r, "/data", "")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("anonymous"))
+
+		Expect(do(r, "/data", "wrong").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("panics at construction without a Validator", func() {
+		Expect(func() { q.APIKey(q.APIKeyConfig{}) }).To(PanicWith(ContainSubstring("Validator is required")))
+	})
+})
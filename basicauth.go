@@ -0,0 +1,85 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// basicAuthUserKey is the context key for the authenticated Basic username.
+type basicAuthUserKey struct{}
+
+// BasicAuthUser returns the username authenticated by the BasicAuth
+// middleware, if any.
+func BasicAuthUser(ctx context.Context) (string, bool) {
+	u, ok := ctx.Value(basicAuthUserKey{}).(string)
+	return u, ok
+}
+
+// BasicAuthConfig configures the BasicAuth middleware.
+// Provide a Validator, or Users for a fixed set of credentials; when both are
+// set the Validator is used. With neither, every request is rejected.
+type BasicAuthConfig struct {
+	// Validator reports whether user and pass are valid. Implementations
+	// should compare secrets in constant time (see subtle.ConstantTimeCompare).
+	Validator func(user, pass string) bool
+
+	// Users maps usernames to passwords. Passwords are compared in constant
+	// time.
+	Users map[string]string
+
+	// Realm is sent in the WWW-Authenticate challenge. Default: "Restricted".
+	Realm string
+}
+
+// BasicAuth creates a middleware that requires HTTP Basic credentials.
+// Failures respond 401 with a WWW-Authenticate challenge; on success the
+// username is stored in the request context (see BasicAuthUser).
+func BasicAuth(cfg BasicAuthConfig) Middleware {
+	if cfg.Realm == "" {
+		cfg.Realm = "Restricted"
+	}
+	validate := cfg.Validator
+	if validate == nil {
+		users := make(map[string][32]byte, len(cfg.Users))
+		for u, p := range cfg.Users {
+			users[u] = sha256.Sum256([]byte(p))
+		}
+		validate = func(user, pass string) bool {
+			want, ok := users[user]
+			got := sha256.Sum256([]byte(pass))
+			// Compare even for unknown users so timing does not reveal them.
+			return subtle.ConstantTimeCompare(got[:], want[:]) == 1 && ok
+		}
+	}
+	challenge := `Basic realm="` + escapeAuthParam(cfg.Realm) + `", charset="UTF-8"`
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			user, pass, ok := c.R.BasicAuth()
+			if !ok || !validate(user, pass) {
+				c.W.Header().Set("WWW-Authenticate", challenge)
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+				return
+			}
+			c.R = c.R.WithContext(context.WithValue(c.R.Context(), basicAuthUserKey{}, user))
+			next(c)
+		}
+	}
+}
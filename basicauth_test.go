@@ -0,0 +1,82 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("BasicAuth Middleware", func() {
+	newRouter := func(cfg q.BasicAuthConfig) *q.Router {
+		r := q.New()
+		r.Use(q.BasicAuth(cfg))
+		r.GET("/admin", func(c *q.Context) {
+			user, _ := q.BasicAuthUser(c.Context())
+			c.Text(http.StatusOK, "hello "+user)
+		})
+		return r
+	}
+	get := func(r *q.Router, user, pass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	users := q.BasicAuthConfig{Users: map[string]string{"admin": "s3cret"}}
+
+	It("passes valid credentials and exposes the username", func() {
+		rr := get(newRouter(users), "admin", "s3cret")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("hello admin"))
+	})
+
+	It("rejects a wrong password with 401", func() {
+		rr := get(newRouter(users), "admin", "nope")
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"unauthorized"}`))
+	})
+
+	It("rejects an unknown user with 401", func() {
+		Expect(get(newRouter(users), "root", "s3cret").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects a missing header with 401 and the realm challenge", func() {
+		rr := get(newRouter(users), "", "")
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Header().Get("WWW-Authenticate")).To(HavePrefix(`Basic realm="Restricted"`))
+
+		cfg := users
+		cfg.Realm = "Admin Area"
+		rr = get(newRouter(cfg), "", "")
+		Expect(rr.Header().Get("WWW-Authenticate")).To(HavePrefix(`Basic realm="Admin Area"`))
+	})
+
+	It("uses a custom Validator", func() {
+		r := newRouter(q.BasicAuthConfig{Validator: func(user, pass string) bool { return user == "ops" && pass == "pw" }})
+		Expect(get(r, "ops", "pw").Code).To(Equal(http.StatusOK))
+		Expect(get(r, "ops", "bad").Code).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -22,12 +22,13 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // BindQuery binds URL query parameters into a struct using `query` struct tags.
 // The destination must be a pointer to a struct.
 func (c *Context) BindQuery(dst any) error {
-	return bindValues(c.R.URL.Query(), dst, "query")
+	return bindValues(c.QueryParams(), dst, "query")
 }
 
 // BindForm parses the request form and binds values into a struct using `form`
@@ -52,21 +53,69 @@ func bindValues(vals url.Values, dst any, tagKey string) error {
 	rt := rv.Type()
 	for i := 0; i < rt.NumField(); i++ {
 		field := rt.Field(i)
-		tag := field.Tag.Get(tagKey)
+		tag, def, hasDef := parseBindTag(field.Tag.Get(tagKey))
 		if tag == "" || tag == "-" {
 			continue
 		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice {
+			in := vals[tag]
+			if len(in) == 0 && hasDef {
+				in = []string{def}
+			}
+			if err := setSlice(fv, in); err != nil {
+				return fmt.Errorf("quokka: field %s: %w", field.Name, err)
+			}
+			continue
+		}
 		val := vals.Get(tag)
+		if val == "" && hasDef {
+			val = def
+		}
 		if val == "" {
 			continue
 		}
-		if err := setField(rv.Field(i), val); err != nil {
+		if err := setField(fv, val); err != nil {
 			return fmt.Errorf("quokka: field %s: %w", field.Name, err)
 		}
 	}
 	return nil
 }
 
+// parseBindTag splits a binding tag such as `limit,default=20` into the key
+// and its optional default value. The default extends to the end of the tag,
+// so it may itself contain commas.
+func parseBindTag(tag string) (name, def string, hasDef bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	if i := strings.Index(opts, "default="); i >= 0 {
+		return name, opts[i+len("default="):], true
+	}
+	return name, "", false
+}
+
+// setSlice fills a slice field with one element per non-empty value, parsing
+// each with setField. The field is left untouched when no values are present.
+func setSlice(fv reflect.Value, vals []string) error {
+	if !fv.CanSet() {
+		return nil
+	}
+	out := reflect.MakeSlice(fv.Type(), 0, len(vals))
+	for _, val := range vals {
+		if val == "" {
+			continue
+		}
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if err := setField(elem, val); err != nil {
+			return err
+		}
+		out = reflect.Append(out, elem)
+	}
+	if out.Len() > 0 {
+		fv.Set(out)
+	}
+	return nil
+}
+
 func setField(fv reflect.Value, val string) error {
 	if !fv.CanSet() {
 		return nil
@@ -174,4 +174,158 @@ var _ = Describe("BindQuery and BindForm", func() {
 		Expect(rr.Code).To(Equal(http.StatusOK))
 		Expect(rr.Body.String()).To(Equal("hi|"))
 	})
+
+	It("parses the query once and shares it between Query and BindQuery", func() {
+		type params struct {
+			Name string `query:"name"`
+		}
+		r := q.New()
+		r.GET("/", func(c *q.Context) {
+			first := c.QueryParams()
+			first.Set("name", "cached")
+			var p params
+			Expect(c.BindQuery(&p)).To(Succeed())
+			c.Text(http.StatusOK, c.Query("name")+"|"+p.Name)
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?name=orig", nil))
+		Expect(rr.Body.String()).To(Equal("cached|cached"))
+	})
+
+	It("rejects requests exceeding MaxQueryParams with 400", func() {
+		r := q.New()
+		r.MaxQueryParams = 3
+		called := false
+		r.GET("/", func(c *q.Context) { called = true; c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?a=1&b=2&c=3", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+
+		called = false
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?a=1&b=2&c=3&d=4", nil))
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		Expect(called).To(BeFalse())
+	})
+
+	It("does not limit query parameters by default", func() {
+		r := q.New()
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusOK) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?"+strings.Repeat("a=1&", 1000)+"b=2", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("counts query parameters as url.ParseQuery stores them", func() {
+		r := q.New()
+		r.MaxQueryParams = 2
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		for target, want := range map[string]int{
+			"/?a=1&&&b=2&":      http.StatusOK,
+			"/?a=1&b=2&c;d=3":   http.StatusOK,
+			"/?a=1&b=2&c":       http.StatusBadRequest,
+			"/?&a=1&&b=2&&c=3&": http.StatusBadRequest,
+		} {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+			Expect(rr.Code).To(Equal(want), target)
+		}
+	})
+
+	It("binds repeated query keys into slice fields", func() {
+		type filters struct {
+			Tags  []string `query:"tag"`
+			IDs   []int    `query:"id"`
+			Flags []bool   `query:"flag"`
+		}
+		var f filters
+		r := q.New()
+		r.GET("/", func(c *q.Context) {
+			Expect(c.BindQuery(&f)).To(Succeed())
+			c.Status(http.StatusOK)
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?tag=a&tag=b&id=1&id=2&id=3&flag=true&flag=false", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(f.Tags).To(Equal([]string{"a", "b"}))
+		Expect(f.IDs).To(Equal([]int{1, 2, 3}))
+		Expect(f.Flags).To(Equal([]bool{true, false}))
+	})
+
+	It("binds a mixed struct of slice and scalar fields", func() {
+		type form struct {
+			Name  string   `form:"name"`
+			Age   int      `form:"age"`
+			Roles []string `form:"role"`
+			Empty []string `form:"none"`
+		}
+		var f form
+		r := q.New()
+		r.POST("/", func(c *q.Context) {
+			Expect(c.BindForm(&f)).To(Succeed())
+			c.Status(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=ann&name=bob&age=30&role=admin&role=dev"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(f.Name).To(Equal("ann"))
+		Expect(f.Age).To(Equal(30))
+		Expect(f.Roles).To(Equal([]string{"admin", "dev"}))
+		Expect(f.Empty).To(BeNil())
+	})
+
+	It("returns an error for an unparsable slice element", func() {
+		type filters struct {
+			IDs []int `query:"id"`
+		}
+		var bindErr error
+		r := q.New()
+		r.GET("/", func(c *q.Context) {
+			var f filters
+			bindErr = c.BindQuery(&f)
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?id=1&id=x", nil))
+		Expect(bindErr).To(MatchError(ContainSubstring("field IDs")))
+	})
+
+	Describe("default tag option", func() {
+		type page struct {
+			Limit  int      `query:"limit,default=20"`
+			Sort   string   `query:"sort,default=name,asc"`
+			Offset int      `query:"offset"`
+			Tags   []string `query:"tag,default=all"`
+		}
+		bind := func(target string) page {
+			var p page
+			r := q.New()
+			r.GET("/", func(c *q.Context) {
+				Expect(c.BindQuery(&p)).To(Succeed())
+				c.Status(http.StatusOK)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+			return p
+		}
+
+		It("uses the request value when present", func() {
+			p := bind("/?limit=5&sort=date&offset=10&tag=x&tag=y")
+			Expect(p).To(Equal(page{Limit: 5, Sort: "date", Offset: 10, Tags: []string{"x", "y"}}))
+		})
+
+		It("applies declared defaults when values are absent", func() {
+			p := bind("/")
+			Expect(p.Limit).To(Equal(20))
+			Expect(p.Sort).To(Equal("name,asc"))
+			Expect(p.Tags).To(Equal([]string{"all"}))
+		})
+
+		It("leaves fields without a default at their zero value", func() {
+			p := bind("/?limit=")
+			Expect(p.Limit).To(Equal(20))
+			Expect(p.Offset).To(Equal(0))
+		})
+	})
 })
@@ -16,7 +16,11 @@
 
 package quokka
 
-import "net/http"
+import (
+	"errors"
+	"io"
+	"net/http"
+)
 
 // BodyLimit creates a middleware that restricts the maximum size of the request
 // body. If the client sends more than maxBytes, subsequent reads from the body
@@ -34,3 +38,69 @@ func BodyLimit(maxBytes int64) Middleware {
 		}
 	}
 }
+
+// errContentLengthMismatch is returned from a body read when the bytes
+// received disagree with the declared Content-Length.
+var errContentLengthMismatch = errors.New("quokka: body length does not match Content-Length")
+
+// ContentLengthCheck creates a middleware that verifies, as the body is read,
+// that a request with a declared Content-Length carries exactly that many
+// bytes. A truncated or overlong body fails the read with an error. The 400
+// response is written from inside that failing Read, before the handler sees
+// the error, so any response the handler then writes is suppressed. A
+// declared length above the router's MaxBodySize, when set, is rejected with
+// 413 before the handler runs. Errors from an inner BodyLimit pass through
+// unchanged.
+func ContentLengthCheck() Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			declared := c.R.ContentLength
+			if declared < 0 || c.R.Body == nil || c.R.Body == http.NoBody {
+				next(c)
+				return
+			}
+			if c.maxBodySize > 0 && declared > c.maxBodySize {
+				c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+				return
+			}
+			c.R.Body = &lengthCheckReader{c: c, ReadCloser: c.R.Body, remaining: declared}
+			next(c)
+		}
+	}
+}
+
+// lengthCheckReader counts down the declared length and fails the read that
+// reveals a mismatch. net/http itself reports a short body as
+// io.ErrUnexpectedEOF, so any read error before the declared length arrives
+// counts as a mismatch.
+type lengthCheckReader struct {
+	io.ReadCloser
+	c         *Context
+	remaining int64
+	failed    bool
+}
+
+func (r *lengthCheckReader) Read(p []byte) (int, error) {
+	if r.failed {
+		return 0, errContentLengthMismatch
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	switch {
+	case r.remaining < 0:
+		return n + int(r.remaining), r.fail()
+	case err != nil && r.remaining > 0:
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return n, err
+		}
+		return n, r.fail()
+	}
+	return n, err
+}
+
+func (r *lengthCheckReader) fail() error {
+	r.failed = true
+	r.c.JSON(http.StatusBadRequest, ErrorResponse{Error: "content length mismatch"})
+	return errContentLengthMismatch
+}
@@ -17,7 +17,9 @@
 package quokka_test
 
 import (
+	"bufio"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -94,4 +96,86 @@ var _ = Describe("BodyLimit", func() {
 		Expect(rr.Code).To(Equal(http.StatusOK))
 		Expect(rr.Body.String()).To(Equal("pong"))
 	})
+
+	Describe("ContentLengthCheck", func() {
+		newRouter := func() *q.Router {
+			r := q.New()
+			r.Use(q.ContentLengthCheck())
+			r.POST("/upload", func(c *q.Context) {
+				body, err := io.ReadAll(c.R.Body)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, q.ErrorResponse{Error: "read failed"})
+					return
+				}
+				c.Text(http.StatusOK, string(body))
+			})
+			return r
+		}
+		post := func(r *q.Router, body string, declared int64) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(body))
+			req.ContentLength = declared
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("passes a body matching its declared length", func() {
+			rr := post(newRouter(), "hello", 5)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("hello"))
+		})
+
+		It("rejects a body shorter than declared with 400", func() {
+			rr := post(newRouter(), "hel", 5)
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			Expect(rr.Body.String()).To(ContainSubstring("content length mismatch"))
+		})
+
+		It("rejects a body shorter than declared on a real server", func() {
+			srv := httptest.NewServer(newRouter())
+			defer srv.Close()
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+			_, err = io.WriteString(conn, "POST /upload HTTP/1.1\r\nHost: x\r\nContent-Length: 10\r\n\r\nhello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conn.(*net.TCPConn).CloseWrite()).To(Succeed())
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects a body longer than declared with 400", func() {
+			rr := post(newRouter(), "hello world", 5)
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("skips requests without a declared length", func() {
+			rr := post(newRouter(), "hello", -1)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+		})
+
+		It("rejects a declared length above MaxBodySize before the handler runs", func() {
+			r := newRouter()
+			r.MaxBodySize = 4
+			rr := post(r, "hello", 5)
+			Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+
+		It("leaves BodyLimit errors to the handler", func() {
+			r := q.New()
+			r.Use(q.ContentLengthCheck(), q.BodyLimit(3))
+			r.POST("/upload", func(c *q.Context) {
+				if _, err := io.ReadAll(c.R.Body); err != nil {
+					c.JSON(http.StatusRequestEntityTooLarge, q.ErrorResponse{Error: "too large"})
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+			rr := post(r, "hello", 5)
+			Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
 })
@@ -0,0 +1,59 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// BrotliConfig configures the Brotli compression middleware.
+type BrotliConfig struct {
+	// Level is the brotli quality level (1-11). 0, or a value outside that
+	// range, means the default. Default: brotli.DefaultCompression (6).
+	Level int
+
+	// MinLength is the minimum response body size in bytes before compression
+	// is applied. Responses smaller than this are sent uncompressed.
+	// Default: 256.
+	MinLength int
+}
+
+// Brotli creates a middleware that compresses responses using brotli
+// encoding when the client sends Accept-Encoding: br. It follows the same
+// rules as Gzip: responses smaller than MinLength bytes are sent uncompressed,
+// already-compressed content types are skipped, and the decision is recorded
+// on the Context. When Brotli and Gzip are both registered, the inner one
+// compresses for clients that accept both and the outer one skips the
+// already-encoded response, so register Brotli after Gzip to prefer it.
+func Brotli(cfg BrotliConfig) Middleware {
+	if cfg.Level <= 0 || cfg.Level > brotli.BestCompression {
+		cfg.Level = brotli.DefaultCompression
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 256
+	}
+	return compress(cfg.MinLength, []string{"br"}, map[string]func(io.Writer) encoder{
+		"br": brotliEncoder(cfg.Level),
+	})
+}
+
+// brotliEncoder returns a constructor for brotli writers at level.
+func brotliEncoder(level int) func(io.Writer) encoder {
+	return func(out io.Writer) encoder { return brotli.NewWriterLevel(out, level) }
+}
@@ -0,0 +1,117 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Brotli Middleware", func() {
+	large := strings.Repeat("quokka brotli payload ", 100)
+
+	serve := func(r *q.Router, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("compresses when the client accepts br and round-trips", func() {
+		r := q.New()
+		r.Use(q.Brotli(q.BrotliConfig{}))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, large) })
+
+		rr := serve(r, "gzip, br")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("br"))
+		Expect(rr.Header().Get("Vary")).To(ContainSubstring("Accept-Encoding"))
+		body, err := io.ReadAll(brotli.NewReader(rr.Body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal(large))
+	})
+
+	It("treats Level 0 as the default quality", func() {
+		bodyAt := func(level int) []byte {
+			r := q.New()
+			r.Use(q.Brotli(q.BrotliConfig{Level: level}))
+			r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, large) })
+			return serve(r, "br").Body.Bytes()
+		}
+		Expect(bodyAt(0)).To(Equal(bodyAt(brotli.DefaultCompression)))
+		Expect(bodyAt(1)).NotTo(Equal(bodyAt(brotli.DefaultCompression)))
+	})
+
+	It("does not compress when br is not accepted", func() {
+		r := q.New()
+		r.Use(q.Brotli(q.BrotliConfig{}))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, large) })
+
+		rr := serve(r, "gzip")
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(Equal(large))
+	})
+
+	It("does not compress bodies below MinLength", func() {
+		r := q.New()
+		r.Use(q.Brotli(q.BrotliConfig{MinLength: 1024}))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, "small") })
+
+		rr := serve(r, "br")
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(Equal("small"))
+	})
+
+	It("skips already-compressed content types", func() {
+		r := q.New()
+		r.Use(q.Brotli(q.BrotliConfig{}))
+		r.GET("/", func(c *q.Context) { c.Bytes(http.StatusOK, []byte(large), "image/png") })
+
+		rr := serve(r, "br")
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(Equal(large))
+	})
+
+	It("prefers the inner encoder when combined with Gzip", func() {
+		var res q.CompressionResult
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) { next(c); res, _ = c.Compression() }
+		})
+		r.Use(q.Gzip(q.GzipConfig{}), q.Brotli(q.BrotliConfig{}))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, large) })
+
+		rr := serve(r, "gzip, br")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("br"))
+		body, err := io.ReadAll(brotli.NewReader(rr.Body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal(large))
+		Expect(res.Encoding).To(Equal("br"))
+
+		rr = serve(r, "gzip")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(decompressGzip(rr.Body.Bytes())).To(Equal(large))
+	})
+})
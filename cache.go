@@ -0,0 +1,341 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a complete response captured by the Cache or Idempotency
+// middleware.
+type CachedResponse struct {
+	Status int
+	Header http.Header // headers set by the handler chain inside the middleware
+	Body   []byte
+
+	// RequestHash fingerprints the request that produced the response. It is
+	// set by Idempotency to detect a key reused with a different request.
+	RequestHash string
+}
+
+// CacheStore stores cached responses. Implementations must be safe for
+// concurrent use. Get must not return entries whose TTL has elapsed.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+}
+
+// CacheConfig configures the Cache middleware.
+type CacheConfig struct {
+	// TTL is how long a response stays cached. Default: 1 minute.
+	TTL time.Duration
+
+	// VaryHeaders lists request headers whose values are part of the cache
+	// key (e.g. "Accept", "Accept-Language"). Default: none.
+	VaryHeaders []string
+
+	// MaxBodySize is the largest response body, in bytes, that is cached.
+	// Larger responses are still served but not stored. Default: 1 MB.
+	MaxBodySize int
+
+	// MaxEntries bounds the default in-memory store. Ignored when Store is
+	// set. Default: 1000.
+	MaxEntries int
+
+	// Store holds cached responses. Default: an in-memory store bounded by
+	// MaxEntries.
+	Store CacheStore
+
+	// IdentityFunc lets requests carrying Authorization or Cookie headers be
+	// cached. By default such requests bypass the cache, because their
+	// responses may be user-specific and a HIT would skip any authentication
+	// registered after Cache. When set, it returns the identity (e.g. the
+	// JWT subject or a hash of the credentials) that becomes part of the
+	// key, so responses are only replayed to the same identity; returning ""
+	// bypasses the cache.
+	IdentityFunc func(*Context) string
+}
+
+// Cache creates a middleware that caches complete GET responses in a store
+// keyed by host, path, query, and VaryHeaders, and coalesces concurrent
+// identical requests so only one reaches the handler while the others wait
+// for its result. Only 200 responses without Set-Cookie or a no-store/private
+// Cache-Control are stored. Responses carry X-Cache: HIT or MISS. Requests
+// with Authorization or Cookie headers bypass the cache unless IdentityFunc
+// is set.
+//
+// Register Cache inside (after) Gzip, or add "Accept-Encoding" to
+// VaryHeaders, so encoded and plain bodies are not mixed.
+func Cache(cfg CacheConfig) Middleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryCacheStore(cfg.MaxEntries)
+	}
+
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]*cacheCall)
+	)
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if c.R.Method != http.MethodGet {
+				next(c)
+				return
+			}
+			key := cacheKey(c.R, cfg.VaryHeaders)
+			if c.R.Header.Get("Authorization") != "" || c.R.Header.Get("Cookie") != "" {
+				id := ""
+				if cfg.IdentityFunc != nil {
+					id = cfg.IdentityFunc(c)
+				}
+				if id == "" {
+					next(c)
+					return
+				}
+				key += "\nidentity:" + id
+			}
+			if res, ok := cfg.Store.Get(key); ok {
+				replayCached(c, res)
+				return
+			}
+
+			mu.Lock()
+			if call, ok := calls[key]; ok {
+				mu.Unlock()
+				select {
+				case <-call.done:
+				case <-c.R.Context().Done():
+					// call.res may still be written by the leader; it is only
+					// safe to read after done is closed.
+					c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "request canceled"})
+					return
+				}
+				if call.res != nil {
+					replayCached(c, call.res)
+					return
+				}
+				next(c)
+				return
+			}
+			call := &cacheCall{done: make(chan struct{})}
+			calls[key] = call
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(call.done)
+			}()
+
+			c.W.Header().Set("X-Cache", "MISS")
+			before := c.W.Header().Clone()
+			rec := &cacheRecorder{ResponseWriter: c.W, limit: cfg.MaxBodySize}
+			original := c.W
+			c.W = rec
+			next(c)
+			c.W = original
+
+			if res := rec.capture(before); res != nil && cacheable(res) {
+				cfg.Store.Set(key, res, cfg.TTL)
+				call.res = res
+			}
+		}
+	}
+}
+
+type cacheCall struct {
+	done chan struct{}
+	res  *CachedResponse
+}
+
+func cacheKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	// The host keeps responses for different virtual hosts or tenants apart.
+	b.WriteString(strings.ToLower(r.Host))
+	b.WriteString(r.URL.RequestURI())
+	for _, h := range vary {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(h))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+func replayCached(c *Context, res *CachedResponse) {
+	c.W.Header().Set("X-Cache", "HIT")
+	writeCached(c, res)
+}
+
+// writeCached writes a stored response's headers, status, and body to c.
+func writeCached(c *Context, res *CachedResponse) {
+	h := c.W.Header()
+	for k, v := range res.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	c.Bytes(res.Status, res.Body, "")
+}
+
+// cacheRecorder passes writes through to the client while buffering the body
+// up to limit bytes for storage.
+type cacheRecorder struct {
+	http.ResponseWriter
+	status   int
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *cacheRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.overflow {
+		if w.buf.Len()+len(b) > w.limit {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streaming compatibility.
+func (w *cacheRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *cacheRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// capture returns the recorded response, or nil when nothing was written or
+// the body exceeded the limit. Only headers added or changed since before
+// are captured so values set by outer middleware (CORS, request IDs) are
+// not replayed to other clients.
+func (w *cacheRecorder) capture(before http.Header) *CachedResponse {
+	if w.status == 0 || w.overflow {
+		return nil
+	}
+	hdr := http.Header{}
+	for k, v := range w.Header() {
+		if k == "X-Cache" {
+			continue
+		}
+		if prev, ok := before[k]; ok && strings.Join(prev, "\x00") == strings.Join(v, "\x00") {
+			continue
+		}
+		hdr[k] = append([]string(nil), v...)
+	}
+	return &CachedResponse{Status: w.status, Header: hdr, Body: bytes.Clone(w.buf.Bytes())}
+}
+
+// cacheable reports whether the Cache middleware may share res with other
+// clients: a 200 without Set-Cookie or a no-store/private Cache-Control.
+func cacheable(res *CachedResponse) bool {
+	if res.Status != http.StatusOK || res.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cc := strings.ToLower(res.Header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// MemoryCacheStore is an in-memory CacheStore bounded by entry count.
+// Expired entries are dropped lazily; when full, the entry closest to
+// expiry is evicted.
+type MemoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCacheStore creates a MemoryCacheStore holding at most maxEntries
+// responses. A maxEntries of 0 or less defaults to 1000.
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &MemoryCacheStore{maxEntries: maxEntries, entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get returns the unexpired response stored under key.
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return e.resp, true
+}
+
+// Set stores resp under key for ttl, evicting an entry if the store is full.
+func (s *MemoryCacheStore) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= s.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.expires.Before(oldest) {
+				oldestKey, oldest = k, e.expires
+			}
+		}
+		if len(s.entries) >= s.maxEntries {
+			delete(s.entries, oldestKey)
+		}
+	}
+	s.entries[key] = memoryCacheEntry{resp: resp, expires: now.Add(ttl)}
+}
@@ -0,0 +1,230 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Cache Middleware", func() {
+	get := func(r *q.Router, target string, hdr map[string]string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("serves a repeated GET from cache", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{TTL: time.Minute}))
+		r.GET("/items", func(c *q.Context) {
+			calls.Add(1)
+			c.SetHeader("X-Handler", "yes")
+			c.JSON(http.StatusOK, map[string]string{"q": c.Query("q")})
+		})
+
+		first := get(r, "/items?q=a", nil)
+		Expect(first.Header().Get("X-Cache")).To(Equal("MISS"))
+		second := get(r, "/items?q=a", nil)
+		Expect(second.Code).To(Equal(http.StatusOK))
+		Expect(second.Header().Get("X-Cache")).To(Equal("HIT"))
+		Expect(second.Header().Get("X-Handler")).To(Equal("yes"))
+		Expect(second.Header().Get("Content-Type")).To(ContainSubstring("application/json"))
+		Expect(second.Body.String()).To(Equal(first.Body.String()))
+		Expect(calls.Load()).To(Equal(int32(1)))
+
+		get(r, "/items?q=b", nil)
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("keys on VaryHeaders", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{VaryHeaders: []string{"Accept-Language"}}))
+		r.GET("/greet", func(c *q.Context) { calls.Add(1); c.Text(http.StatusOK, c.Header("Accept-Language")) })
+
+		Expect(get(r, "/greet", map[string]string{"Accept-Language": "en"}).Body.String()).To(Equal("en"))
+		Expect(get(r, "/greet", map[string]string{"Accept-Language": "fr"}).Body.String()).To(Equal("fr"))
+		Expect(get(r, "/greet", map[string]string{"Accept-Language": "en"}).Body.String()).To(Equal("en"))
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("does not store errors, cookies, or oversized bodies", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{MaxBodySize: 8}))
+		r.GET("/err", func(c *q.Context) { calls.Add(1); c.Status(http.StatusInternalServerError) })
+		r.GET("/cookie", func(c *q.Context) { calls.Add(1); c.SetCookie("s", "1", nil); c.Text(http.StatusOK, "ok") })
+		r.GET("/big", func(c *q.Context) { calls.Add(1); c.Text(http.StatusOK, strings.Repeat("x", 64)) })
+
+		for _, p := range []string{"/err", "/cookie", "/big"} {
+			get(r, p, nil)
+			rr := get(r, p, nil)
+			Expect(rr.Header().Get("X-Cache")).To(Equal("MISS"))
+		}
+		Expect(calls.Load()).To(Equal(int32(6)))
+	})
+
+	It("coalesces concurrent identical requests", func() {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{}))
+		r.GET("/slow", func(c *q.Context) {
+			calls.Add(1)
+			<-release
+			c.Text(http.StatusOK, "done")
+		})
+
+		const n = 10
+		var wg sync.WaitGroup
+		bodies := make([]string, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				bodies[i] = get(r, "/slow", nil).Body.String()
+			}(i)
+		}
+		Eventually(calls.Load).Should(Equal(int32(1)))
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		Expect(calls.Load()).To(Equal(int32(1)))
+		for _, b := range bodies {
+			Expect(b).To(Equal("done"))
+		}
+	})
+
+	It("answers a waiter whose context ends without reading the leader's result", func() {
+		release := make(chan struct{})
+		entered := make(chan struct{})
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{}))
+		r.GET("/slow", func(c *q.Context) {
+			close(entered)
+			<-release
+			c.Text(http.StatusOK, "done")
+		})
+
+		leader := make(chan *httptest.ResponseRecorder, 1)
+		go func() { leader <- get(r, "/slow", nil) }()
+		<-entered
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+		Expect(rr.Code).To(Equal(http.StatusServiceUnavailable))
+
+		close(release)
+		Expect((<-leader).Body.String()).To(Equal("done"))
+	})
+
+	It("bypasses requests with Authorization or Cookie headers by default", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{}))
+		r.GET("/me", func(c *q.Context) { calls.Add(1); c.Text(http.StatusOK, c.Header("Authorization")+c.Header("Cookie")) })
+
+		for _, hdr := range []map[string]string{{"Authorization": "Bearer alice"}, {"Cookie": "session=alice"}} {
+			for i := 0; i < 2; i++ {
+				rr := get(r, "/me", hdr)
+				Expect(rr.Header().Get("X-Cache")).To(BeEmpty())
+			}
+		}
+		Expect(calls.Load()).To(Equal(int32(4)))
+		Expect(get(r, "/me", map[string]string{"Authorization": "Bearer bob"}).Body.String()).To(Equal("Bearer bob"))
+	})
+
+	It("caches authenticated requests per identity with IdentityFunc", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{IdentityFunc: func(c *q.Context) string { return c.Header("Authorization") }}))
+		r.GET("/me", func(c *q.Context) { calls.Add(1); c.Text(http.StatusOK, c.Header("Authorization")) })
+
+		alice := map[string]string{"Authorization": "Bearer alice"}
+		bob := map[string]string{"Authorization": "Bearer bob"}
+		Expect(get(r, "/me", alice).Body.String()).To(Equal("Bearer alice"))
+		Expect(get(r, "/me", bob).Body.String()).To(Equal("Bearer bob"))
+		rr := get(r, "/me", alice)
+		Expect(rr.Header().Get("X-Cache")).To(Equal("HIT"))
+		Expect(rr.Body.String()).To(Equal("Bearer alice"))
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("passes non-GET requests through", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{}))
+		r.POST("/items", func(c *q.Context) { calls.Add(1); c.Status(http.StatusCreated) })
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items", nil))
+			Expect(rr.Header().Get("X-Cache")).To(BeEmpty())
+		}
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("MemoryCacheStore expires and evicts entries", func() {
+		s := q.NewMemoryCacheStore(2)
+		s.Set("a", &q.CachedResponse{Status: 200}, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		_, ok := s.Get("a")
+		Expect(ok).To(BeFalse())
+
+		s.Set("b", &q.CachedResponse{Status: 200}, time.Minute)
+		s.Set("c", &q.CachedResponse{Status: 200}, 2*time.Minute)
+		s.Set("d", &q.CachedResponse{Status: 200}, 3*time.Minute)
+		_, ok = s.Get("b")
+		Expect(ok).To(BeFalse())
+		_, ok = s.Get("d")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("keeps entries for different hosts apart", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Cache(q.CacheConfig{}))
+		r.GET("/profile", func(c *q.Context) { calls.Add(1); c.Text(http.StatusOK, c.R.Host) })
+
+		for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+			req.Host = host
+			r.ServeHTTP(rr, req)
+			Expect(rr.Body.String()).To(Equal(host))
+		}
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+})
@@ -0,0 +1,222 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Codec marshals and unmarshals values for a content type. Register one with
+// Router.RegisterCodec to support formats such as msgpack, protobuf, or CBOR
+// in Context.Bind and Context.Render.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the built-in application/json codec. Unmarshal rejects
+// unknown fields, matching BindJSON.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes JSON data into v, rejecting unknown fields.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// XMLCodec is the built-in application/xml and text/xml codec.
+type XMLCodec struct{}
+
+// Marshal encodes v as XML.
+func (XMLCodec) Marshal(v any) ([]byte, error) { return xml.Marshal(v) }
+
+// Unmarshal decodes XML data into v.
+func (XMLCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+// FormCodec is the built-in application/x-www-form-urlencoded codec. It
+// unmarshals into structs with `form` tags (see BindForm) and marshals
+// url.Values, map[string]string, or structs with `form` tags.
+type FormCodec struct{}
+
+// Marshal encodes v as a URL-encoded form.
+func (FormCodec) Marshal(v any) ([]byte, error) {
+	switch vals := v.(type) {
+	case url.Values:
+		return []byte(vals.Encode()), nil
+	case map[string]string:
+		out := url.Values{}
+		for k, s := range vals {
+			out.Set(k, s)
+		}
+		return []byte(out.Encode()), nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("quokka: cannot form-encode %T", v)
+	}
+	out := url.Values{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag, _, _ := parseBindTag(rt.Field(i).Tag.Get("form"))
+		if tag == "" || tag == "-" || !rt.Field(i).IsExported() {
+			continue
+		}
+		out.Set(tag, fmt.Sprint(rv.Field(i).Interface()))
+	}
+	return []byte(out.Encode()), nil
+}
+
+// Unmarshal decodes a URL-encoded form into a struct with `form` tags.
+func (FormCodec) Unmarshal(data []byte, v any) error {
+	vals, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	return bindValues(vals, v, "form")
+}
+
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		"application/json":                  JSONCodec{},
+		"application/xml":                   XMLCodec{},
+		"text/xml":                          XMLCodec{},
+		"application/x-www-form-urlencoded": FormCodec{},
+	}
+}
+
+// RegisterCodec registers codec for contentType (e.g. "application/msgpack"),
+// replacing any existing codec, including the built-ins.
+func (r *Router) RegisterCodec(contentType string, codec Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[normalizeMediaType(contentType)] = codec
+}
+
+// codecFor returns the codec registered for contentType.
+func (r *Router) codecFor(contentType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findCodec(r.codecs, contentType)
+}
+
+// findCodec looks up contentType in codecs. Structured syntax suffixes fall
+// back to their base format, so application/problem+json uses the JSON codec.
+func findCodec(codecs map[string]Codec, contentType string) (Codec, bool) {
+	mt := normalizeMediaType(contentType)
+	if codec, ok := codecs[mt]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndexByte(mt, '+'); i >= 0 {
+		codec, ok := codecs["application/"+mt[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+func normalizeMediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// codecFor resolves a codec from the dispatching router, or from the
+// built-ins when the Context was not created by a Router.
+func (c *Context) codecFor(contentType string) (Codec, bool) {
+	if c.router == nil {
+		return findCodec(defaultCodecs(), contentType)
+	}
+	return c.router.codecFor(contentType)
+}
+
+// Bind decodes the request body into dst using the codec registered for the
+// request's Content-Type. It returns an error wrapping ErrUnsupportedMediaType
+// when no codec matches, so handlers can respond with 415. The body is
+// limited to MaxBodySize (default 10 MB).
+//
+// A multipart/form-data body is parsed as by MultipartForm instead, and its
+// values, not the query string, are bound by `form` tags as with FormCodec;
+// uploaded files remain available through FormFile and FormFiles.
+func (c *Context) Bind(dst any) error {
+	ct := c.R.Header.Get("Content-Type")
+	if normalizeMediaType(ct) == "multipart/form-data" {
+		if err := c.parseMultipartForm(); err != nil {
+			return err
+		}
+		return bindValues(c.R.PostForm, dst, "form")
+	}
+	codec, ok := c.codecFor(ct)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, ct)
+	}
+	limit := c.maxBodySize
+	if limit <= 0 {
+		limit = 10 << 20 // 10MB default
+	}
+	ctx := c.R.Context()
+	data, err := io.ReadAll(io.LimitReader(ctxReader{ctx: ctx, r: c.R.Body}, limit))
+	_ = c.R.Body.Close()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("quokka: reading request body: %w", ctxErr)
+		}
+		return err
+	}
+	return codec.Unmarshal(data, dst)
+}
+
+// Render marshals v with the codec registered for contentType and writes it
+// with the given status code. If no codec matches or marshaling fails, a 500
+// is written instead.
+func (c *Context) Render(code int, v any, contentType string) {
+	if c.wrote {
+		return
+	}
+	codec, ok := c.codecFor(contentType)
+	var b []byte
+	err := errors.New("no codec registered")
+	if ok {
+		b, err = codec.Marshal(v)
+	}
+	if err != nil {
+		slog.Error("render failed", slog.String("content_type", logSanitizer.Replace(contentType)), slog.Any("err", err)) // #nosec G706 -- newlines stripped by logSanitizer
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Bytes(code, b, contentType)
+}
@@ -0,0 +1,170 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+// reverseCodec is a fake codec that stores strings reversed on the wire.
+type reverseCodec struct{}
+
+func reverse(s string) string {
+	b := []rune(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func (reverseCodec) Marshal(v any) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T", v)
+	}
+	return []byte(reverse(s)), nil
+}
+
+func (reverseCodec) Unmarshal(data []byte, v any) error {
+	p, ok := v.(*string)
+	if !ok {
+		return fmt.Errorf("unsupported %T", v)
+	}
+	*p = reverse(string(data))
+	return nil
+}
+
+var _ = Describe("Codecs", func() {
+	It("round-trips through a registered codec", func() {
+		r := q.New()
+		r.RegisterCodec("application/x-reverse", reverseCodec{})
+		r.POST("/echo", func(c *q.Context) {
+			var s string
+			if err := c.Bind(&s); err != nil {
+				c.Status(http.StatusBadRequest)
+				return
+			}
+			c.Render(http.StatusOK, s+"!", "application/x-reverse")
+		})
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("olleh"))
+		req.Header.Set("Content-Type", "application/x-reverse; charset=utf-8")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Type")).To(Equal("application/x-reverse"))
+		Expect(rr.Body.String()).To(Equal("!olleh"))
+	})
+
+	It("binds and renders the built-in formats", func() {
+		type item struct {
+			Name string `json:"name" xml:"name" form:"name"`
+			Qty  int    `json:"qty" xml:"qty" form:"qty"`
+		}
+		r := q.New()
+		r.POST("/items", func(c *q.Context) {
+			var it item
+			if err := c.Bind(&it); err != nil {
+				c.Text(http.StatusBadRequest, err.Error())
+				return
+			}
+			c.Render(http.StatusOK, it, c.Header("Accept"))
+		})
+
+		for _, tc := range []struct{ ct, body, accept, want string }{
+			{"application/json", `{"name":"a","qty":2}`, "application/xml", "<item><name>a</name><qty>2</qty></item>"},
+			{"application/xml", `<item><name>b</name><qty>3</qty></item>`, "application/json", `{"name":"b","qty":3}`},
+			{"application/x-www-form-urlencoded", "name=c&qty=4", "application/x-www-form-urlencoded", "name=c&qty=4"},
+			{"application/json", `{"name":"d","qty":5}`, "application/problem+json", `{"name":"d","qty":5}`},
+		} {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.ct)
+			req.Header.Set("Accept", tc.accept)
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusOK), tc.ct)
+			Expect(strings.TrimSpace(rr.Body.String())).To(Equal(tc.want))
+		}
+	})
+
+	It("binds multipart metadata fields and leaves the file to FormFile", func() {
+		type upload struct {
+			Title string   `form:"title"`
+			Tags  []string `form:"tags"`
+			Size  int      `form:"size"`
+		}
+		r := q.New()
+		r.POST("/upload", func(c *q.Context) {
+			var u upload
+			if err := c.Bind(&u); err != nil {
+				c.Text(http.StatusBadRequest, err.Error())
+				return
+			}
+			fh, err := c.FormFile("file")
+			if err != nil {
+				c.Text(http.StatusBadRequest, err.Error())
+				return
+			}
+			c.Text(http.StatusOK, fmt.Sprintf("%s|%v|%d|%s", u.Title, u.Tags, u.Size, fh.Filename))
+		})
+
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("title", "report")
+		_ = mw.WriteField("tags", "a")
+		_ = mw.WriteField("tags", "b")
+		_ = mw.WriteField("size", "42")
+		fw, _ := mw.CreateFormFile("file", "report.pdf")
+		_, _ = fw.Write([]byte("%PDF"))
+		_ = mw.Close()
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload?title=ignored", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("report|[a b]|42|report.pdf"))
+	})
+
+	It("reports unsupported media types from Bind and fails Render with 500", func() {
+		r := q.New()
+		var bindErr error
+		r.POST("/x", func(c *q.Context) {
+			var v any
+			bindErr = c.Bind(&v)
+			c.Render(http.StatusOK, "v", "application/x-unknown")
+		})
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/x", strings.NewReader("data"))
+		req.Header.Set("Content-Type", "application/x-unknown")
+		r.ServeHTTP(rr, req)
+		Expect(errors.Is(bindErr, q.ErrUnsupportedMediaType)).To(BeTrue())
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+	})
+})
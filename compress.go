@@ -0,0 +1,79 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// CompressConfig configures the Compress middleware.
+type CompressConfig struct {
+	// Encodings lists the content codings to offer, most preferred first.
+	// Supported values are "br" and "gzip"; others are ignored. The client's
+	// Accept-Encoding quality values decide, and this order breaks ties.
+	// Default: ["br", "gzip"].
+	Encodings []string
+
+	// GzipLevel is the gzip compression level. Default: gzip.DefaultCompression.
+	GzipLevel int
+
+	// BrotliLevel is the brotli quality level (1-11). 0, or a value outside
+	// that range, means the default, as for BrotliConfig.Level.
+	// Default: brotli.DefaultCompression (6).
+	BrotliLevel int
+
+	// MinLength is the minimum response body size in bytes before compression
+	// is applied. Default: 256.
+	MinLength int
+}
+
+// Compress creates a middleware that negotiates the response encoding from
+// the full Accept-Encoding header, choosing between brotli, gzip, and
+// identity. It applies the same MinLength buffering, content-type skip list,
+// and Context.Compression reporting as Gzip. When no offered coding is
+// acceptable the response is sent uncompressed, even if the client refused
+// identity.
+func Compress(cfg CompressConfig) Middleware {
+	if cfg.GzipLevel == 0 {
+		cfg.GzipLevel = gzip.DefaultCompression
+	}
+	if cfg.BrotliLevel <= 0 || cfg.BrotliLevel > brotli.BestCompression {
+		cfg.BrotliLevel = brotli.DefaultCompression
+	}
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 256
+	}
+	available := map[string]func(io.Writer) encoder{
+		"br":   brotliEncoder(cfg.BrotliLevel),
+		"gzip": gzipEncoder(cfg.GzipLevel),
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{"br", "gzip"}
+	}
+	var order []string
+	encoders := map[string]func(io.Writer) encoder{}
+	for _, e := range cfg.Encodings {
+		if enc, ok := available[e]; ok && encoders[e] == nil {
+			order = append(order, e)
+			encoders[e] = enc
+		}
+	}
+	return compress(cfg.MinLength, order, encoders)
+}
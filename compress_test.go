@@ -0,0 +1,90 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Compress Middleware", func() {
+	large := strings.Repeat("negotiated compression payload ", 100)
+
+	serve := func(cfg q.CompressConfig, acceptEncoding string) *httptest.ResponseRecorder {
+		r := q.New()
+		r.Use(q.Compress(cfg))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, large) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("prefers br when gzip and br are equally acceptable", func() {
+		rr := serve(q.CompressConfig{}, "gzip, br")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("br"))
+		body, err := io.ReadAll(brotli.NewReader(rr.Body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal(large))
+	})
+
+	It("treats BrotliLevel 0 as the default quality", func() {
+		def := serve(q.CompressConfig{BrotliLevel: brotli.DefaultCompression}, "br").Body.Bytes()
+		Expect(serve(q.CompressConfig{}, "br").Body.Bytes()).To(Equal(def))
+		Expect(serve(q.CompressConfig{BrotliLevel: 1}, "br").Body.Bytes()).NotTo(Equal(def))
+	})
+
+	It("follows q-values over the default order", func() {
+		rr := serve(q.CompressConfig{}, "br;q=0.5, gzip;q=0.9")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(decompressGzip(rr.Body.Bytes())).To(Equal(large))
+	})
+
+	It("uses the configured order to break ties", func() {
+		rr := serve(q.CompressConfig{Encodings: []string{"gzip", "br"}}, "gzip, br")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+	})
+
+	It("sends identity when only identity is accepted", func() {
+		rr := serve(q.CompressConfig{}, "identity")
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(Equal(large))
+	})
+
+	It("sends uncompressed when identity is refused but nothing supported is offered", func() {
+		rr := serve(q.CompressConfig{}, "identity;q=0, zstd")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(Equal(large))
+	})
+
+	It("honors a wildcard and explicit exclusions", func() {
+		rr := serve(q.CompressConfig{}, "*, br;q=0")
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+	})
+})
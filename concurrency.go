@@ -0,0 +1,83 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"net/http"
+	"sync"
+)
+
+// PerClientConcurrencyConfig configures the PerClientConcurrency middleware.
+type PerClientConcurrencyConfig struct {
+	// Limit is the maximum number of in-flight requests per client key.
+	// Default: 10.
+	Limit int
+
+	// Status is the response status for a request over the limit, typically
+	// 429 Too Many Requests or 503 Service Unavailable. Default: 429.
+	Status int
+
+	// KeyFunc extracts a client key from the request, such as an API key
+	// identity. When nil, the default uses Context.ClientIP, as in RateLimit.
+	KeyFunc func(*Context) string
+}
+
+// PerClientConcurrency creates a middleware that caps simultaneous in-flight
+// requests per client key, so a single client cannot monopolize capacity.
+// Requests over the limit are rejected immediately with cfg.Status rather
+// than queued. Unlike RateLimit it bounds concurrency, not request rate;
+// the two can be combined.
+func PerClientConcurrency(cfg PerClientConcurrencyConfig) Middleware {
+	if cfg.Limit < 1 {
+		cfg.Limit = 10
+	}
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusTooManyRequests
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = defaultKeyFunc
+	}
+
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]int)
+	)
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			key := cfg.KeyFunc(c)
+
+			mu.Lock()
+			if inFlight[key] >= cfg.Limit {
+				mu.Unlock()
+				c.JSON(cfg.Status, ErrorResponse{Error: "too many concurrent requests"})
+				return
+			}
+			inFlight[key]++
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				if inFlight[key]--; inFlight[key] == 0 {
+					delete(inFlight, key)
+				}
+				mu.Unlock()
+			}()
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("PerClientConcurrency", func() {
+	var (
+		r       *q.Router
+		entered chan struct{}
+		release chan struct{}
+	)
+
+	setup := func(cfg q.PerClientConcurrencyConfig) {
+		entered = make(chan struct{}, 10)
+		release = make(chan struct{})
+		r = q.New()
+		r.Use(q.PerClientConcurrency(cfg))
+		r.GET("/slow", func(c *q.Context) {
+			entered <- struct{}{}
+			<-release
+			c.Status(http.StatusOK)
+		})
+		r.GET("/fast", func(c *q.Context) { c.Status(http.StatusOK) })
+	}
+
+	do := func(path, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	It("blocks one client at its limit while another proceeds", func() {
+		setup(q.PerClientConcurrencyConfig{Limit: 2})
+
+		var wg sync.WaitGroup
+		codes := make(chan int, 2)
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- do("/slow", "10.0.0.1")
+			}()
+		}
+		Eventually(entered).Should(Receive())
+		Eventually(entered).Should(Receive())
+
+		Expect(do("/fast", "10.0.0.1")).To(Equal(http.StatusTooManyRequests))
+		Expect(do("/fast", "10.0.0.2")).To(Equal(http.StatusOK))
+
+		close(release)
+		wg.Wait()
+		Expect(<-codes).To(Equal(http.StatusOK))
+		Expect(<-codes).To(Equal(http.StatusOK))
+		Expect(do("/fast", "10.0.0.1")).To(Equal(http.StatusOK))
+	})
+
+	It("uses the configured status and key function", func() {
+		setup(q.PerClientConcurrencyConfig{
+			Limit:   1,
+			Status:  http.StatusServiceUnavailable,
+			KeyFunc: func(c *q.Context) string { return c.R.Header.Get("X-API-Key") },
+		})
+
+		done := make(chan int, 1)
+		go func() {
+			req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+			req.Header.Set("X-API-Key", "alpha")
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			done <- rr.Code
+		}()
+		Eventually(entered).Should(Receive())
+
+		for key, want := range map[string]int{"alpha": http.StatusServiceUnavailable, "beta": http.StatusOK} {
+			req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+			req.Header.Set("X-API-Key", key)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(want), key)
+		}
+
+		close(release)
+		Eventually(done).Should(Receive(Equal(http.StatusOK)))
+	})
+
+	It("releases the slot when the handler panics", func() {
+		setup(q.PerClientConcurrencyConfig{Limit: 1})
+		r.GET("/panic", func(c *q.Context) { panic("boom") })
+		Expect(func() { do("/panic", "10.0.0.3") }).To(Panic())
+		Expect(do("/fast", "10.0.0.3")).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,168 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConditionalRequest holds the conditional request headers of RFC 9110
+// section 13. Entity tag lists keep their quotes and W/ prefixes; dates are
+// zero when the header is absent or not a valid HTTP-date.
+type ConditionalRequest struct {
+	IfMatch           []string
+	IfNoneMatch       []string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+	IfRange           string
+}
+
+// Conditional parses the request's If-Match, If-None-Match,
+// If-Modified-Since, If-Unmodified-Since and If-Range headers.
+func (c *Context) Conditional() ConditionalRequest {
+	h := c.R.Header
+	cr := ConditionalRequest{
+		IfMatch:     splitETags(h.Get("If-Match")),
+		IfNoneMatch: splitETags(h.Get("If-None-Match")),
+		IfRange:     strings.TrimSpace(h.Get("If-Range")),
+	}
+	cr.IfModifiedSince, _ = http.ParseTime(h.Get("If-Modified-Since"))
+	cr.IfUnmodifiedSince, _ = http.ParseTime(h.Get("If-Unmodified-Since"))
+	return cr
+}
+
+// IsConditional reports whether any conditional header was sent.
+func (cr ConditionalRequest) IsConditional() bool {
+	return len(cr.IfMatch) > 0 || len(cr.IfNoneMatch) > 0 || !cr.IfModifiedSince.IsZero() ||
+		!cr.IfUnmodifiedSince.IsZero() || cr.IfRange != ""
+}
+
+// Matches reports whether the If-Match precondition passes for the current
+// etag, using strong comparison. It passes when If-Match is absent; "*"
+// passes whenever etag is non-empty. A failed check should answer 412, e.g.
+// for optimistic locking on PUT.
+func (cr ConditionalRequest) Matches(etag string) bool {
+	if len(cr.IfMatch) == 0 {
+		return true
+	}
+	for _, t := range cr.IfMatch {
+		if (t == "*" && etag != "") || strongETagMatch(t, etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// NoneMatch reports whether the If-None-Match precondition passes for the
+// current etag, i.e. no listed tag matches it by weak comparison. It passes
+// when If-None-Match is absent; "*" fails whenever etag is non-empty.
+func (cr ConditionalRequest) NoneMatch(etag string) bool {
+	for _, t := range cr.IfNoneMatch {
+		if (t == "*" && etag != "") || (etag != "" && strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return false
+		}
+	}
+	return true
+}
+
+// ModifiedSince reports whether a resource last modified at t has changed
+// since If-Modified-Since. It is true when the header is absent or invalid.
+// Times are compared at the one-second precision of HTTP dates.
+func (cr ConditionalRequest) ModifiedSince(t time.Time) bool {
+	return cr.IfModifiedSince.IsZero() || t.Truncate(time.Second).After(cr.IfModifiedSince)
+}
+
+// UnmodifiedSince reports whether the If-Unmodified-Since precondition
+// passes for a resource last modified at t. It is true when the header is
+// absent or invalid.
+func (cr ConditionalRequest) UnmodifiedSince(t time.Time) bool {
+	return cr.IfUnmodifiedSince.IsZero() || !t.Truncate(time.Second).After(cr.IfUnmodifiedSince)
+}
+
+// NotModified reports whether a GET or HEAD for a resource with the given
+// etag and modification time should be answered 304 Not Modified.
+// If-None-Match takes precedence; If-Modified-Since is consulted only when it
+// is absent. Pass "" or the zero time for validators the resource lacks.
+func (cr ConditionalRequest) NotModified(etag string, modTime time.Time) bool {
+	if len(cr.IfNoneMatch) > 0 {
+		return !cr.NoneMatch(etag)
+	}
+	return !cr.IfModifiedSince.IsZero() && !modTime.IsZero() && !cr.ModifiedSince(modTime)
+}
+
+// RangeValid reports whether a Range header should be honored given
+// If-Range: true when If-Range is absent, when it is an entity tag strongly
+// matching etag, or when it is a date equal to modTime.
+func (cr ConditionalRequest) RangeValid(etag string, modTime time.Time) bool {
+	if cr.IfRange == "" {
+		return true
+	}
+	if strings.HasPrefix(cr.IfRange, `"`) || strings.HasPrefix(cr.IfRange, "W/") {
+		return strongETagMatch(cr.IfRange, etag)
+	}
+	t, err := http.ParseTime(cr.IfRange)
+	return err == nil && !modTime.IsZero() && t.Equal(modTime.Truncate(time.Second))
+}
+
+// RequireIfMatch enforces optimistic concurrency on PUT, PATCH and DELETE
+// requests: when If-Match does not match currentETag (strong comparison) it
+// writes 412 Precondition Failed and returns false. A request without
+// If-Match passes unless Router.IfMatchRequired is set, in which case it gets
+// 428 Precondition Required. Other methods always pass. On false the Context
+// is aborted, so the handler should return.
+//
+//	if !c.RequireIfMatch(doc.ETag) {
+//		return
+//	}
+func (c *Context) RequireIfMatch(currentETag string) bool {
+	switch c.R.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return true
+	}
+	cr := c.Conditional()
+	if len(cr.IfMatch) == 0 {
+		if c.router != nil && c.router.IfMatchRequired {
+			c.Fail(http.StatusPreconditionRequired, "precondition_required", "If-Match header required")
+			return false
+		}
+		return true
+	}
+	if !cr.Matches(currentETag) {
+		c.Fail(http.StatusPreconditionFailed, "precondition_failed", "resource has been modified")
+		return false
+	}
+	return true
+}
+
+// splitETags splits a comma-separated entity tag list, dropping empty items.
+func splitETags(header string) []string {
+	var tags []string
+	for _, t := range strings.Split(header, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// strongETagMatch applies RFC 9110 strong comparison: neither tag may be weak.
+func strongETagMatch(a, b string) bool {
+	return a != "" && a == b && !strings.HasPrefix(a, "W/")
+}
@@ -0,0 +1,216 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Conditional", func() {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 500, time.UTC)
+	httpDate := func(t time.Time) string { return t.UTC().Format(http.TimeFormat) }
+
+	parse := func(headers map[string]string) q.ConditionalRequest {
+		var cr q.ConditionalRequest
+		r := q.New()
+		r.GET("/doc", func(c *q.Context) { cr = c.Conditional(); c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/doc", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return cr
+	}
+
+	It("parses every header", func() {
+		cr := parse(map[string]string{
+			"If-Match":            `"a", W/"b"`,
+			"If-None-Match":       `"c"`,
+			"If-Modified-Since":   httpDate(modTime),
+			"If-Unmodified-Since": httpDate(modTime.Add(time.Hour)),
+			"If-Range":            `"a"`,
+		})
+		Expect(cr.IfMatch).To(Equal([]string{`"a"`, `W/"b"`}))
+		Expect(cr.IfNoneMatch).To(Equal([]string{`"c"`}))
+		Expect(cr.IfModifiedSince).To(BeTemporally("==", modTime.Truncate(time.Second)))
+		Expect(cr.IfUnmodifiedSince).To(BeTemporally("==", modTime.Add(time.Hour).Truncate(time.Second)))
+		Expect(cr.IfRange).To(Equal(`"a"`))
+		Expect(cr.IsConditional()).To(BeTrue())
+	})
+
+	It("passes every check for an unconditional request", func() {
+		cr := parse(nil)
+		Expect(cr.IsConditional()).To(BeFalse())
+		Expect(cr.Matches(`"a"`)).To(BeTrue())
+		Expect(cr.NoneMatch(`"a"`)).To(BeTrue())
+		Expect(cr.ModifiedSince(modTime)).To(BeTrue())
+		Expect(cr.UnmodifiedSince(modTime)).To(BeTrue())
+		Expect(cr.NotModified(`"a"`, modTime)).To(BeFalse())
+		Expect(cr.RangeValid(`"a"`, modTime)).To(BeTrue())
+	})
+
+	It("ignores invalid dates", func() {
+		cr := parse(map[string]string{"If-Modified-Since": "yesterday", "If-Unmodified-Since": "soon"})
+		Expect(cr.IsConditional()).To(BeFalse())
+		Expect(cr.ModifiedSince(modTime)).To(BeTrue())
+		Expect(cr.UnmodifiedSince(modTime)).To(BeTrue())
+	})
+
+	Context("If-Match", func() {
+		It("uses strong comparison", func() {
+			cr := parse(map[string]string{"If-Match": `"v1", "v2"`})
+			Expect(cr.Matches(`"v2"`)).To(BeTrue())
+			Expect(cr.Matches(`"v3"`)).To(BeFalse())
+			Expect(cr.Matches(`W/"v2"`)).To(BeFalse())
+			Expect(parse(map[string]string{"If-Match": `W/"v1"`}).Matches(`W/"v1"`)).To(BeFalse())
+		})
+
+		It("treats * as any current representation", func() {
+			cr := parse(map[string]string{"If-Match": "*"})
+			Expect(cr.Matches(`"anything"`)).To(BeTrue())
+			Expect(cr.Matches("")).To(BeFalse())
+		})
+	})
+
+	Context("If-None-Match", func() {
+		It("uses weak comparison", func() {
+			cr := parse(map[string]string{"If-None-Match": `W/"v1"`})
+			Expect(cr.NoneMatch(`"v1"`)).To(BeFalse())
+			Expect(cr.NoneMatch(`"v2"`)).To(BeTrue())
+			Expect(cr.NotModified(`"v1"`, time.Time{})).To(BeTrue())
+		})
+
+		It("treats * as any current representation", func() {
+			cr := parse(map[string]string{"If-None-Match": "*"})
+			Expect(cr.NoneMatch(`"v1"`)).To(BeFalse())
+			Expect(cr.NoneMatch("")).To(BeTrue())
+		})
+
+		It("takes precedence over If-Modified-Since", func() {
+			cr := parse(map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": httpDate(modTime)})
+			Expect(cr.NotModified(`"new"`, modTime)).To(BeFalse())
+			cr = parse(map[string]string{"If-None-Match": `"cur"`, "If-Modified-Since": httpDate(modTime.Add(-time.Hour))})
+			Expect(cr.NotModified(`"cur"`, modTime)).To(BeTrue())
+		})
+	})
+
+	Context("If-Modified-Since", func() {
+		It("compares at one-second precision", func() {
+			cr := parse(map[string]string{"If-Modified-Since": httpDate(modTime)})
+			Expect(cr.ModifiedSince(modTime)).To(BeFalse())
+			Expect(cr.ModifiedSince(modTime.Add(time.Second))).To(BeTrue())
+			Expect(cr.NotModified("", modTime)).To(BeTrue())
+			Expect(cr.NotModified("", modTime.Add(time.Minute))).To(BeFalse())
+			Expect(cr.NotModified("", time.Time{})).To(BeFalse())
+		})
+	})
+
+	Context("If-Unmodified-Since", func() {
+		It("fails once the resource changed", func() {
+			cr := parse(map[string]string{"If-Unmodified-Since": httpDate(modTime)})
+			Expect(cr.UnmodifiedSince(modTime)).To(BeTrue())
+			Expect(cr.UnmodifiedSince(modTime.Add(-time.Hour))).To(BeTrue())
+			Expect(cr.UnmodifiedSince(modTime.Add(time.Second))).To(BeFalse())
+		})
+
+		It("combines with If-Match for optimistic locking", func() {
+			cr := parse(map[string]string{"If-Match": `"v1"`, "If-Unmodified-Since": httpDate(modTime)})
+			Expect(cr.Matches(`"v1"`) && cr.UnmodifiedSince(modTime)).To(BeTrue())
+			Expect(cr.Matches(`"v2"`) && cr.UnmodifiedSince(modTime)).To(BeFalse())
+		})
+	})
+
+	Context("If-Range", func() {
+		It("honors the range for a strongly matching entity tag", func() {
+			cr := parse(map[string]string{"If-Range": `"v1"`})
+			Expect(cr.RangeValid(`"v1"`, modTime)).To(BeTrue())
+			Expect(cr.RangeValid(`"v2"`, modTime)).To(BeFalse())
+			Expect(parse(map[string]string{"If-Range": `W/"v1"`}).RangeValid(`W/"v1"`, modTime)).To(BeFalse())
+		})
+
+		It("honors the range for an exactly matching date", func() {
+			cr := parse(map[string]string{"If-Range": httpDate(modTime)})
+			Expect(cr.RangeValid("", modTime)).To(BeTrue())
+			Expect(cr.RangeValid("", modTime.Add(time.Second))).To(BeFalse())
+			Expect(cr.RangeValid("", time.Time{})).To(BeFalse())
+			Expect(parse(map[string]string{"If-Range": "garbage"}).RangeValid(`"v1"`, modTime)).To(BeFalse())
+		})
+	})
+
+	Context("RequireIfMatch", func() {
+		const current = `"v2"`
+		var r *q.Router
+
+		BeforeEach(func() {
+			r = q.New()
+			h := func(c *q.Context) {
+				if !c.RequireIfMatch(current) {
+					return
+				}
+				c.Status(http.StatusNoContent)
+			}
+			r.PUT("/doc", h)
+			r.DELETE("/doc", h)
+			r.GET("/doc", h)
+		})
+
+		do := func(method, ifMatch string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "/doc", nil)
+			if ifMatch != "" {
+				req.Header.Set("If-Match", ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("allows a write whose If-Match equals the current ETag", func() {
+			Expect(do(http.MethodPut, current).Code).To(Equal(http.StatusNoContent))
+			Expect(do(http.MethodDelete, `"v1", "v2"`).Code).To(Equal(http.StatusNoContent))
+			Expect(do(http.MethodPut, "*").Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("answers 412 when If-Match does not match", func() {
+			rr := do(http.MethodPut, `"v1"`)
+			Expect(rr.Code).To(Equal(http.StatusPreconditionFailed))
+			Expect(rr.Body.String()).To(ContainSubstring(`"code":"precondition_failed"`))
+			Expect(do(http.MethodDelete, `W/"v2"`).Code).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("allows a missing If-Match unless IfMatchRequired is set", func() {
+			Expect(do(http.MethodPut, "").Code).To(Equal(http.StatusNoContent))
+
+			r.IfMatchRequired = true
+			rr := do(http.MethodPut, "")
+			Expect(rr.Code).To(Equal(http.StatusPreconditionRequired))
+			Expect(rr.Body.String()).To(ContainSubstring(`"code":"precondition_required"`))
+		})
+
+		It("ignores safe methods", func() {
+			r.IfMatchRequired = true
+			Expect(do(http.MethodGet, "").Code).To(Equal(http.StatusNoContent))
+			Expect(do(http.MethodGet, `"v1"`).Code).To(Equal(http.StatusNoContent))
+		})
+	})
+})
@@ -20,14 +20,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -42,7 +46,22 @@ type Context struct {
 	status      int
 	wrote       bool
 	maxBodySize int64
-	uploadDir   string // base directory for SaveFile; required for path confinement
+	maxPartSize int64 // per-part multipart limit; see Router.MaxPartSize
+
+	maxMultipartMemory int64    // see Router.MaxMultipartMemory
+	uploadDir          string   // base directory for SaveFile; required for path confinement
+	applied            []string // names recorded by NamedMiddleware, in execution order
+	router             *Router  // dispatching router; used by Forward
+	forwards           int      // number of Forward calls made for this request
+	compression        *CompressionResult
+	decompression      *DecompressionResult
+	debug              bool // enables development-mode checks; see Router.Debug
+	routeMeta          map[string]any
+	routePattern       string
+	query              url.Values      // parsed query string, cached by QueryParams
+	aborted            bool            // set by Abort and Fail; skips the rest of the chain
+	flags              map[string]bool // evaluated by FeatureFlags
+	values             map[string]any  // request-scoped store for Set and Get
 }
 
 func newContext(w http.ResponseWriter, r *http.Request) *Context {
@@ -52,8 +71,204 @@ func newContext(w http.ResponseWriter, r *http.Request) *Context {
 // Param returns the value of a path parameter by name (e.g. ":id").
 func (c *Context) Param(name string) string { return c.params[name] }
 
+// ParamInt parses a path parameter as a base-10 int64. It returns an error if
+// the parameter is missing or not a valid integer.
+func (c *Context) ParamInt(name string) (int64, error) {
+	v, err := c.requiredParam(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("quokka: param %q: %w", name, err)
+	}
+	return n, nil
+}
+
+// ParamUint parses a path parameter as a base-10 uint64. It returns an error
+// if the parameter is missing or not a valid unsigned integer.
+func (c *Context) ParamUint(name string) (uint64, error) {
+	v, err := c.requiredParam(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("quokka: param %q: %w", name, err)
+	}
+	return n, nil
+}
+
+// ParamBool parses a path parameter with strconv.ParseBool (accepting 1, t,
+// true, 0, f, false, and so on). It returns an error if the parameter is
+// missing or not a valid boolean.
+func (c *Context) ParamBool(name string) (bool, error) {
+	v, err := c.requiredParam(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("quokka: param %q: %w", name, err)
+	}
+	return b, nil
+}
+
+// ParamIntDefault is like ParamInt but returns def when the parameter is
+// missing or invalid.
+func (c *Context) ParamIntDefault(name string, def int64) int64 {
+	n, err := c.ParamInt(name)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func (c *Context) requiredParam(name string) (string, error) {
+	v, ok := c.params[name]
+	if !ok || v == "" {
+		return "", fmt.Errorf("quokka: missing param %q", name)
+	}
+	return v, nil
+}
+
+// propagatedHeaders are incoming trace-context headers forwarded by
+// OutgoingHeaders (W3C Trace Context and Baggage).
+var propagatedHeaders = []string{"Traceparent", "Tracestate", "Baggage"}
+
+// OutgoingHeaders returns the correlation and trace headers to copy onto
+// requests this handler makes to other services: X-Request-Id (from the
+// Logger middleware, or the incoming header) and any incoming traceparent,
+// tracestate, and baggage. The returned header is a fresh copy.
+//
+//	req, _ := http.NewRequestWithContext(c.Context(), http.MethodGet, url, nil)
+//	for k, v := range c.OutgoingHeaders() {
+//		req.Header[k] = v
+//	}
+func (c *Context) OutgoingHeaders() http.Header {
+	h := http.Header{}
+	id, ok := RequestID(c.R.Context())
+	if !ok {
+		id = c.R.Header.Get("X-Request-Id")
+	}
+	if id != "" {
+		h.Set("X-Request-Id", id)
+	}
+	for _, k := range propagatedHeaders {
+		if vals := c.R.Header.Values(k); len(vals) > 0 {
+			h[k] = append([]string(nil), vals...)
+		}
+	}
+	return h
+}
+
+// RoutePattern returns the registered path of the matched route (e.g.
+// "/users/:id"), suitable as a low-cardinality metrics label. It is empty
+// when no route matched.
+func (c *Context) RoutePattern() string { return c.routePattern }
+
+// RouteMeta returns the metadata value stored under key by Route.Meta on the
+// matched route. ok is false when the route declared no such key. Metadata is
+// set with Meta on the returned Route rather than a WithMeta option in the
+// middleware list, because a Middleware is an opaque func and cannot carry
+// data to the router without running it at registration time.
+func (c *Context) RouteMeta(key string) (v any, ok bool) {
+	v, ok = c.routeMeta[key]
+	return v, ok
+}
+
 // Query returns a query string parameter value by key.
-func (c *Context) Query(key string) string { return c.R.URL.Query().Get(key) }
+func (c *Context) Query(key string) string { return c.QueryParams().Get(key) }
+
+// QueryParams returns the parsed query string. It is parsed once per request
+// and shared by Query and BindQuery; callers must not modify the result.
+func (c *Context) QueryParams() url.Values {
+	if c.query == nil {
+		c.query = c.R.URL.Query()
+	}
+	return c.query
+}
+
+// Set stores a request-scoped value under key, e.g. the authenticated user
+// computed by middleware. Values live on the Context, not in c.R.Context(),
+// and survive Forward.
+func (c *Context) Set(key string, val any) {
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = val
+}
+
+// Get returns the value stored under key by Set.
+func (c *Context) Get(key string) (any, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// MustGet returns the value stored under key by Set, panicking if none was
+// set.
+func (c *Context) MustGet(key string) any {
+	v, ok := c.values[key]
+	if !ok {
+		panic("quokka: key " + key + " not set on context")
+	}
+	return v
+}
+
+// QueryDefault returns a query string parameter value, or def when it is
+// missing or empty.
+func (c *Context) QueryDefault(key, def string) string {
+	if v := c.Query(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// QueryInt parses a query string parameter as a base-10 int. It returns an
+// error if the parameter is missing or not a valid integer.
+func (c *Context) QueryInt(key string) (int, error) {
+	v, err := c.requiredQuery(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("quokka: query %q: %w", key, err)
+	}
+	return n, nil
+}
+
+// QueryIntDefault is like QueryInt but returns def when the parameter is
+// missing or invalid.
+func (c *Context) QueryIntDefault(key string, def int) int {
+	n, err := c.QueryInt(key)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// QueryBool parses a query string parameter with strconv.ParseBool. It
+// returns an error if the parameter is missing or not a valid boolean.
+func (c *Context) QueryBool(key string) (bool, error) {
+	v, err := c.requiredQuery(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("quokka: query %q: %w", key, err)
+	}
+	return b, nil
+}
+
+func (c *Context) requiredQuery(key string) (string, error) {
+	v := c.Query(key)
+	if v == "" {
+		return "", fmt.Errorf("quokka: missing query parameter %q", key)
+	}
+	return v, nil
+}
 
 // Form returns a form field value by key, parsing the form if necessary.
 func (c *Context) Form(key string) string {
@@ -66,8 +281,48 @@ func (c *Context) Form(key string) string {
 // Header returns a request header value by key.
 func (c *Context) Header(key string) string { return c.R.Header.Get(key) }
 
+// BearerToken returns the token from an "Authorization: Bearer <token>"
+// header. ok is false when the header is missing, uses another scheme, or
+// carries an empty token. The token is not validated; see JWTAuth.
+func (c *Context) BearerToken() (token string, ok bool) {
+	return bearerToken(c.R.Header.Get("Authorization"))
+}
+
+// BasicAuth returns the credentials from an "Authorization: Basic" header.
+// ok is false when the header is missing, uses another scheme, or is not
+// valid base64 "user:pass". The credentials are not checked; see BasicAuth.
+func (c *Context) BasicAuth() (user, pass string, ok bool) { return c.R.BasicAuth() }
+
+// bearerToken parses a Bearer Authorization header value, matching the
+// scheme case-insensitively.
+func bearerToken(authz string) (string, bool) {
+	scheme, token, ok := strings.Cut(authz, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// OriginalURI returns the request-target as originally sent by the client.
+// When an ingress has rewritten the path and supplied X-Original-URI, that
+// value is returned; otherwise it is the unmodified RequestURI, which is also
+// left untouched by Forward.
+func (c *Context) OriginalURI() string {
+	if v := c.R.Header.Get("X-Original-URI"); v != "" {
+		return v
+	}
+	if c.R.RequestURI != "" {
+		return c.R.RequestURI
+	}
+	return c.R.URL.RequestURI()
+}
+
 // BindJSON decodes the request body as JSON into dst.
 // Unknown fields are rejected and the body is limited to MaxBodySize (default 10 MB).
+// If the request context is cancelled or its deadline passes while the body is
+// being read (e.g. under the Timeout middleware), the returned error wraps
+// context.Canceled or context.DeadlineExceeded so handlers can respond with
+// 408 or 499 instead of 400.
 func (c *Context) BindJSON(dst any) error {
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -79,13 +334,67 @@ func (c *Context) BindJSON(dst any) error {
 	if limit <= 0 {
 		limit = 10 << 20 // 10MB default
 	}
-	dec := json.NewDecoder(io.LimitReader(c.R.Body, limit))
+	ctx := c.R.Context()
+	dec := json.NewDecoder(io.LimitReader(ctxReader{ctx: ctx, r: c.R.Body}, limit))
 	dec.DisallowUnknownFields()
-	return dec.Decode(dst)
+	if err := dec.Decode(dst); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("quokka: reading request body: %w", ctxErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// BindXML decodes the request body as XML into dst. The body is limited to
+// MaxBodySize (default 10 MB) and cancellation is reported as for BindJSON.
+func (c *Context) BindXML(dst any) error {
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			slog.Debug("error closing body", slog.String("error", logSanitizer.Replace(err.Error()))) // #nosec G706 -- newlines stripped by logSanitizer
+		}
+	}(c.R.Body)
+	limit := c.maxBodySize
+	if limit <= 0 {
+		limit = 10 << 20 // 10MB default
+	}
+	ctx := c.R.Context()
+	if err := xml.NewDecoder(io.LimitReader(ctxReader{ctx: ctx, r: c.R.Body}, limit)).Decode(dst); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("quokka: reading request body: %w", ctxErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ctxReader fails reads once ctx is done so a cancelled request stops
+// consuming its body. Data that arrives after ctx is done is discarded.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := cr.r.Read(p)
+	if ctxErr := cr.ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
+	return n, err
 }
 
 // JSON serializes v as JSON and writes it with the given status code.
 func (c *Context) JSON(code int, v any) {
+	c.writeJSON(code, v, "application/json; charset=utf-8")
+}
+
+// writeJSON serializes v as JSON and writes it with the given status code
+// and Content-Type.
+func (c *Context) writeJSON(code int, v any, contentType string) {
 	if c.wrote {
 		return
 	}
@@ -97,7 +406,33 @@ func (c *Context) JSON(code int, v any) {
 		c.wrote = true
 		return
 	}
-	c.W.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.W.Header().Set("Content-Type", contentType)
+	c.checkContentType(buf.Bytes())
+	c.status = code
+	c.W.WriteHeader(code)
+	if _, err := c.W.Write(buf.Bytes()); err != nil {
+		slog.Debug("response write error", slog.Any("err", err))
+	}
+	c.wrote = true
+}
+
+// XML serializes v as XML, prefixed with the standard XML declaration, and
+// writes it with the given status code.
+func (c *Context) XML(code int, v any) {
+	if c.wrote {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		slog.Error("XML encoding failed", slog.Any("err", err))
+		c.W.WriteHeader(http.StatusInternalServerError)
+		c.status = http.StatusInternalServerError
+		c.wrote = true
+		return
+	}
+	c.W.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	c.checkContentType(buf.Bytes())
 	c.status = code
 	c.W.WriteHeader(code)
 	if _, err := c.W.Write(buf.Bytes()); err != nil {
@@ -112,6 +447,7 @@ func (c *Context) Text(code int, s string) {
 		return
 	}
 	c.W.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.checkContentType([]byte(s))
 	c.status = code
 	c.W.WriteHeader(code)
 	if _, err := c.W.Write([]byte(s)); err != nil {
@@ -128,6 +464,7 @@ func (c *Context) Bytes(code int, b []byte, contentType string) {
 	if contentType != "" {
 		c.W.Header().Set("Content-Type", contentType)
 	}
+	c.checkContentType(b)
 	c.status = code
 	c.W.WriteHeader(code)
 	if _, err := c.W.Write(b); err != nil {
@@ -136,6 +473,88 @@ func (c *Context) Bytes(code int, b []byte, contentType string) {
 	c.wrote = true
 }
 
+// Fail writes an ErrorResponse with the given status, machine-readable code,
+// and message, then marks the Context aborted. Error is set to the router's
+// StatusMessages entry for status, or the lowercase status text (e.g. "not
+// found"). Later writes are ignored.
+func (c *Context) Fail(status int, code, message string) {
+	c.JSON(status, ErrorResponse{Error: c.statusMessage(status), Code: code, Message: message})
+	c.aborted = true
+}
+
+// serverError writes resp with status as JSON, or the matching page from
+// Router.ErrorPages when the client prefers HTML.
+func (c *Context) serverError(status int, resp ErrorResponse) {
+	if c.router != nil && c.router.ErrorPages != nil && c.Accepts("application/json", "text/html") == "text/html" {
+		if page, ok := errorPage(c.router.ErrorPages, status); ok {
+			c.Bytes(status, page, "text/html; charset=utf-8")
+			return
+		}
+	}
+	c.JSON(status, resp)
+}
+
+// errorPage reads "/<status>.html", or "/5xx.html" for server errors, from
+// pages.
+func errorPage(pages http.FileSystem, status int) ([]byte, bool) {
+	names := []string{"/" + strconv.Itoa(status) + ".html"}
+	if status >= http.StatusInternalServerError {
+		names = append(names, "/5xx.html")
+	}
+	for _, name := range names {
+		f, err := pages.Open(name)
+		if err != nil {
+			continue
+		}
+		b, err := io.ReadAll(f)
+		_ = f.Close()
+		if err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// UnprocessableEntity writes a 422 ErrorResponse with Code "validation_error"
+// and fieldErrors (field name to message) as Details, then marks the Context
+// aborted like Fail.
+func (c *Context) UnprocessableEntity(fieldErrors map[string]string) {
+	c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Error:   c.statusMessage(http.StatusUnprocessableEntity),
+		Code:    "validation_error",
+		Message: "validation failed",
+		Details: fieldErrors,
+	})
+	c.aborted = true
+}
+
+// statusMessage returns the Error text for status: the router's
+// StatusMessages entry if any, else the lowercase status text.
+func (c *Context) statusMessage(status int) string {
+	if c.router != nil {
+		if msg, ok := c.router.StatusMessages[status]; ok {
+			return msg
+		}
+	}
+	return strings.ToLower(http.StatusText(status))
+}
+
+// Abort marks the Context aborted: handlers and middleware further down the
+// chain that have not started yet are skipped. It does not write a response
+// or stop the calling function, and code that runs after next(c) in enclosing
+// middleware (logging, metrics) still executes.
+func (c *Context) Abort() { c.aborted = true }
+
+// AbortWithStatus writes code as the response status and aborts the chain.
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.aborted = true
+}
+
+// IsAborted reports whether Abort, AbortWithStatus, Fail, or
+// UnprocessableEntity has been called for this request.
+func (c *Context) IsAborted() bool { return c.aborted }
+
 // NoContent writes a 204 No Content
 func (c *Context) NoContent() { c.Status(http.StatusNoContent) }
 
@@ -180,6 +599,45 @@ func (c *Context) Cookie(name string) (string, bool) {
 	return v, true
 }
 
+// CookieValue returns the decoded value of the named cookie, or "" if it is
+// absent or cannot be decoded
+func (c *Context) CookieValue(name string) string {
+	v, _ := c.Cookie(name)
+	return v
+}
+
+// Cookies returns all cookies sent with the request. Values are returned as
+// sent, without the decoding applied by Cookie.
+func (c *Context) Cookies() []*http.Cookie { return c.R.Cookies() }
+
+// responseResetter is implemented by ResponseWriter wrappers that buffer the
+// response and can discard it before it reaches the client.
+type responseResetter interface {
+	Reset() error
+}
+
+// Reset discards the response built so far so the handler can write a
+// different one. Headers are cleared, including any set by middleware that
+// ran earlier. Once a status or body has been written, Reset succeeds only
+// when the innermost writer buffers the response (e.g. ETag) and has not
+// sent it yet; otherwise it returns ErrResponseCommitted and leaves the
+// response unchanged. A buffering writer is always asked, so writes made
+// directly to c.W are caught too; such writes cannot be detected when c.W
+// does not buffer, and Reset then succeeds as if nothing was written.
+func (c *Context) Reset() error {
+	if rr, ok := c.W.(responseResetter); ok {
+		if err := rr.Reset(); err != nil {
+			return err
+		}
+	} else if c.wrote {
+		return ErrResponseCommitted
+	}
+	clear(c.W.Header())
+	c.status = 0
+	c.wrote = false
+	return nil
+}
+
 // Status writes only the status code
 func (c *Context) Status(code int) {
 	if c.wrote {
@@ -193,11 +651,7 @@ func (c *Context) Status(code int) {
 // FormFile returns the first file for the provided form key.
 // It parses the multipart form if it has not been parsed yet.
 func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
-	limit := c.maxBodySize
-	if limit <= 0 {
-		limit = 10 << 20
-	}
-	if err := c.R.ParseMultipartForm(limit); err != nil {
+	if err := c.parseMultipartForm(); err != nil {
 		return nil, err
 	}
 	f, fh, err := c.R.FormFile(name)
@@ -211,11 +665,7 @@ func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
 // FormFiles returns all files for the provided form key.
 // It parses the multipart form if it has not been parsed yet.
 func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
-	limit := c.maxBodySize
-	if limit <= 0 {
-		limit = 10 << 20
-	}
-	if err := c.R.ParseMultipartForm(limit); err != nil {
+	if err := c.parseMultipartForm(); err != nil {
 		return nil, err
 	}
 	if c.R.MultipartForm == nil || c.R.MultipartForm.File == nil {
@@ -228,6 +678,96 @@ func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
 	return fhs, nil
 }
 
+// MultipartForm parses a multipart/form-data body and returns the form,
+// holding up to Router.MaxMultipartMemory bytes in memory. Limits from
+// Router.MaxPartSize and the BodyLimit middleware respond 413 when exceeded.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+	return c.R.MultipartForm, nil
+}
+
+// parseMultipartForm parses the multipart form once. A body cut off by
+// BodyLimit responds 413. When a per-part limit is configured, the body is
+// teed through a checker that walks the parts and aborts the stream the
+// moment one exceeds the limit, responding 413 and returning ErrPartTooLarge.
+func (c *Context) parseMultipartForm() error {
+	if c.R.MultipartForm != nil {
+		return nil
+	}
+	err := c.parseMultipartBody()
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+	}
+	return err
+}
+
+func (c *Context) parseMultipartBody() error {
+	limit := c.maxMultipartMemory
+	if limit <= 0 {
+		limit = 32 << 20
+	}
+	if c.maxPartSize <= 0 {
+		return c.R.ParseMultipartForm(limit)
+	}
+	_, params, err := mime.ParseMediaType(c.R.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return c.R.ParseMultipartForm(limit)
+	}
+
+	pr, pw := io.Pipe()
+	body := c.R.Body
+	var checkErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checkErr = checkPartSizes(io.TeeReader(body, pw), params["boundary"], c.maxPartSize)
+		_ = pw.CloseWithError(checkErr)
+	}()
+	c.R.Body = pr
+	err = c.R.ParseMultipartForm(limit)
+	_ = pr.Close()
+	<-done
+	c.R.Body = body
+	if errors.Is(checkErr, ErrPartTooLarge) {
+		if c.R.MultipartForm != nil {
+			_ = c.R.MultipartForm.RemoveAll()
+		}
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "multipart part too large"})
+		return ErrPartTooLarge
+	}
+	return err
+}
+
+// checkPartSizes reads every part of a multipart stream, returning
+// ErrPartTooLarge as soon as one exceeds max bytes. Parse errors are left for
+// the real parser to report, so the rest of the stream is drained.
+func checkPartSizes(r io.Reader, boundary string, max int64) error {
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		n, err := io.CopyN(io.Discard, p, max+1)
+		if n > max {
+			return ErrPartTooLarge
+		}
+		if err != nil && err != io.EOF {
+			break
+		}
+	}
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// SaveUploadedFile is equivalent to SaveFile.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	return c.SaveFile(fh, dst)
+}
+
 // SaveFile copies an uploaded file into the router's configured UploadDir.
 // dst is treated as a relative path within UploadDir. If UploadDir is not
 // configured, dst is used directly after filepath.Clean (callers are then
@@ -276,5 +816,119 @@ func (c *Context) resolveUploadPath(dst string) (string, error) {
 	return absTarget, nil
 }
 
+// maxForwards bounds the number of internal re-dispatches per request so a
+// misconfigured Forward cycle fails fast instead of recursing forever.
+const maxForwards = 10
+
+// Forward re-dispatches the current request to the route registered for
+// method and target without a client round-trip. The request body, headers,
+// and context are preserved; target may include a query string, which replaces
+// the current one. Router-level middleware has already run for this request
+// and is not applied again; the target route's own middleware is. Exceeding
+// the forward depth limit responds with 500.
+func (c *Context) Forward(method, target string) {
+	if c.router == nil {
+		slog.Error("forward without router", slog.String("target", logSanitizer.Replace(target))) // #nosec G706 -- newlines stripped by logSanitizer
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		return
+	}
+	if c.forwards >= maxForwards {
+		slog.Error("forward loop detected", slog.String("target", logSanitizer.Replace(target))) // #nosec G706 -- newlines stripped by logSanitizer
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		return
+	}
+	c.forwards++
+
+	req := c.R.Clone(c.R.Context())
+	req.Method = strings.ToUpper(method)
+	p, rawQuery, hasQuery := strings.Cut(target, "?")
+	req.URL.Path = p
+	req.URL.RawPath = ""
+	if hasQuery {
+		req.URL.RawQuery = rawQuery
+		c.query = nil
+	}
+	c.R = req
+	c.params = map[string]string{}
+	c.routeMeta = nil
+	c.routePattern = ""
+
+	c.router.mu.RLock()
+	h := c.router.resolve(c, p)
+	c.router.mu.RUnlock()
+	h(c)
+}
+
+// AppliedMiddleware returns the names of the NamedMiddleware that have run for
+// this request so far, in execution order. Unnamed middleware is not reported.
+func (c *Context) AppliedMiddleware() []string {
+	out := make([]string, len(c.applied))
+	copy(out, c.applied)
+	return out
+}
+
+// Compression returns the decision recorded by the Gzip middleware for this
+// request. ok is false when no compression middleware ran.
+func (c *Context) Compression() (res CompressionResult, ok bool) {
+	if c.compression == nil {
+		return CompressionResult{}, false
+	}
+	return *c.compression, true
+}
+
+// Decompression returns the accounting recorded by the Decompress middleware
+// for this request's body. ok is false when the body was not decompressed.
+func (c *Context) Decompression() (res DecompressionResult, ok bool) {
+	if c.decompression == nil {
+		return DecompressionResult{}, false
+	}
+	return *c.decompression, true
+}
+
+// checkContentType warns, in debug mode only, when the response Content-Type
+// disagrees with the type sniffed from body (e.g. JSON declared, HTML written).
+func (c *Context) checkContentType(body []byte) {
+	if !c.debug {
+		return
+	}
+	declared := c.W.Header().Get("Content-Type")
+	if sniffed, mismatch := contentTypeMismatch(declared, body); mismatch {
+		slog.Warn("response content type mismatch", slog.String("path", logSanitizer.Replace(c.R.URL.Path)), slog.String("declared", logSanitizer.Replace(declared)), slog.String("sniffed", sniffed)) // #nosec G706 -- newlines stripped by logSanitizer
+	}
+}
+
+// contentTypeMismatch reports whether declared is inconsistent with the type
+// sniffed from body. The sniffer only distinguishes broad families, so any
+// textual declared type is accepted for a text/plain sniff and an
+// application/octet-stream sniff is treated as no opinion.
+func contentTypeMismatch(declared string, body []byte) (string, bool) {
+	if declared == "" || len(body) == 0 {
+		return "", false
+	}
+	sniffed := http.DetectContentType(body)
+	st, _, _ := mime.ParseMediaType(sniffed)
+	dt, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return sniffed, true
+	}
+	switch {
+	case st == dt, st == "application/octet-stream":
+		return sniffed, false
+	case st == "text/plain":
+		return sniffed, !isTextualType(dt)
+	case st == "text/xml":
+		return sniffed, !strings.HasSuffix(dt, "xml")
+	}
+	return sniffed, true
+}
+
+func isTextualType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		strings.HasSuffix(mediaType, "javascript") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
 // Context returns the request's context.Context.
 func (c *Context) Context() context.Context { return c.R.Context() }
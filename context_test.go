@@ -18,13 +18,21 @@ package quokka_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -167,6 +175,80 @@ var _ = Describe("Context", func() {
 		Expect(rr.Code).To(Equal(http.StatusBadRequest))
 	})
 
+	It("decodes a body within a custom MaxBodySize in BindJSON", func() {
+		r := q.New()
+		r.MaxBodySize = 64
+		type X struct {
+			A string `json:"a"`
+		}
+		r.POST("/bind", func(c *q.Context) {
+			var x X
+			if err := c.BindJSON(&x); err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.Text(http.StatusOK, x.A)
+		})
+
+		body := `{"a":"` + strings.Repeat("x", 50) + `"}`
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bind", bytes.NewBufferString(body)))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal(strings.Repeat("x", 50)))
+
+		body = `{"a":"` + strings.Repeat("x", 60) + `"}`
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bind", bytes.NewBufferString(body)))
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("binds an XML body with BindXML", func() {
+		type order struct {
+			XMLName xml.Name `xml:"order"`
+			ID      int      `xml:"id,attr"`
+			Item    string   `xml:"item"`
+			Qty     int      `xml:"qty"`
+		}
+		var got order
+		r := q.New()
+		r.POST("/bind", func(c *q.Context) {
+			if err := c.BindXML(&got); err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+		body := `<?xml version="1.0"?><order id="9"><item>widget</item><qty>3</qty></order>`
+		req := httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/xml")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(got.ID).To(Equal(9))
+		Expect(got.Item).To(Equal("widget"))
+		Expect(got.Qty).To(Equal(3))
+	})
+
+	It("rejects oversized body in BindXML with custom MaxBodySize", func() {
+		r := q.New()
+		r.MaxBodySize = 16
+		type X struct {
+			A string `xml:"a"`
+		}
+		r.POST("/bind", func(c *q.Context) {
+			var x X
+			if err := c.BindXML(&x); err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: "too large"})
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+		bigBody := `<X><a>` + strings.Repeat("x", 100) + `</a></X>`
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/bind", bytes.NewBufferString(bigBody)))
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+	})
+
 	It("prevents double-write: JSON then Text is silently ignored", func() {
 		r := q.New()
 		r.GET("/dw", func(c *q.Context) {
@@ -245,6 +327,127 @@ var _ = Describe("Context", func() {
 		Expect(rr.Header().Get("X-Custom")).To(Equal("resp"))
 	})
 
+	It("reads multiple cookies at once", func() {
+		r := q.New()
+		r.GET("/c", func(c *q.Context) {
+			names := []string{}
+			for _, ck := range c.Cookies() {
+				names = append(names, ck.Name)
+			}
+			c.JSON(http.StatusOK, map[string]any{
+				"names":   names,
+				"session": c.CookieValue("session"),
+				"theme":   c.CookieValue("theme"),
+				"missing": c.CookieValue("missing"),
+			})
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/c", nil)
+		req.Header.Set("Cookie", "session=abc123; theme=dark%20mode; lang=en")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(MatchJSON(`{"names":["session","theme","lang"],"session":"abc123","theme":"dark mode","missing":""}`))
+	})
+
+	It("parses bearer and basic credentials from Authorization", func() {
+		type creds struct {
+			Token      string
+			BearerOK   bool
+			User, Pass string
+			BasicOK    bool
+		}
+		read := func(authz string) creds {
+			var got creds
+			r := q.New()
+			r.GET("/auth", func(c *q.Context) {
+				got.Token, got.BearerOK = c.BearerToken()
+				got.User, got.Pass, got.BasicOK = c.BasicAuth()
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+			if authz != "" {
+				req.Header.Set("Authorization", authz)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			return got
+		}
+
+		Expect(read("Bearer abc.def.ghi")).To(Equal(creds{Token: "abc.def.ghi", BearerOK: true}))
+		Expect(read("bearer xyz")).To(Equal(creds{Token: "xyz", BearerOK: true}))
+		basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cr:et"))
+		Expect(read(basic)).To(Equal(creds{User: "alice", Pass: "s3cr:et", BasicOK: true}))
+
+		for _, malformed := range []string{"", "Bearer", "Bearer ", "Token abc", "Basic", "Basic !!!notbase64", "Basic " + base64.StdEncoding.EncodeToString([]byte("nocolon"))} {
+			Expect(read(malformed)).To(Equal(creds{}), malformed)
+		}
+	})
+
+	Describe("Reset", func() {
+		It("discards headers set before the first write", func() {
+			r := q.New()
+			r.GET("/r", func(c *q.Context) {
+				c.SetHeader("X-Draft", "1")
+				c.SetHeader("Content-Type", "text/csv")
+				Expect(c.Reset()).To(Succeed())
+				c.SetHeader("X-Final", "1")
+				c.JSON(http.StatusCreated, map[string]string{"v": "final"})
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/r", nil))
+			Expect(rr.Code).To(Equal(http.StatusCreated))
+			Expect(rr.Header().Get("X-Draft")).To(BeEmpty())
+			Expect(rr.Header().Get("X-Final")).To(Equal("1"))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			Expect(rr.Body.String()).To(MatchJSON(`{"v":"final"}`))
+		})
+
+		It("replaces a written response held by a buffering writer", func() {
+			r := q.New()
+			r.Use(q.ETag(q.ETagConfig{}))
+			r.GET("/r", func(c *q.Context) {
+				c.Text(http.StatusOK, "draft")
+				Expect(c.Reset()).To(Succeed())
+				c.Text(http.StatusAccepted, "final")
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/r", nil))
+			Expect(rr.Code).To(Equal(http.StatusAccepted))
+			Expect(rr.Body.String()).To(Equal("final"))
+		})
+
+		It("detects a direct write that a buffering writer already sent", func() {
+			var err error
+			r := q.New()
+			r.Use(q.ETag(q.ETagConfig{}))
+			r.GET("/r", func(c *q.Context) {
+				c.W.WriteHeader(http.StatusNotFound)
+				_, _ = c.W.Write([]byte("sent"))
+				err = c.Reset()
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/r", nil))
+			Expect(err).To(MatchError(q.ErrResponseCommitted))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+			Expect(rr.Body.String()).To(Equal("sent"))
+		})
+
+		It("returns ErrResponseCommitted once the response was sent", func() {
+			var err error
+			r := q.New()
+			r.GET("/r", func(c *q.Context) {
+				c.Text(http.StatusOK, "sent")
+				err = c.Reset()
+				c.Text(http.StatusTeapot, "ignored")
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/r", nil))
+			Expect(err).To(MatchError(q.ErrResponseCommitted))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("sent"))
+		})
+	})
+
 	It("returns false for missing cookie", func() {
 		r := q.New()
 		r.GET("/c", func(c *q.Context) {
@@ -374,4 +577,523 @@ var _ = Describe("Context", func() {
 		r.ServeHTTP(rr, req)
 		Expect(rr.Code).To(Equal(http.StatusBadRequest))
 	})
+
+	It("MultipartForm and SaveUploadedFile handle a full upload", func() {
+		dir := GinkgoT().TempDir()
+		r := q.New()
+		r.UploadDir = dir
+		r.MaxMultipartMemory = 1024
+		r.POST("/upload", func(c *q.Context) {
+			form, err := c.MultipartForm()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			fh := form.File["doc"][0]
+			if err := c.SaveUploadedFile(fh, "saved-"+fh.Filename); err != nil {
+				c.JSON(http.StatusInternalServerError, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.Text(http.StatusOK, form.Value["title"][0])
+		})
+
+		content := bytes.Repeat([]byte("0123456789"), 500) // larger than MaxMultipartMemory
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("title", "report")
+		fw, _ := mw.CreateFormFile("doc", "report.txt")
+		_, _ = fw.Write(content)
+		_ = mw.Close()
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("report"))
+		saved, err := os.ReadFile(filepath.Join(dir, "saved-report.txt"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved).To(Equal(content))
+	})
+
+	It("responds 413 when a multipart body exceeds BodyLimit", func() {
+		for _, partLimit := range []int64{0, 1 << 20} {
+			r := q.New()
+			r.MaxPartSize = partLimit
+			r.POST("/upload", func(c *q.Context) {
+				if _, err := c.MultipartForm(); err != nil {
+					return
+				}
+				c.Status(http.StatusOK)
+			}, q.BodyLimit(512))
+
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			fw, _ := mw.CreateFormFile("doc", "big.bin")
+			_, _ = fw.Write(bytes.Repeat([]byte("a"), 4096))
+			_ = mw.Close()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge), "MaxPartSize=%d", partLimit)
+		}
+	})
+
+	It("rejects an oversized multipart part with 413 before reading it fully", func() {
+		r := q.New()
+		r.MaxBodySize = 100 << 20
+		r.MaxPartSize = 1024
+		var gotErr error
+		r.POST("/upload", func(c *q.Context) {
+			_, gotErr = c.FormFile("file")
+			if gotErr != nil {
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+
+		boundary := "xyz"
+		head := "--xyz\r\nContent-Disposition: form-data; name=\"file\"; filename=\"big.bin\"\r\n\r\n"
+		tail := "\r\n--xyz--\r\n"
+		body := &countingBody{r: io.MultiReader(strings.NewReader(head), io.LimitReader(zeroReader{}, 50<<20), strings.NewReader(tail))}
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(gotErr).To(MatchError(q.ErrPartTooLarge))
+		Expect(body.n).To(BeNumerically("<", 1<<20))
+	})
+
+	It("accepts multipart parts within MaxPartSize", func() {
+		r := q.New()
+		r.MaxPartSize = 1024
+		r.POST("/upload", func(c *q.Context) {
+			fh, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			c.Text(http.StatusOK, fh.Filename+" "+c.R.FormValue("note"))
+		})
+
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("note", "hi")
+		fw, _ := mw.CreateFormFile("file", "small.txt")
+		_, _ = fw.Write(bytes.Repeat([]byte("a"), 1024))
+		_ = mw.Close()
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("small.txt hi"))
+	})
+
+	It("Forward re-dispatches to another route without a redirect", func() {
+		r := q.New()
+		r.POST("/new/:id", func(c *q.Context) {
+			var body map[string]string
+			Expect(c.BindJSON(&body)).To(Succeed())
+			c.JSON(http.StatusOK, map[string]string{"id": c.Param("id"), "name": body["name"], "v": c.Query("v")})
+		})
+		r.POST("/old/:id", func(c *q.Context) { c.Forward(http.MethodPost, "/new/"+c.Param("id")+"?v=2") })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/old/7", strings.NewReader(`{"name":"widget"}`))
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		var m map[string]string
+		Expect(json.Unmarshal(rr.Body.Bytes(), &m)).To(Succeed())
+		Expect(m).To(Equal(map[string]string{"id": "7", "name": "widget", "v": "2"}))
+	})
+
+	It("Forward returns 404 for an unknown target", func() {
+		r := q.New()
+		r.GET("/old", func(c *q.Context) { c.Forward(http.MethodGet, "/missing") })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/old", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Forward stops forwarding loops with a 500", func() {
+		r := q.New()
+		calls := 0
+		r.GET("/a", func(c *q.Context) { calls++; c.Forward(http.MethodGet, "/b") })
+		r.GET("/b", func(c *q.Context) { calls++; c.Forward(http.MethodGet, "/a") })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/a", nil))
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(calls).To(Equal(11))
+	})
+
+	It("OriginalURI returns the client request-target", func() {
+		r := q.New()
+		var seen string
+		r.GET("/new", func(c *q.Context) { seen = c.OriginalURI(); c.Status(http.StatusOK) })
+		r.GET("/items/:id", func(c *q.Context) { seen = c.OriginalURI(); c.Forward(http.MethodGet, "/new") })
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1?x=y", nil))
+		Expect(seen).To(Equal("/items/1?x=y"))
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/2", nil))
+		Expect(seen).To(Equal("/items/2"))
+	})
+
+	It("OriginalURI prefers X-Original-URI from an ingress", func() {
+		r := q.New()
+		var seen string
+		r.GET("/items", func(c *q.Context) { seen = c.OriginalURI(); c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("X-Original-URI", "/api/v1/items?page=2")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(seen).To(Equal("/api/v1/items?page=2"))
+	})
+
+	Describe("Debug content type check", func() {
+		var buf bytes.Buffer
+		var prev *slog.Logger
+
+		BeforeEach(func() {
+			buf.Reset()
+			prev = slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		})
+
+		AfterEach(func() { slog.SetDefault(prev) })
+
+		It("warns when the declared type does not match the body in debug mode", func() {
+			r := q.New()
+			r.Debug = true
+			r.GET("/m", func(c *q.Context) {
+				c.Bytes(http.StatusOK, []byte("<!DOCTYPE html><html><body>hi</body></html>"), "application/json")
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/m", nil))
+			Expect(buf.String()).To(ContainSubstring("response content type mismatch"))
+			Expect(buf.String()).To(ContainSubstring("sniffed=\"text/html"))
+		})
+
+		It("does not warn for consistent responses", func() {
+			r := q.New()
+			r.Debug = true
+			r.GET("/j", func(c *q.Context) { c.JSON(http.StatusOK, map[string]int{"a": 1}) })
+			r.GET("/t", func(c *q.Context) { c.Text(http.StatusOK, "hello") })
+			r.GET("/p", func(c *q.Context) { c.Bytes(http.StatusOK, []byte("\x89PNG\r\n\x1a\n0000"), "image/png") })
+			for _, p := range []string{"/j", "/t", "/p"} {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+			}
+			Expect(buf.String()).To(BeEmpty())
+		})
+
+		It("does not check when Debug is off", func() {
+			r := q.New()
+			r.GET("/m", func(c *q.Context) {
+				c.Bytes(http.StatusOK, []byte("<!DOCTYPE html><html></html>"), "application/json")
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/m", nil))
+			Expect(buf.String()).To(BeEmpty())
+		})
+	})
+
+	It("BindJSON reports a cancelled request context distinctly", func() {
+		r := q.New()
+		var bindErr error
+		r.POST("/slow", func(c *q.Context) {
+			var x map[string]any
+			bindErr = c.BindJSON(&x)
+			switch {
+			case errors.Is(bindErr, context.DeadlineExceeded):
+				c.Status(http.StatusRequestTimeout)
+			case bindErr != nil:
+				c.Status(http.StatusBadRequest)
+			}
+		}, q.Timeout(10*time.Millisecond))
+
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = pw.Write([]byte(`{"a":`))
+			time.Sleep(50 * time.Millisecond)
+			_, _ = pw.Write([]byte(`1}`))
+			_ = pw.Close()
+		}()
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/slow", pr))
+		Expect(rr.Code).To(Equal(http.StatusRequestTimeout))
+		Expect(errors.Is(bindErr, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("BindJSON wraps context.Canceled for an already cancelled request", func() {
+		r := q.New()
+		var bindErr error
+		r.POST("/c", func(c *q.Context) {
+			var x map[string]any
+			bindErr = c.BindJSON(&x)
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodPost, "/c", strings.NewReader(`{"a":1}`)).WithContext(ctx)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(errors.Is(bindErr, context.Canceled)).To(BeTrue())
+	})
+
+	It("OutgoingHeaders carries the request id and trace context", func() {
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: io.Discard}))
+		var out http.Header
+		r.GET("/call", func(c *q.Context) { out = c.OutgoingHeaders(); c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodGet, "/call", nil)
+		req.Header.Set("X-Request-Id", "req-123")
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set("tracestate", "vendor=1")
+		req.Header.Set("Authorization", "Bearer secret")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		Expect(out.Get("X-Request-Id")).To(Equal("req-123"))
+		Expect(out.Get("Traceparent")).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+		Expect(out.Get("Tracestate")).To(Equal("vendor=1"))
+		Expect(out.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("OutgoingHeaders includes a generated request id", func() {
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: io.Discard}))
+		var out http.Header
+		var id string
+		r.GET("/gen", func(c *q.Context) {
+			id, _ = q.RequestID(c.Context())
+			out = c.OutgoingHeaders()
+			c.Status(http.StatusOK)
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/gen", nil))
+		Expect(id).NotTo(BeEmpty())
+		Expect(out.Get("X-Request-Id")).To(Equal(id))
+	})
+
+	It("writes XML with content type and round-trips a struct", func() {
+		type book struct {
+			XMLName xml.Name `xml:"book"`
+			ID      int      `xml:"id,attr"`
+			Title   string   `xml:"title"`
+		}
+		r := q.New()
+		r.GET("/x", func(c *q.Context) {
+			c.XML(http.StatusOK, book{ID: 7, Title: "Go"})
+			c.XML(http.StatusTeapot, book{ID: 8}) // ignored: already written
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Type")).To(Equal("application/xml; charset=utf-8"))
+		Expect(rr.Body.String()).To(HavePrefix("<?xml"))
+		var got book
+		Expect(xml.Unmarshal(rr.Body.Bytes(), &got)).To(Succeed())
+		Expect(got.ID).To(Equal(7))
+		Expect(got.Title).To(Equal("Go"))
+	})
+
+	It("XML returns 500 when encoding fails", func() {
+		r := q.New()
+		r.GET("/bad", func(c *q.Context) { c.XML(http.StatusOK, map[string]string{"a": "b"}) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bad", nil))
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("Fail writes a standard ErrorResponse and suppresses later writes", func() {
+		var aborted bool
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) { next(c); aborted = c.IsAborted() }
+		})
+		r.GET("/x", func(c *q.Context) {
+			c.Fail(http.StatusNotFound, "todo_not_found", "todo 7 does not exist")
+			c.JSON(http.StatusOK, map[string]string{"ignored": "yes"})
+			c.Text(http.StatusOK, "ignored")
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"not found","code":"todo_not_found","message":"todo 7 does not exist"}`))
+		Expect(aborted).To(BeTrue())
+	})
+
+	Describe("typed param accessors", func() {
+		run := func(pattern, target string, fn func(c *q.Context)) {
+			r := q.New()
+			r.GET(pattern, func(c *q.Context) { fn(c); c.Status(http.StatusOK) })
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		}
+
+		It("converts valid values", func() {
+			run("/items/:id/:n/:flag", "/items/-42/7/true", func(c *q.Context) {
+				id, err := c.ParamInt("id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(int64(-42)))
+				n, err := c.ParamUint("n")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(n).To(Equal(uint64(7)))
+				flag, err := c.ParamBool("flag")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flag).To(BeTrue())
+			})
+		})
+
+		It("returns a clear error for invalid input", func() {
+			run("/items/:id", "/items/abc", func(c *q.Context) {
+				_, err := c.ParamInt("id")
+				Expect(err).To(MatchError(ContainSubstring(`param "id"`)))
+				Expect(errors.Is(err, strconv.ErrSyntax)).To(BeTrue())
+				_, err = c.ParamUint("id")
+				Expect(err).To(HaveOccurred())
+				_, err = c.ParamBool("id")
+				Expect(err).To(HaveOccurred())
+			})
+			run("/items/:id", "/items/-1", func(c *q.Context) {
+				_, err := c.ParamUint("id")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("reports missing params and falls back to the default", func() {
+			run("/items", "/items", func(c *q.Context) {
+				_, err := c.ParamInt("id")
+				Expect(err).To(MatchError(`quokka: missing param "id"`))
+				Expect(c.ParamIntDefault("id", 10)).To(Equal(int64(10)))
+			})
+			run("/items/:id", "/items/x", func(c *q.Context) {
+				Expect(c.ParamIntDefault("id", 10)).To(Equal(int64(10)))
+			})
+			run("/items/:id", "/items/5", func(c *q.Context) {
+				Expect(c.ParamIntDefault("id", 10)).To(Equal(int64(5)))
+			})
+		})
+	})
+
+	Describe("typed query accessors", func() {
+		run := func(target string, fn func(c *q.Context)) {
+			r := q.New()
+			r.GET("/list", func(c *q.Context) { fn(c); c.Status(http.StatusOK) })
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		}
+
+		It("QueryInt parses valid values and errors on missing or invalid ones", func() {
+			run("/list?limit=25&bad=x", func(c *q.Context) {
+				n, err := c.QueryInt("limit")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(n).To(Equal(25))
+				_, err = c.QueryInt("bad")
+				Expect(err).To(MatchError(ContainSubstring(`query "bad"`)))
+				_, err = c.QueryInt("offset")
+				Expect(err).To(MatchError(`quokka: missing query parameter "offset"`))
+			})
+		})
+
+		It("QueryIntDefault returns the default for missing and unparseable values", func() {
+			run("/list?limit=25&bad=x&empty=", func(c *q.Context) {
+				Expect(c.QueryIntDefault("limit", 10)).To(Equal(25))
+				Expect(c.QueryIntDefault("bad", 10)).To(Equal(10))
+				Expect(c.QueryIntDefault("empty", 10)).To(Equal(10))
+				Expect(c.QueryIntDefault("offset", 10)).To(Equal(10))
+			})
+		})
+
+		It("QueryBool parses booleans", func() {
+			run("/list?done=true&off=0&bad=maybe", func(c *q.Context) {
+				b, err := c.QueryBool("done")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(b).To(BeTrue())
+				b, err = c.QueryBool("off")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(b).To(BeFalse())
+				_, err = c.QueryBool("bad")
+				Expect(err).To(HaveOccurred())
+				_, err = c.QueryBool("missing")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("QueryDefault falls back for missing or empty values", func() {
+			run("/list?sort=name&empty=", func(c *q.Context) {
+				Expect(c.QueryDefault("sort", "id")).To(Equal("name"))
+				Expect(c.QueryDefault("empty", "id")).To(Equal("id"))
+				Expect(c.QueryDefault("order", "asc")).To(Equal("asc"))
+			})
+		})
+	})
+
+	It("Set stores values that handlers read with Get and MustGet", func() {
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) {
+				c.Set("tenant", "acme")
+				next(c)
+			}
+		})
+		var tenant any
+		var ok, missingOK bool
+		r.GET("/x", func(c *q.Context) {
+			tenant, ok = c.Get("tenant")
+			_, missingOK = c.Get("user")
+			c.Text(http.StatusOK, c.MustGet("tenant").(string))
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+		Expect(rr.Body.String()).To(Equal("acme"))
+		Expect(ok).To(BeTrue())
+		Expect(tenant).To(Equal("acme"))
+		Expect(missingOK).To(BeFalse())
+	})
+
+	It("MustGet panics on a missing key", func() {
+		r := q.New()
+		r.GET("/x", func(c *q.Context) {
+			defer GinkgoRecover()
+			Expect(func() { c.MustGet("user") }).To(Panic())
+			c.Status(http.StatusOK)
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("UnprocessableEntity writes a 422 with per-field details", func() {
+		r := q.New()
+		r.POST("/users", func(c *q.Context) {
+			c.UnprocessableEntity(map[string]string{"email": "must be a valid address", "age": "must be positive"})
+			c.Text(http.StatusOK, "ignored")
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users", nil))
+		Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rr.Body.String()).To(MatchJSON(`{
+			"error": "unprocessable entity",
+			"code": "validation_error",
+			"message": "validation failed",
+			"details": {"email": "must be a valid address", "age": "must be positive"}
+		}`))
+	})
 })
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type countingBody struct {
+	r io.Reader
+	n int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += n
+	return n, err
+}
@@ -26,8 +26,21 @@ import (
 type CORSConfig struct {
 	// AllowOrigins is the list of origins permitted to make cross-origin requests.
 	// Use ["*"] to allow all origins. Default: ["*"].
+	//
+	// An entry may use a wildcard subdomain after the scheme, such as
+	// "https://*.example.com". It matches origins with that exact scheme and
+	// port and one or more subdomain labels, e.g. https://app.example.com,
+	// but not https://example.com itself (list that separately),
+	// http://app.example.com, or https://evilexample.com. Matching origins
+	// are reflected in Access-Control-Allow-Origin.
 	AllowOrigins []string
 
+	// AllowOriginFunc, when set, decides which origins are allowed instead of
+	// AllowOrigins, e.g. by looking up per-tenant origins. Allowed origins
+	// are reflected in Access-Control-Allow-Origin. It is called for every
+	// request carrying an Origin header, so it should be fast.
+	AllowOriginFunc func(origin string) bool
+
 	// AllowMethods is the list of HTTP methods allowed for cross-origin requests.
 	// Default: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS.
 	AllowMethods []string
@@ -49,6 +62,15 @@ type CORSConfig struct {
 	// When true and AllowOrigins contains "*", the middleware reflects the
 	// actual request Origin instead of emitting "*" (per the CORS spec).
 	AllowCredentials bool
+
+	// RejectDisallowed, when true, answers preflight requests from origins
+	// not in AllowOrigins with 403 Forbidden instead of passing them through
+	// to the route. Default: false (spec-compliant passthrough).
+	RejectDisallowed bool
+
+	// OptionsSuccessStatus is the status of successful preflight responses.
+	// Some legacy browsers mishandle 204 and need 200. Default: 204.
+	OptionsSuccessStatus int
 }
 
 // DefaultCORSConfig returns a CORSConfig with sensible defaults.
@@ -71,9 +93,10 @@ func DefaultCORSConfig() CORSConfig {
 			"Authorization",
 			"X-Request-Id",
 		},
-		ExposeHeaders:    []string{},
-		MaxAge:           86400,
-		AllowCredentials: false,
+		ExposeHeaders:        []string{},
+		MaxAge:               86400,
+		AllowCredentials:     false,
+		OptionsSuccessStatus: http.StatusNoContent,
 	}
 }
 
@@ -84,7 +107,14 @@ func CORS(cfg CORSConfig) Middleware {
 	allowHeadersStr := strings.Join(cfg.AllowHeaders, ", ")
 	exposeHeadersStr := strings.Join(cfg.ExposeHeaders, ", ")
 	maxAgeStr := strconv.Itoa(cfg.MaxAge)
-	allowAll := len(cfg.AllowOrigins) == 1 && cfg.AllowOrigins[0] == "*"
+	if cfg.OptionsSuccessStatus == 0 {
+		cfg.OptionsSuccessStatus = http.StatusNoContent
+	}
+	allowAll := cfg.AllowOriginFunc == nil && len(cfg.AllowOrigins) == 1 && cfg.AllowOrigins[0] == "*"
+	allowed := cfg.AllowOriginFunc
+	if allowed == nil {
+		allowed = func(origin string) bool { return originAllowed(strings.ToLower(origin), cfg.AllowOrigins) }
+	}
 
 	return func(next Handler) Handler {
 		return func(c *Context) {
@@ -94,7 +124,11 @@ func CORS(cfg CORSConfig) Middleware {
 				return
 			}
 
-			if !allowAll && !originAllowed(origin, cfg.AllowOrigins) {
+			if !allowAll && !allowed(origin) {
+				if cfg.RejectDisallowed && isPreflight(c.R) {
+					c.JSON(http.StatusForbidden, ErrorResponse{Error: "origin not allowed"})
+					return
+				}
 				next(c)
 				return
 			}
@@ -105,8 +139,7 @@ func CORS(cfg CORSConfig) Middleware {
 			}
 
 			// Preflight request
-			if c.R.Method == http.MethodOptions &&
-				c.R.Header.Get("Access-Control-Request-Method") != "" {
+			if isPreflight(c.R) {
 				h := c.W.Header()
 				h.Set("Access-Control-Allow-Origin", allowOriginValue)
 				h.Set("Access-Control-Allow-Methods", allowMethodsStr)
@@ -117,8 +150,8 @@ func CORS(cfg CORSConfig) Middleware {
 				if cfg.AllowCredentials {
 					h.Set("Access-Control-Allow-Credentials", "true")
 				}
-				h.Set("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
-				c.Status(http.StatusNoContent)
+				addVary(h, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+				c.Status(cfg.OptionsSuccessStatus)
 				return
 			}
 
@@ -131,17 +164,75 @@ func CORS(cfg CORSConfig) Middleware {
 			if exposeHeadersStr != "" {
 				h.Set("Access-Control-Expose-Headers", exposeHeadersStr)
 			}
-			h.Add("Vary", "Origin")
+			addVary(h, "Origin")
 			next(c)
 		}
 	}
 }
 
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// addVary merges names into the Vary header of h, keeping a single
+// comma-separated value without duplicates (compared case-insensitively).
+// A Vary of "*" is left alone.
+func addVary(h http.Header, names ...string) {
+	var vary []string
+	seen := make(map[string]bool)
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name != "" && !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				vary = append(vary, name)
+			}
+		}
+	}
+	for _, name := range names {
+		if !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			vary = append(vary, name)
+		}
+	}
+	h.Set("Vary", strings.Join(vary, ", "))
+}
+
+// originAllowed reports whether the lowercased origin matches an entry of
+// allowed, including "scheme://*.domain" wildcard entries.
 func originAllowed(origin string, allowed []string) bool {
 	for _, a := range allowed {
+		a = strings.ToLower(a)
 		if a == "*" || a == origin {
 			return true
 		}
+		scheme, domain, ok := strings.Cut(a, "://*.")
+		if !ok {
+			continue
+		}
+		prefix, suffix := scheme+"://", "."+domain
+		if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+			isSubdomain(origin[len(prefix):len(origin)-len(suffix)]) {
+			return true
+		}
 	}
 	return false
 }
+
+// isSubdomain reports whether s is one or more dot-separated host labels.
+func isSubdomain(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -19,6 +19,7 @@ package quokka_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -225,4 +226,146 @@ var _ = Describe("CORS Middleware", func() {
 		Expect(vary).To(ContainSubstring("Access-Control-Request-Method"))
 		Expect(vary).To(ContainSubstring("Access-Control-Request-Headers"))
 	})
+
+	It("rejects preflight from disallowed origin with 403 when RejectDisallowed is set", func() {
+		cfg := q.DefaultCORSConfig()
+		cfg.AllowOrigins = []string{"http://allowed.com"}
+		cfg.RejectDisallowed = true
+		r := q.New()
+		r.Use(q.CORS(cfg))
+		handlerCalled := false
+		r.OPTIONS("/api", func(c *q.Context) { handlerCalled = true; c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/api", nil)
+		req.Header.Set("Origin", "http://evil.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusForbidden))
+		Expect(rr.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+		Expect(handlerCalled).To(BeFalse())
+	})
+
+	It("passes non-preflight requests from disallowed origin through when RejectDisallowed is set", func() {
+		cfg := q.DefaultCORSConfig()
+		cfg.AllowOrigins = []string{"http://allowed.com"}
+		cfg.RejectDisallowed = true
+		r := q.New()
+		r.Use(q.CORS(cfg))
+		r.GET("/api", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("Origin", "http://evil.com")
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+	})
+
+	It("matches wildcard subdomain origins by scheme and suffix", func() {
+		cfg := q.DefaultCORSConfig()
+		cfg.AllowOrigins = []string{"https://*.example.com"}
+		r := q.New()
+		r.Use(q.CORS(cfg))
+		r.GET("/api", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
+
+		allowOrigin := func(origin string) string {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Origin", origin)
+			r.ServeHTTP(rr, req)
+			return rr.Header().Get("Access-Control-Allow-Origin")
+		}
+
+		Expect(allowOrigin("https://app.example.com")).To(Equal("https://app.example.com"))
+		Expect(allowOrigin("https://a.b.example.com")).To(Equal("https://a.b.example.com"))
+		Expect(allowOrigin("https://example.com")).To(BeEmpty())
+		Expect(allowOrigin("https://evilexample.com")).To(BeEmpty())
+		Expect(allowOrigin("http://app.example.com")).To(BeEmpty())
+		Expect(allowOrigin("https://app.example.com.evil.net")).To(BeEmpty())
+		Expect(allowOrigin("https://app.example.com:8443")).To(BeEmpty())
+		Expect(allowOrigin("https://evil.net/.example.com")).To(BeEmpty())
+	})
+
+	It("uses AllowOriginFunc instead of AllowOrigins when set", func() {
+		cfg := q.DefaultCORSConfig()
+		cfg.AllowCredentials = true
+		cfg.AllowOriginFunc = func(origin string) bool { return strings.HasSuffix(origin, ".tenant.example") }
+		r := q.New()
+		r.Use(q.CORS(cfg))
+		r.GET("/api", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
+
+		do := func(origin string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Origin", origin)
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		rr := do("https://acme.tenant.example")
+		Expect(rr.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://acme.tenant.example"))
+		Expect(rr.Header().Get("Access-Control-Allow-Credentials")).To(Equal("true"))
+
+		// AllowOrigins is ["*"] by default, but the func takes precedence.
+		rr = do("https://other.example")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+	})
+
+	It("combines Vary values from CORS and Gzip into one de-duplicated header", func() {
+		r := q.New()
+		r.Use(
+			func(next q.Handler) q.Handler {
+				return func(c *q.Context) {
+					c.W.Header().Add("Vary", "origin")
+					next(c)
+				}
+			},
+			q.CORS(q.DefaultCORSConfig()),
+			q.Gzip(q.GzipConfig{}),
+		)
+		r.GET("/api", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Accept-Encoding", "gzip")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Header().Values("Vary")).To(Equal([]string{"origin, Accept-Encoding"}))
+	})
+
+	It("answers preflights with OptionsSuccessStatus and merges Vary", func() {
+		cfg := q.DefaultCORSConfig()
+		cfg.OptionsSuccessStatus = http.StatusOK
+		r := q.New()
+		r.Use(
+			func(next q.Handler) q.Handler {
+				return func(c *q.Context) {
+					c.W.Header().Add("Vary", "Accept-Encoding")
+					next(c)
+				}
+			},
+			q.CORS(cfg),
+		)
+		r.POST("/api", func(c *q.Context) { c.Status(http.StatusCreated) })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/api", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Values("Vary")).To(Equal([]string{"Accept-Encoding, Origin, Access-Control-Request-Method, Access-Control-Request-Headers"}))
+
+		cfg.OptionsSuccessStatus = 0
+		r = q.New()
+		r.Use(q.CORS(cfg))
+		r.POST("/api", func(c *q.Context) { c.Status(http.StatusCreated) })
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusNoContent))
+	})
 })
@@ -0,0 +1,170 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DecompressConfig configures the Decompress middleware.
+type DecompressConfig struct {
+	// MaxRatio caps the decompressed body at this multiple of the router's
+	// MaxBodySize (10 MB when unset). Reading past the cap aborts the request
+	// with 413, guarding against decompression bombs. Default: 10.
+	MaxRatio int64
+}
+
+// DecompressionResult records the request-body decompression performed by the
+// Decompress middleware. Retrieve it with Context.Decompression after the
+// body has been read.
+type DecompressionResult struct {
+	// Encoding is the request content coding that was removed (e.g. "gzip").
+	Encoding string
+
+	// BytesIn is the number of compressed bytes read from the client.
+	BytesIn int64
+
+	// BytesOut is the number of decompressed bytes delivered to the handler.
+	BytesOut int64
+
+	// Exceeded is set when the decompressed body hit the size cap.
+	Exceeded bool
+}
+
+// Ratio returns BytesOut divided by BytesIn, or 0 when nothing was read.
+func (r DecompressionResult) Ratio() float64 {
+	if r.BytesIn == 0 {
+		return 0
+	}
+	return float64(r.BytesOut) / float64(r.BytesIn)
+}
+
+// Decompress creates a middleware that transparently decompresses request
+// bodies sent with Content-Encoding gzip, deflate (zlib), or br, and removes
+// the header before the handler runs. The compressed body is limited to
+// MaxBodySize and the decompressed body to MaxRatio times that; exceeding
+// either responds 413 and fails further reads. A malformed gzip or deflate
+// header responds 400, corrupt data later in the stream fails the read, and
+// any other content coding responds 415. Register BodyLimit after Decompress
+// to limit the decompressed bytes the handler sees.
+func Decompress(cfg DecompressConfig) Middleware {
+	if cfg.MaxRatio <= 0 {
+		cfg.MaxRatio = 10
+	}
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			enc := strings.ToLower(strings.TrimSpace(c.R.Header.Get("Content-Encoding")))
+			switch enc {
+			case "", "identity":
+				next(c)
+				return
+			case "x-gzip":
+				enc = "gzip"
+			case "gzip", "deflate", "br":
+			default:
+				c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "unsupported content encoding"})
+				return
+			}
+
+			limit := c.maxBodySize
+			if limit <= 0 {
+				limit = 10 << 20 // 10MB default
+			}
+			res := &DecompressionResult{Encoding: enc}
+			c.decompression = res
+			compressed := &countingReader{r: http.MaxBytesReader(c.W, c.R.Body, limit), n: &res.BytesIn}
+			var dr io.Reader
+			var err error
+			switch enc {
+			case "gzip":
+				dr, err = gzip.NewReader(compressed)
+			case "deflate":
+				dr, err = zlib.NewReader(compressed)
+			case "br":
+				dr = brotli.NewReader(compressed)
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + enc + " body"})
+				return
+			}
+			c.R.Body = &decompressReader{c: c, r: dr, body: c.R.Body, res: res, max: limit * cfg.MaxRatio}
+			c.R.Header.Del("Content-Encoding")
+			c.R.Header.Del("Content-Length")
+			c.R.ContentLength = -1
+			next(c)
+		}
+	}
+}
+
+// countingReader counts bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	*cr.n += int64(n)
+	return n, err
+}
+
+// decompressReader enforces the decompressed size cap. On the first read past
+// the cap it responds 413 so later error responses from the handler are
+// ignored, and every subsequent read fails.
+type decompressReader struct {
+	c    *Context
+	r    io.Reader
+	body io.Closer
+	res  *DecompressionResult
+	max  int64
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.res.Exceeded {
+		return 0, errBodyTooLarge
+	}
+	if remaining := d.max - d.res.BytesOut + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := d.r.Read(p)
+	d.res.BytesOut += int64(n)
+	if d.res.BytesOut > d.max {
+		d.res.BytesOut = d.max
+		d.res.Exceeded = true
+		d.c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+		return n - 1, errBodyTooLarge
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		d.c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+	}
+	return n, err
+}
+
+func (d *decompressReader) Close() error {
+	if rc, ok := d.r.(io.Closer); ok {
+		_ = rc.Close()
+	}
+	return d.body.Close()
+}
@@ -0,0 +1,204 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Decompress Middleware", func() {
+	gzipped := func(s string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write([]byte(s))
+		_ = gw.Close()
+		return &buf
+	}
+
+	It("decompresses a gzip body and records the ratio", func() {
+		var res q.DecompressionResult
+		var ok bool
+		r := q.New()
+		r.Use(q.Decompress(q.DecompressConfig{}))
+		r.POST("/in", func(c *q.Context) {
+			var body struct {
+				Msg string `json:"msg"`
+			}
+			if err := c.BindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+				return
+			}
+			res, ok = c.Decompression()
+			c.Text(http.StatusOK, body.Msg)
+		})
+
+		payload := `{"msg":"` + strings.Repeat("a", 1000) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/in", gzipped(payload))
+		req.Header.Set("Content-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal(strings.Repeat("a", 1000)))
+		Expect(ok).To(BeTrue())
+		Expect(res.Encoding).To(Equal("gzip"))
+		Expect(res.BytesOut).To(Equal(int64(len(payload))))
+		Expect(res.Ratio()).To(BeNumerically(">", 10))
+		Expect(res.Exceeded).To(BeFalse())
+	})
+
+	It("rejects a highly compressible body that expands past the cap with 413", func() {
+		var res q.DecompressionResult
+		r := q.New()
+		r.MaxBodySize = 4096
+		r.Use(q.Decompress(q.DecompressConfig{MaxRatio: 4}))
+		r.POST("/in", func(c *q.Context) {
+			_, err := io.ReadAll(c.R.Body)
+			res, _ = c.Decompression()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: "bad body"})
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+
+		bomb := gzipped(strings.Repeat("0", 1<<20))
+		Expect(bomb.Len()).To(BeNumerically("<", 4096))
+		req := httptest.NewRequest(http.MethodPost, "/in", bomb)
+		req.Header.Set("Content-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(res.Exceeded).To(BeTrue())
+		Expect(res.BytesOut).To(Equal(int64(4 * 4096)))
+	})
+
+	It("passes uncompressed bodies through untouched", func() {
+		r := q.New()
+		r.Use(q.Decompress(q.DecompressConfig{}))
+		r.POST("/in", func(c *q.Context) {
+			_, ok := c.Decompression()
+			b, _ := io.ReadAll(c.R.Body)
+			Expect(ok).To(BeFalse())
+			c.Text(http.StatusOK, string(b))
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/in", strings.NewReader("plain")))
+		Expect(rr.Body.String()).To(Equal("plain"))
+	})
+
+	It("responds 400 for a malformed gzip body", func() {
+		r := q.New()
+		r.Use(q.Decompress(q.DecompressConfig{}))
+		r.POST("/in", func(c *q.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodPost, "/in", strings.NewReader("not gzip"))
+		req.Header.Set("Content-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("responds 415 for an unsupported content encoding", func() {
+		r := q.New()
+		r.Use(q.Decompress(q.DecompressConfig{}))
+		r.POST("/in", func(c *q.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodPost, "/in", strings.NewReader("x"))
+		req.Header.Set("Content-Encoding", "zstd")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusUnsupportedMediaType))
+	})
+
+	Describe("other encodings", func() {
+		bindRouter := func() *q.Router {
+			r := q.New()
+			r.Use(q.Decompress(q.DecompressConfig{}))
+			r.POST("/in", func(c *q.Context) {
+				var body struct {
+					Msg string `json:"msg"`
+				}
+				if err := c.BindJSON(&body); err != nil {
+					c.JSON(http.StatusBadRequest, q.ErrorResponse{Error: err.Error()})
+					return
+				}
+				c.Text(http.StatusOK, body.Msg+"|"+c.R.Header.Get("Content-Encoding"))
+			})
+			return r
+		}
+		post := func(r *q.Router, encoding string, body io.Reader) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/in", body)
+			req.Header.Set("Content-Encoding", encoding)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("binds a deflate (zlib) body", func() {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			_, _ = zw.Write([]byte(`{"msg":"deflated"}`))
+			_ = zw.Close()
+			rr := post(bindRouter(), "deflate", &buf)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("deflated|"))
+		})
+
+		It("binds a brotli body", func() {
+			var buf bytes.Buffer
+			bw := brotli.NewWriter(&buf)
+			_, _ = bw.Write([]byte(`{"msg":"brotli"}`))
+			_ = bw.Close()
+			rr := post(bindRouter(), "br", &buf)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("brotli|"))
+		})
+
+		It("responds 400 for a malformed deflate body", func() {
+			rr := post(bindRouter(), "deflate", strings.NewReader("not zlib"))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("applies an inner BodyLimit to the decompressed bytes", func() {
+			r := q.New()
+			r.Use(q.Decompress(q.DecompressConfig{}), q.BodyLimit(100))
+			r.POST("/in", func(c *q.Context) {
+				if _, err := io.ReadAll(c.R.Body); err != nil {
+					c.JSON(http.StatusRequestEntityTooLarge, q.ErrorResponse{Error: "too large"})
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+			body := gzipped(strings.Repeat("x", 1000))
+			Expect(body.Len()).To(BeNumerically("<", 100))
+			rr := post(r, "gzip", body)
+			Expect(rr.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+})
@@ -16,12 +16,27 @@
 
 package quokka
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
 // Sentinel errors used by the router and available to ErrorHandler implementations.
 var (
 	ErrNotFound         = errors.New("not found")
 	ErrMethodNotAllowed = errors.New("method not allowed")
+
+	// ErrUnsupportedMediaType is wrapped by Context.Bind when no codec is
+	// registered for the request's Content-Type.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	// ErrPartTooLarge is returned by FormFile and FormFiles when a multipart
+	// part exceeds Router.MaxPartSize.
+	ErrPartTooLarge = errors.New("multipart part too large")
+
+	// ErrResponseCommitted is returned by Context.Reset when the response
+	// has already been sent to the client.
+	ErrResponseCommitted = errors.New("response already committed")
 )
 
 // ErrorResponse is a consistent error payload loosely inspired by RFC 9457 (Problem Details for HTTP APIs).
@@ -31,4 +46,35 @@ type ErrorResponse struct {
 	Message string            `json:"message,omitempty"`
 	Code    string            `json:"code,omitempty"`
 	Details map[string]string `json:"details,omitempty"`
+
+	// RequestID identifies the failed request so clients can quote it to
+	// support. Recover fills it in for panics.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// HTTPError is an error carrying the HTTP status and client-facing message to
+// respond with. Return one from a JSONHandler to control the error response;
+// Err, when set, is the underlying cause and is not sent to the client.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewHTTPError returns an HTTPError with the given status and message. An
+// empty message defaults to the status text.
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
 }
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
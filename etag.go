@@ -0,0 +1,187 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETagConfig configures the ETag middleware.
+type ETagConfig struct {
+	// Weak marks generated tags as weak validators (W/"...").
+	Weak bool
+
+	// MaxBodySize is the largest response body buffered for hashing. Larger
+	// or flushed responses are streamed without an ETag. Default: 1 MB.
+	MaxBodySize int
+}
+
+// ETag creates a middleware that buffers successful GET and HEAD responses,
+// sets an ETag header holding the SHA-256 of the body, and answers 304 Not
+// Modified with no body when the request's If-None-Match matches. An ETag
+// set by the handler is kept and compared instead. Other methods and non-200
+// responses pass through untouched.
+//
+// The tag describes the bytes ETag sees, so register it after Gzip, Brotli,
+// or Compress to hash the uncompressed representation:
+//
+//	r.Use(quokka.Gzip(quokka.GzipConfig{}), quokka.ETag(quokka.ETagConfig{}))
+//
+// Every encoding of the body then shares one tag, which RFC 9110 allows only
+// for a weak validator, so the tag is made weak whenever a compression
+// middleware outside ETag has negotiated an encoding for the request.
+func ETag(cfg ETagConfig) Middleware {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20 // 1MB
+	}
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if c.R.Method != http.MethodGet && c.R.Method != http.MethodHead {
+				next(c)
+				return
+			}
+			ew := &etagWriter{ResponseWriter: c.W, limit: cfg.MaxBodySize}
+			original := c.W
+			c.W = ew
+			defer func() { c.W = original }()
+
+			next(c)
+
+			if ew.passthrough || ew.status == 0 {
+				return
+			}
+			h := original.Header()
+			tag := h.Get("ETag")
+			if tag == "" {
+				sum := sha256.Sum256(ew.buf.Bytes())
+				tag = `"` + hex.EncodeToString(sum[:]) + `"`
+			}
+			if (cfg.Weak || findCompressWriter(original) != nil) && !strings.HasPrefix(tag, "W/") {
+				tag = "W/" + tag
+			}
+			h.Set("ETag", tag)
+			if etagMatches(c.R.Header.Get("If-None-Match"), tag) {
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				c.status = http.StatusNotModified
+				original.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_ = ew.release()
+		}
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches tag using the
+// weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter holds back a 200 response so its ETag can be computed before
+// anything is sent. Non-200 statuses, bodies over limit, and flushes switch it
+// to passthrough, forwarding everything held so far.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	if code != http.StatusOK {
+		w.release()
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.limit {
+		if err := w.release(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush implements http.Flusher; a flushed response is streamed without an
+// ETag.
+func (w *etagWriter) Flush() {
+	if !w.passthrough && w.status != 0 {
+		_ = w.release()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *etagWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Reset discards the held status and body. It fails once the response has
+// been released to the client.
+func (w *etagWriter) Reset() error {
+	if w.passthrough {
+		return ErrResponseCommitted
+	}
+	w.status = 0
+	w.buf.Reset()
+	return nil
+}
+
+// release switches to passthrough and forwards the held status and body.
+func (w *etagWriter) release() error {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
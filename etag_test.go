@@ -0,0 +1,146 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("ETag Middleware", func() {
+	newRouter := func(cfg q.ETagConfig) *q.Router {
+		r := q.New()
+		r.Use(q.ETag(cfg))
+		r.GET("/item", func(c *q.Context) { c.JSON(http.StatusOK, map[string]string{"name": "quokka"}) })
+		r.GET("/missing", func(c *q.Context) { c.JSON(http.StatusNotFound, q.ErrorResponse{Error: "not found"}) })
+		r.POST("/item", func(c *q.Context) { c.JSON(http.StatusOK, map[string]string{"ok": "yes"}) })
+		return r
+	}
+	get := func(r *q.Router, method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("sets a strong ETag on a cache miss", func() {
+		rr := get(newRouter(q.ETagConfig{}), http.MethodGet, "/item", "")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("ETag")).To(MatchRegexp(`^"[0-9a-f]{64}"$`))
+		Expect(rr.Body.String()).To(MatchJSON(`{"name":"quokka"}`))
+	})
+
+	It("answers 304 with an empty body when If-None-Match matches", func() {
+		r := newRouter(q.ETagConfig{})
+		tag := get(r, http.MethodGet, "/item", "").Header().Get("ETag")
+
+		rr := get(r, http.MethodGet, "/item", `"other", `+tag)
+		Expect(rr.Code).To(Equal(http.StatusNotModified))
+		Expect(rr.Body.Len()).To(BeZero())
+		Expect(rr.Header().Get("ETag")).To(Equal(tag))
+
+		rr = get(r, http.MethodHead, "/item", tag)
+		Expect(rr.Code).To(Equal(http.StatusNotModified))
+	})
+
+	It("returns 200 when If-None-Match does not match", func() {
+		rr := get(newRouter(q.ETagConfig{}), http.MethodGet, "/item", `"stale"`)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.Len()).NotTo(BeZero())
+	})
+
+	It("generates weak tags when configured and compares weakly", func() {
+		r := newRouter(q.ETagConfig{Weak: true})
+		tag := get(r, http.MethodGet, "/item", "").Header().Get("ETag")
+		Expect(tag).To(HavePrefix(`W/"`))
+		Expect(get(r, http.MethodGet, "/item", strings.TrimPrefix(tag, "W/")).Code).To(Equal(http.StatusNotModified))
+	})
+
+	It("skips non-200 responses and other methods", func() {
+		r := newRouter(q.ETagConfig{})
+		rr := get(r, http.MethodGet, "/missing", "*")
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+		Expect(rr.Header().Get("ETag")).To(BeEmpty())
+
+		rr = get(r, http.MethodPost, "/item", "*")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("ETag")).To(BeEmpty())
+	})
+
+	It("streams bodies over MaxBodySize without an ETag", func() {
+		r := q.New()
+		r.Use(q.ETag(q.ETagConfig{MaxBodySize: 10}))
+		r.GET("/big", func(c *q.Context) { c.Text(http.StatusOK, strings.Repeat("x", 100)) })
+		rr := get(r, http.MethodGet, "/big", "")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("ETag")).To(BeEmpty())
+		Expect(rr.Body.Len()).To(Equal(100))
+	})
+
+	It("hashes the uncompressed body when registered after Gzip", func() {
+		body := strings.Repeat("etag gzip ", 100)
+		plain := q.New()
+		plain.Use(q.ETag(q.ETagConfig{}))
+		plain.GET("/", func(c *q.Context) { c.Text(http.StatusOK, body) })
+
+		zipped := q.New()
+		zipped.Use(q.Gzip(q.GzipConfig{}), q.ETag(q.ETagConfig{}))
+		zipped.GET("/", func(c *q.Context) { c.Text(http.StatusOK, body) })
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		zipped.ServeHTTP(rr, req)
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rr.Header().Get("ETag")).To(Equal("W/" + get(plain, http.MethodGet, "/", "").Header().Get("ETag")))
+		Expect(decompressGzip(rr.Body.Bytes())).To(Equal(body))
+	})
+
+	It("weakens the tag under a compressor so encodings do not share a strong validator", func() {
+		r := q.New()
+		r.Use(q.Compress(q.CompressConfig{}), q.ETag(q.ETagConfig{}))
+		r.GET("/", func(c *q.Context) { c.Text(http.StatusOK, strings.Repeat("etag br ", 100)) })
+
+		tags := map[string]string{}
+		for _, ae := range []string{"br", "gzip"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", ae)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			Expect(rr.Header().Get("Content-Encoding")).To(Equal(ae))
+			tags[ae] = rr.Header().Get("ETag")
+		}
+		Expect(tags["br"]).To(HavePrefix("W/"))
+		Expect(tags["gzip"]).To(Equal(tags["br"]))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("If-None-Match", tags["gzip"])
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusNotModified))
+	})
+})
@@ -21,7 +21,6 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -133,6 +132,7 @@ func main() {
 	st := newStore()
 
 	r := quokka.New()
+	r.HandleOPTIONS = true // OPTIONS responses advertise the registered methods
 	r.Use(
 		quokka.Recover(logger),
 		quokka.Logger(quokka.LoggerConfig{Logger: logger}),
@@ -149,20 +149,10 @@ func main() {
 	// Todos group
 	api := r.Group("/api")
 
-	// OPTIONS handlers advertise allowed methods
-	allow := func(methods string) quokka.Handler {
-		return func(c *quokka.Context) {
-			c.SetHeader("Allow", methods)
-			c.Status(http.StatusNoContent)
-		}
-	}
-	api.OPTIONS("/todos", allow("OPTIONS, GET, POST"))
-	api.OPTIONS("/todos/:id", allow("OPTIONS, GET, PUT, PATCH, DELETE, HEAD"))
-
 	// List with pagination (?offset=&limit=)
 	api.GET("/todos", func(c *quokka.Context) {
-		offset, _ := strconv.Atoi(c.Query("offset"))
-		limit, _ := strconv.Atoi(c.Query("limit"))
+		offset := c.QueryIntDefault("offset", 0)
+		limit := c.QueryIntDefault("limit", 0)
 		items, total := st.list(offset, limit)
 		c.JSON(http.StatusOK, map[string]any{"items": items, "total": total, "offset": offset, "limit": limit})
 	})
@@ -183,7 +173,7 @@ func main() {
 
 	// Retrieve
 	api.GET("/todos/:id", func(c *quokka.Context) {
-		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		id, _ := c.ParamInt("id")
 		if t, ok := st.get(id); ok {
 			c.JSON(http.StatusOK, t)
 			return
@@ -193,7 +183,7 @@ func main() {
 
 	// HEAD existence
 	api.HEAD("/todos/:id", func(c *quokka.Context) {
-		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		id, _ := c.ParamInt("id")
 		if _, ok := st.get(id); ok {
 			c.NoContent()
 			return
@@ -203,7 +193,7 @@ func main() {
 
 	// PUT replace
 	api.PUT("/todos/:id", func(c *quokka.Context) {
-		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		id, _ := c.ParamInt("id")
 		var body Todo
 		if err := c.BindJSON(&body); err != nil {
 			c.JSON(http.StatusBadRequest, quokka.ErrorResponse{Error: "invalid_json"})
@@ -219,7 +209,7 @@ func main() {
 
 	// PATCH partial
 	api.PATCH("/todos/:id", func(c *quokka.Context) {
-		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		id, _ := c.ParamInt("id")
 		var fields map[string]any
 		if err := c.BindJSON(&fields); err != nil {
 			c.JSON(http.StatusBadRequest, quokka.ErrorResponse{Error: "invalid_json"})
@@ -234,7 +224,7 @@ func main() {
 
 	// DELETE
 	api.DELETE("/todos/:id", func(c *quokka.Context) {
-		id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+		id, _ := c.ParamInt("id")
 		if ok := st.delete(id); ok {
 			c.NoContent()
 			return
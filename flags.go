@@ -0,0 +1,46 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+// FlagProvider evaluates feature flags for a request. Implementations can key
+// on anything reachable from the Context, such as JWT claims, the
+// authenticated user, or request headers.
+type FlagProvider interface {
+	Flags(c *Context) map[string]bool
+}
+
+// FlagProviderFunc adapts an ordinary function to a FlagProvider.
+type FlagProviderFunc func(c *Context) map[string]bool
+
+// Flags calls f(c).
+func (f FlagProviderFunc) Flags(c *Context) map[string]bool { return f(c) }
+
+// FeatureFlags creates a middleware that evaluates provider once per request
+// and exposes the result through Context.FlagEnabled. Register it after any
+// authentication middleware whose identity the provider relies on.
+func FeatureFlags(provider FlagProvider) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			c.flags = provider.Flags(c)
+			next(c)
+		}
+	}
+}
+
+// FlagEnabled reports whether the named feature flag is on for this request.
+// Unknown flags, and all flags when FeatureFlags is not in the chain, are off.
+func (c *Context) FlagEnabled(name string) bool { return c.flags[name] }
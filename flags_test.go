@@ -0,0 +1,64 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("FeatureFlags Middleware", func() {
+	It("toggles a flag per request from the provider", func() {
+		provider := q.FlagProviderFunc(func(c *q.Context) map[string]bool {
+			return map[string]bool{"new-checkout": c.Header("X-Tenant") == "beta"}
+		})
+		r := q.New()
+		r.Use(q.FeatureFlags(provider))
+		r.GET("/checkout", func(c *q.Context) {
+			if c.FlagEnabled("new-checkout") {
+				c.Text(http.StatusOK, "new")
+				return
+			}
+			c.Text(http.StatusOK, "old")
+		})
+
+		for tenant, want := range map[string]string{"beta": "new", "acme": "old"} {
+			req := httptest.NewRequest(http.MethodGet, "/checkout", nil)
+			req.Header.Set("X-Tenant", tenant)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			Expect(rr.Body.String()).To(Equal(want))
+		}
+	})
+
+	It("reports unknown flags and missing middleware as off", func() {
+		var known, unknown bool
+		r := q.New()
+		r.GET("/x", func(c *q.Context) { known = c.FlagEnabled("anything") })
+		r.GET("/y", func(c *q.Context) { unknown = c.FlagEnabled("missing") },
+			q.FeatureFlags(q.FlagProviderFunc(func(*q.Context) map[string]bool { return map[string]bool{"on": true} })))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/y", nil))
+		Expect(known).To(BeFalse())
+		Expect(unknown).To(BeFalse())
+	})
+})
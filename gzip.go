@@ -17,7 +17,12 @@
 package quokka
 
 import (
+	"compress/flate"
 	"compress/gzip"
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"io"
 	"net/http"
 	"strings"
 )
@@ -32,6 +37,20 @@ type GzipConfig struct {
 	// is applied. Responses smaller than this are sent uncompressed.
 	// Default: 256.
 	MinLength int
+
+	// Dictionary, when set, primes the deflate stream with a preset
+	// dictionary, which improves ratios for small, repetitive payloads such as
+	// similar JSON responses. Standard gzip decoders cannot read the result,
+	// so it is only sent to clients that name DictionaryEncoding in
+	// Accept-Encoding, labelled with that coding; everyone else gets plain
+	// gzip. Such clients inflate the body with the same dictionary (e.g.
+	// flate.NewReaderDict after the 10-byte gzip header).
+	Dictionary []byte
+
+	// DictionaryEncoding is the content coding that selects and labels
+	// dictionary-compressed responses. A "*" in Accept-Encoding does not
+	// select it. Default: "x-gzip-dict".
+	DictionaryEncoding string
 }
 
 // Content types that are already compressed and should not be gzip-compressed.
@@ -63,32 +82,106 @@ func shouldSkipContentType(ct string) bool {
 	return false
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to transparently compress responses.
-// It buffers writes until MinLength is reached, then decides whether to compress.
-type gzipResponseWriter struct {
+// Compression skip reasons reported in CompressionResult.SkipReason.
+const (
+	SkipReasonAcceptEncoding = "accept-encoding" // client did not accept the encoding
+	SkipReasonContentType    = "content-type"    // content type is already compressed
+	SkipReasonMinLength      = "min-length"      // body smaller than MinLength
+	SkipReasonNoBody         = "no-body"         // status code carries no body (1xx, 204, 304)
+	SkipReasonEncoded        = "encoded"         // response already has a Content-Encoding
+	SkipReasonProxied        = "proxied"         // response relayed as-is by Context.ReverseProxy
+)
+
+// CompressionResult records what the compression middleware decided for a
+// request. Retrieve it with Context.Compression, typically from a logging
+// middleware registered outside Gzip.
+type CompressionResult struct {
+	// Encoding is the content coding applied (e.g. "gzip"); empty when skipped.
+	Encoding string
+
+	// SkipReason explains why the response was not compressed; empty when
+	// Encoding is set.
+	SkipReason string
+
+	// BytesIn is the number of uncompressed body bytes written by the handler.
+	BytesIn int64
+
+	// BytesOut is the number of body bytes sent to the client.
+	BytesOut int64
+}
+
+// Compressed reports whether the response body was compressed.
+func (r CompressionResult) Compressed() bool { return r.Encoding != "" }
+
+// Ratio returns BytesOut divided by BytesIn, or 0 when no body was written.
+func (r CompressionResult) Ratio() float64 {
+	if r.BytesIn == 0 {
+		return 0
+	}
+	return float64(r.BytesOut) / float64(r.BytesIn)
+}
+
+// countingWriter counts bytes written through to the underlying writer.
+type countingWriter struct {
+	w http.ResponseWriter
+	n *int64
+}
+
+func (cw countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	*cw.n += int64(n)
+	return n, err
+}
+
+// encoder is a streaming compressor such as *gzip.Writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressResponseWriter wraps http.ResponseWriter to transparently compress
+// responses with the content coding produced by newEncoder. It buffers writes
+// until MinLength is reached, then decides whether to compress.
+type compressResponseWriter struct {
 	http.ResponseWriter
-	gw            *gzip.Writer
+	encoding      string
+	newEncoder    func(io.Writer) encoder
+	enc           encoder
 	buf           []byte
 	minLength     int
-	level         int
 	decided       bool
 	compressing   bool
 	statusCode    int
 	headerWritten bool
+	skipReason    string
+	bytesIn       int64
+	bytesOut      int64
+	head          bool // HEAD request: decide and set headers, but discard the body
+	bypass        bool // relay the body uncompressed (set by Context.ReverseProxy)
 }
 
-func (w *gzipResponseWriter) WriteHeader(code int) {
+func (w *compressResponseWriter) WriteHeader(code int) {
 	w.statusCode = code
 	// For status codes that indicate no body, forward immediately
 	if code == http.StatusNoContent || code == http.StatusNotModified || (code >= 100 && code < 200) {
 		w.decided = true
 		w.compressing = false
+		w.skipReason = SkipReasonNoBody
 		w.ResponseWriter.WriteHeader(code)
 		w.headerWritten = true
 	}
 }
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	w.bytesIn += int64(len(b))
+	if w.head {
+		// The body of a HEAD response is never sent, so only the length
+		// matters for the decision and nothing is buffered or compressed.
+		if !w.decided && w.bytesIn >= int64(w.minLength) {
+			w.decide()
+		}
+		return len(b), nil
+	}
 	if !w.decided {
 		w.buf = append(w.buf, b...)
 		if len(w.buf) >= w.minLength {
@@ -98,30 +191,46 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 		return len(b), nil
 	}
 	if w.compressing {
-		return w.gw.Write(b)
+		return w.enc.Write(b)
 	}
-	return w.ResponseWriter.Write(b)
+	return w.writeRaw(b)
+}
+
+// writeRaw writes b uncompressed to the underlying writer, counting the bytes.
+func (w *compressResponseWriter) writeRaw(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesOut += int64(n)
+	return n, err
 }
 
-func (w *gzipResponseWriter) decide() {
+func (w *compressResponseWriter) decide() {
 	w.decided = true
+	if w.bypass {
+		w.compressing = false
+		w.skipReason = SkipReasonProxied
+		return
+	}
+	if w.ResponseWriter.Header().Get("Content-Encoding") != "" {
+		w.compressing = false
+		w.skipReason = SkipReasonEncoded
+		return
+	}
 	ct := w.ResponseWriter.Header().Get("Content-Type")
 	if shouldSkipContentType(ct) {
 		w.compressing = false
+		w.skipReason = SkipReasonContentType
 		return
 	}
 	w.compressing = true
 	w.ResponseWriter.Header().Del("Content-Length")
-	w.ResponseWriter.Header().Set("Content-Encoding", "gzip")
-	var err error
-	w.gw, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
-	if err != nil {
-		// Fallback to default compression on invalid level
-		w.gw = gzip.NewWriter(w.ResponseWriter)
+	w.ResponseWriter.Header().Set("Content-Encoding", w.encoding)
+	if w.head {
+		return
 	}
+	w.enc = w.newEncoder(countingWriter{w: w.ResponseWriter, n: &w.bytesOut})
 }
 
-func (w *gzipResponseWriter) flush() error {
+func (w *compressResponseWriter) flush() error {
 	if !w.headerWritten && w.statusCode != 0 {
 		w.ResponseWriter.WriteHeader(w.statusCode)
 		w.headerWritten = true
@@ -129,49 +238,69 @@ func (w *gzipResponseWriter) flush() error {
 	if len(w.buf) == 0 {
 		return nil
 	}
-	if w.compressing && w.gw != nil {
-		_, err := w.gw.Write(w.buf)
+	if w.compressing && w.enc != nil {
+		_, err := w.enc.Write(w.buf)
 		w.buf = nil
 		return err
 	}
-	_, err := w.ResponseWriter.Write(w.buf)
+	_, err := w.writeRaw(w.buf)
 	w.buf = nil
 	return err
 }
 
-func (w *gzipResponseWriter) close() error {
+func (w *compressResponseWriter) close() error {
 	if !w.decided {
 		// Response was smaller than minLength — send uncompressed
 		w.decided = true
 		w.compressing = false
+		w.skipReason = SkipReasonMinLength
 	}
 	if !w.headerWritten && w.statusCode != 0 {
 		w.ResponseWriter.WriteHeader(w.statusCode)
 		w.headerWritten = true
 	}
 	if len(w.buf) > 0 {
-		_, _ = w.ResponseWriter.Write(w.buf)
+		_, _ = w.writeRaw(w.buf)
 		w.buf = nil
 	}
-	if w.compressing && w.gw != nil {
-		return w.gw.Close()
+	if w.compressing && w.enc != nil {
+		return w.enc.Close()
 	}
 	return nil
 }
 
-// Flush implements http.Flusher for streaming compatibility.
-func (w *gzipResponseWriter) Flush() {
-	if w.compressing && w.gw != nil {
-		_ = w.gw.Flush()
+// Flush implements http.Flusher for streaming compatibility. Flushing before
+// MinLength bytes have been written forces the compression decision so
+// streamed responses are not held back in the buffer.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	_ = w.flush()
+	if w.compressing && w.enc != nil {
+		_ = w.enc.Flush()
 	}
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
 }
 
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// result summarizes the compression decision once the writer is closed.
+func (w *compressResponseWriter) result() *CompressionResult {
+	res := &CompressionResult{SkipReason: w.skipReason, BytesIn: w.bytesIn, BytesOut: w.bytesOut}
+	if w.compressing {
+		res.Encoding = w.encoding
+	}
+	return res
+}
+
 // Gzip creates a middleware that compresses responses using gzip encoding.
 // Responses smaller than MinLength bytes are sent uncompressed.
 // Already-compressed content types (images, archives) are skipped.
+// The decision is recorded on the Context and available via Context.Compression.
 func Gzip(cfg GzipConfig) Middleware {
 	if cfg.Level == 0 {
 		cfg.Level = gzip.DefaultCompression
@@ -180,29 +309,180 @@ func Gzip(cfg GzipConfig) Middleware {
 		cfg.MinLength = 256
 	}
 
+	plain := compress(cfg.MinLength, []string{"gzip"}, map[string]func(io.Writer) encoder{
+		"gzip": gzipEncoder(cfg.Level),
+	})
+	if len(cfg.Dictionary) == 0 {
+		return plain
+	}
+	if cfg.DictionaryEncoding == "" {
+		cfg.DictionaryEncoding = "x-gzip-dict"
+	}
+	withDict := compress(cfg.MinLength, []string{cfg.DictionaryEncoding, "gzip"}, map[string]func(io.Writer) encoder{
+		cfg.DictionaryEncoding: dictGzipEncoder(cfg.Level, cfg.Dictionary),
+		"gzip":                 gzipEncoder(cfg.Level),
+	})
+	return func(next Handler) Handler {
+		plainNext, dictNext := plain(next), withDict(next)
+		return func(c *Context) {
+			if acceptsCodingExplicitly(c.R.Header.Get("Accept-Encoding"), cfg.DictionaryEncoding) {
+				dictNext(c)
+				return
+			}
+			plainNext(c)
+		}
+	}
+}
+
+// acceptsCodingExplicitly reports whether the Accept-Encoding header names
+// coding itself with a non-zero quality, ignoring "*".
+func acceptsCodingExplicitly(header, coding string) bool {
+	for _, item := range parseAccept(header) {
+		if strings.EqualFold(item.value, coding) {
+			return item.q > 0
+		}
+	}
+	return false
+}
+
+// gzipEncoder returns a constructor for gzip writers at level.
+func gzipEncoder(level int) func(io.Writer) encoder {
+	return func(out io.Writer) encoder {
+		gw, err := gzip.NewWriterLevel(out, level)
+		if err != nil {
+			// Fallback to default compression on invalid level
+			return gzip.NewWriter(out)
+		}
+		return gw
+	}
+}
+
+// dictGzipEncoder returns a constructor for gzip writers whose deflate stream
+// is primed with dict. compress/gzip has no dictionary support, so the gzip
+// framing is written directly.
+func dictGzipEncoder(level int, dict []byte) func(io.Writer) encoder {
+	return func(out io.Writer) encoder {
+		fw, err := flate.NewWriterDict(out, level, dict)
+		if err != nil {
+			fw, _ = flate.NewWriterDict(out, flate.DefaultCompression, dict)
+		}
+		return &dictGzipWriter{out: out, fw: fw, crc: crc32.NewIEEE()}
+	}
+}
+
+// dictGzipWriter writes a gzip member whose deflate payload uses a preset
+// dictionary. The gzip header is held back until the first Write, Flush, or
+// Close so the response status is sent before any body bytes.
+type dictGzipWriter struct {
+	out         io.Writer
+	fw          *flate.Writer
+	crc         hash.Hash32
+	size        uint32
+	wroteHeader bool
+	err         error
+}
+
+// writeHeader writes the gzip header once.
+func (w *dictGzipWriter) writeHeader() error {
+	if !w.wroteHeader && w.err == nil {
+		w.wroteHeader = true
+		// ID1, ID2, CM=deflate, FLG=0, MTIME=0, XFL=0, OS=unknown.
+		_, w.err = w.out.Write([]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255})
+	}
+	return w.err
+}
+
+func (w *dictGzipWriter) Write(p []byte) (int, error) {
+	if err := w.writeHeader(); err != nil {
+		return 0, err
+	}
+	w.crc.Write(p)
+	w.size += uint32(len(p)) // #nosec G115 -- ISIZE is the input size modulo 2^32
+	return w.fw.Write(p)
+}
+
+func (w *dictGzipWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.fw.Flush()
+}
+
+func (w *dictGzipWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	if err := w.fw.Close(); err != nil {
+		return err
+	}
+	var trailer [8]byte
+	binary.LittleEndian.PutUint32(trailer[:4], w.crc.Sum32())
+	binary.LittleEndian.PutUint32(trailer[4:], w.size)
+	_, err := w.out.Write(trailer[:])
+	return err
+}
+
+// compress returns a middleware that compresses responses with the best
+// content coding from encoders that the client accepts, as chosen by
+// negotiateEncoding. It is shared by Gzip, Brotli, and Compress.
+func compress(minLength int, order []string, encoders map[string]func(io.Writer) encoder) Middleware {
 	return func(next Handler) Handler {
 		return func(c *Context) {
-			if !strings.Contains(c.R.Header.Get("Accept-Encoding"), "gzip") {
+			encoding := negotiateEncoding(c.R.Header.Get("Accept-Encoding"), order)
+			if encoding == "" {
+				c.compression = &CompressionResult{SkipReason: SkipReasonAcceptEncoding}
 				next(c)
 				return
 			}
 
-			c.W.Header().Add("Vary", "Accept-Encoding")
+			addVary(c.W.Header(), "Accept-Encoding")
 
-			grw := &gzipResponseWriter{
+			crw := &compressResponseWriter{
 				ResponseWriter: c.W,
-				minLength:      cfg.MinLength,
-				level:          cfg.Level,
+				encoding:       encoding,
+				newEncoder:     encoders[encoding],
+				minLength:      minLength,
+				head:           c.R.Method == http.MethodHead,
 			}
 
 			original := c.W
-			c.W = grw
+			c.W = crw
 			defer func() {
-				_ = grw.close()
+				_ = crw.close()
 				c.W = original
+				// Keep an inner compressor's result over this one's skip.
+				if res := crw.result(); res.Compressed() || c.compression == nil || !c.compression.Compressed() {
+					c.compression = res
+				}
 			}()
 
 			next(c)
 		}
 	}
 }
+
+// negotiateEncoding picks the content coding from supported that the
+// Accept-Encoding header rates highest. An explicit entry takes precedence
+// over "*"; ties go to the earlier entry in supported. It returns "" when no
+// supported coding has a non-zero quality, meaning the response is sent
+// uncompressed (identity), even if identity itself was refused.
+func negotiateEncoding(header string, supported []string) string {
+	items := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, coding := range supported {
+		q := 0.0
+		for _, item := range items {
+			if strings.EqualFold(item.value, coding) {
+				q = item.q
+				break
+			}
+			if item.value == "*" {
+				q = item.q
+			}
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
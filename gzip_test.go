@@ -17,7 +17,11 @@
 package quokka_test
 
 import (
+	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -303,4 +307,186 @@ var _ = Describe("Gzip Middleware", func() {
 		r.ServeHTTP(rr, req)
 		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
 	})
+
+	Describe("Compression result", func() {
+		serve := func(r *q.Router, acceptGzip bool) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			if acceptGzip {
+				req.Header.Set("Accept-Encoding", "gzip")
+			}
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+
+		It("records a content-type skip", func() {
+			var res q.CompressionResult
+			var ok bool
+			r := q.New()
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) { next(c); res, ok = c.Compression() }
+			})
+			r.Use(q.Gzip(q.GzipConfig{MinLength: 10}))
+			r.GET("/x", func(c *q.Context) { c.Bytes(http.StatusOK, make([]byte, 100), "image/png") })
+
+			serve(r, true)
+			Expect(ok).To(BeTrue())
+			Expect(res.Compressed()).To(BeFalse())
+			Expect(res.SkipReason).To(Equal(q.SkipReasonContentType))
+			Expect(res.BytesIn).To(Equal(int64(100)))
+			Expect(res.BytesOut).To(Equal(int64(100)))
+		})
+
+		It("records min-length and accept-encoding skips", func() {
+			var res q.CompressionResult
+			r := q.New()
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) { next(c); res, _ = c.Compression() }
+			})
+			r.Use(q.Gzip(q.GzipConfig{MinLength: 1024}))
+			r.GET("/x", func(c *q.Context) { c.Text(http.StatusOK, "short") })
+
+			serve(r, true)
+			Expect(res.SkipReason).To(Equal(q.SkipReasonMinLength))
+
+			serve(r, false)
+			Expect(res.SkipReason).To(Equal(q.SkipReasonAcceptEncoding))
+		})
+
+		It("records encoding and ratio when compressed", func() {
+			var res q.CompressionResult
+			r := q.New()
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) { next(c); res, _ = c.Compression() }
+			})
+			r.Use(q.Gzip(q.GzipConfig{}))
+			r.GET("/x", func(c *q.Context) { c.Text(http.StatusOK, strings.Repeat("a", 4096)) })
+
+			rr := serve(r, true)
+			Expect(res.Compressed()).To(BeTrue())
+			Expect(res.Encoding).To(Equal("gzip"))
+			Expect(res.SkipReason).To(BeEmpty())
+			Expect(res.BytesIn).To(Equal(int64(4096)))
+			Expect(res.BytesOut).To(Equal(int64(rr.Body.Len())))
+			Expect(res.Ratio()).To(BeNumerically("<", 0.1))
+		})
+
+		It("reports no result when Gzip is not in the chain", func() {
+			var ok bool
+			r := q.New()
+			r.GET("/x", func(c *q.Context) { _, ok = c.Compression(); c.Status(http.StatusOK) })
+			serve(r, true)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("sets headers but writes no compressed body for HEAD", func() {
+		var res q.CompressionResult
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) { next(c); res, _ = c.Compression() }
+		})
+		r.Use(q.Gzip(q.GzipConfig{}))
+		r.GET("/data", func(c *q.Context) { c.Text(http.StatusOK, strings.Repeat("head ", 200)) })
+
+		req := httptest.NewRequest(http.MethodHead, "/data", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(rr.Header().Get("Vary")).To(ContainSubstring("Accept-Encoding"))
+		Expect(rr.Body.Len()).To(BeZero())
+		Expect(res.BytesIn).To(Equal(int64(1000)))
+		Expect(res.BytesOut).To(BeZero())
+	})
+
+	It("writes no body for a small HEAD response", func() {
+		r := q.New()
+		r.Use(q.Gzip(q.GzipConfig{}))
+		r.GET("/small", func(c *q.Context) { c.Text(http.StatusOK, "tiny") })
+
+		req := httptest.NewRequest(http.MethodHead, "/small", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.Len()).To(BeZero())
+	})
+
+	It("compresses with a preset dictionary that round-trips with the same dictionary", func() {
+		dict := []byte(`{"id":,"name":"","status":"active","created_at":"2025-01-01T00:00:00Z","tags":[]}`)
+		payload := map[string]any{"id": 7, "name": "widget", "status": "active", "created_at": "2025-01-01T00:00:00Z", "tags": []string{}, "notes": strings.Repeat("ab", 60)}
+		serve := func(cfg q.GzipConfig, acceptEncoding, wantEncoding string) []byte {
+			cfg.MinLength = 1
+			r := q.New()
+			r.Use(q.Gzip(cfg))
+			r.GET("/api", func(c *q.Context) { c.JSON(http.StatusOK, payload) })
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+			r.ServeHTTP(rr, req)
+			Expect(rr.Header().Get("Content-Encoding")).To(Equal(wantEncoding))
+			return rr.Body.Bytes()
+		}
+
+		plain := serve(q.GzipConfig{}, "gzip", "gzip")
+		withDict := serve(q.GzipConfig{Dictionary: dict}, "x-gzip-dict, gzip", "x-gzip-dict")
+		Expect(len(withDict)).To(BeNumerically("<", len(plain)))
+
+		Expect(withDict[:3]).To(Equal([]byte{0x1f, 0x8b, 8}))
+		fr := flate.NewReaderDict(bytes.NewReader(withDict[10:len(withDict)-8]), dict)
+		body, err := io.ReadAll(fr)
+		Expect(err).NotTo(HaveOccurred())
+		expected, _ := decompressGzip(plain)
+		Expect(string(body)).To(Equal(expected))
+		trailer := withDict[len(withDict)-8:]
+		Expect(binary.LittleEndian.Uint32(trailer[:4])).To(Equal(crc32.ChecksumIEEE(body)))
+		Expect(binary.LittleEndian.Uint32(trailer[4:])).To(Equal(uint32(len(body))))
+
+		_, err = decompressGzip(withDict)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("sends standard gzip to clients that do not name the dictionary coding", func() {
+		dict := []byte(`{"error":"","message":""}`)
+		body := strings.Repeat(`{"error":"x","message":"y"}`, 20)
+		r := q.New()
+		r.Use(q.Gzip(q.GzipConfig{MinLength: 1, Dictionary: dict, DictionaryEncoding: "dict-gzip"}))
+		r.GET("/api", func(c *q.Context) { c.Text(http.StatusOK, body) })
+		for _, ae := range []string{"gzip", "*", "dict-gzip;q=0, gzip"} {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Accept-Encoding", ae)
+			r.ServeHTTP(rr, req)
+			Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"), ae)
+			Expect(decompressGzip(rr.Body.Bytes())).To(Equal(body), ae)
+		}
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("Accept-Encoding", "gzip, dict-gzip")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("dict-gzip"))
+	})
+
+	It("keeps the handler's status when compressing with a dictionary", func() {
+		dict := []byte(`{"error":"","message":""}`)
+		for _, status := range []int{http.StatusNotFound, http.StatusCreated} {
+			r := q.New()
+			r.Use(q.Gzip(q.GzipConfig{MinLength: 1, Dictionary: dict}))
+			r.GET("/api", func(c *q.Context) { c.JSON(status, map[string]string{"error": "x"}) })
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Accept-Encoding", "x-gzip-dict")
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(status))
+			Expect(rr.Header().Get("Content-Encoding")).To(Equal("x-gzip-dict"))
+			Expect(rr.Body.Bytes()[:3]).To(Equal([]byte{0x1f, 0x8b, 8}))
+		}
+	})
 })
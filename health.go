@@ -0,0 +1,185 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck reports whether a component is healthy. It should return
+// promptly once ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+// Health is a registry of liveness and readiness checks served by Server when
+// ServerConfig.Health is set. Liveness checks should only detect a process that
+// must be restarted (e.g. a deadlock) and never depend on external services;
+// dependency checks belong in readiness. It is safe for concurrent use.
+type Health struct {
+	// Timeout bounds each endpoint's run of its checks. Default: 5s.
+	Timeout time.Duration
+
+	mu            sync.RWMutex
+	liveness      map[string]HealthCheck
+	readiness     map[string]HealthCheck
+	livenessPath  string
+	readinessPath string
+	logger        *slog.Logger
+}
+
+// HealthReport is the JSON body of the liveness and readiness endpoints.
+type HealthReport struct {
+	Status string   `json:"status"`           // "ok" or "unavailable"
+	Failed []string `json:"failed,omitempty"` // names of failing checks, sorted
+}
+
+// errHealthTimeout is reported for checks still running at the deadline.
+var errHealthTimeout = errors.New("health check timed out")
+
+// AddLivenessCheck registers fn under name for liveness, replacing any check
+// with the same name.
+func (h *Health) AddLivenessCheck(name string, fn HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.liveness == nil {
+		h.liveness = map[string]HealthCheck{}
+	}
+	h.liveness[name] = fn
+}
+
+// AddReadinessCheck registers fn under name for readiness, replacing any check
+// with the same name.
+func (h *Health) AddReadinessCheck(name string, fn HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.readiness == nil {
+		h.readiness = map[string]HealthCheck{}
+	}
+	h.readiness[name] = fn
+}
+
+// wrap returns a handler answering GET and HEAD requests for the liveness and
+// readiness paths and passing every other request to next.
+func (h *Health) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case h.livenessPath:
+			h.serve(w, r, false)
+		case h.readinessPath:
+			h.serve(w, r, true)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// serve runs the liveness or readiness checks and writes the report.
+func (h *Health) serve(w http.ResponseWriter, r *http.Request, readiness bool) {
+	h.mu.RLock()
+	checks := h.liveness
+	if readiness {
+		checks = h.readiness
+	}
+	run := make(map[string]HealthCheck, len(checks))
+	for name, fn := range checks {
+		run[name] = fn
+	}
+	h.mu.RUnlock()
+
+	failed := h.run(r.Context(), run)
+	report := HealthReport{Status: "ok"}
+	status := http.StatusOK
+	if len(failed) > 0 {
+		report = HealthReport{Status: "unavailable", Failed: failed}
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// run executes checks in parallel until they finish or the timeout expires
+// and returns the sorted names of those that failed or did not finish.
+func (h *Health) run(ctx context.Context, checks map[string]HealthCheck) []string {
+	if len(checks) == 0 {
+		return nil
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(checks))
+	for name, fn := range checks {
+		go func() { results <- result{name, runCheck(ctx, fn)} }()
+	}
+
+	pending := make(map[string]struct{}, len(checks))
+	for name := range checks {
+		pending[name] = struct{}{}
+	}
+	var failed []string
+	fail := func(name string, err error) {
+		failed = append(failed, name)
+		loggerOrDefault(h.logger).Warn("health check failed", slog.String("check", name), slog.Any("err", err))
+	}
+	for len(pending) > 0 {
+		select {
+		case res := <-results:
+			delete(pending, res.name)
+			if res.err != nil {
+				fail(res.name, res.err)
+			}
+		case <-ctx.Done():
+			for name := range pending {
+				fail(name, errHealthTimeout)
+			}
+			pending = nil
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// runCheck calls fn, reporting a panic as a failure instead of crashing the
+// process.
+func runCheck(ctx context.Context, fn HealthCheck) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("health check panicked: %v", v)
+		}
+	}()
+	return fn(ctx)
+}
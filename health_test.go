@@ -0,0 +1,140 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		s    *q.Server
+		logs bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logs.Reset()
+		r := q.New()
+		r.GET("/app", func(c *q.Context) { c.Text(http.StatusOK, "app") })
+		s = q.NewServer(q.ServerConfig{Health: &q.HealthConfig{}}, r, slog.New(slog.NewTextHandler(&logs, nil)))
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		s.HTTP.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	It("answers 200 with no checks and passes other paths through", func() {
+		for _, p := range []string{"/livez", "/readyz"} {
+			rr := get(p)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(MatchJSON(`{"status":"ok"}`))
+			Expect(rr.Header().Get("Cache-Control")).To(Equal("no-store"))
+		}
+		Expect(get("/app").Body.String()).To(Equal("app"))
+	})
+
+	It("returns 503 listing failing readiness checks", func() {
+		s.Health.AddReadinessCheck("db", func(context.Context) error { return nil })
+		s.Health.AddReadinessCheck("cache", func(context.Context) error { return errors.New("connection refused") })
+		s.Health.AddReadinessCheck("queue", func(context.Context) error { return errors.New("no broker") })
+
+		rr := get("/readyz")
+		Expect(rr.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rr.Body.String()).To(MatchJSON(`{"status":"unavailable","failed":["cache","queue"]}`))
+		Expect(rr.Body.String()).NotTo(ContainSubstring("connection refused"))
+		Expect(logs.String()).To(ContainSubstring("check=cache"))
+		Expect(logs.String()).To(ContainSubstring("connection refused"))
+
+		Expect(get("/livez").Code).To(Equal(http.StatusOK))
+	})
+
+	It("returns 200 when every readiness check passes", func() {
+		s.Health.AddReadinessCheck("db", func(context.Context) error { return nil })
+		s.Health.AddLivenessCheck("loop", func(context.Context) error { return nil })
+		Expect(get("/readyz").Code).To(Equal(http.StatusOK))
+		Expect(get("/livez").Code).To(Equal(http.StatusOK))
+	})
+
+	It("runs checks in parallel and fails those exceeding the timeout", func() {
+		s.Health.Timeout = 50 * time.Millisecond
+		block := make(chan struct{})
+		DeferCleanup(func() { close(block) })
+		for _, name := range []string{"a", "b", "c"} {
+			s.Health.AddReadinessCheck(name, func(ctx context.Context) error {
+				select {
+				case <-time.After(30 * time.Millisecond):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}
+		s.Health.AddReadinessCheck("stuck", func(context.Context) error { <-block; return nil })
+
+		start := time.Now()
+		rr := get("/readyz")
+		Expect(time.Since(start)).To(BeNumerically("<", 200*time.Millisecond))
+		Expect(rr.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rr.Body.String()).To(MatchJSON(`{"status":"unavailable","failed":["stuck"]}`))
+	})
+
+	It("keeps liveness separate from readiness", func() {
+		s.Health.AddLivenessCheck("deadlock", func(context.Context) error { return errors.New("stalled") })
+		Expect(get("/livez").Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(get("/readyz").Code).To(Equal(http.StatusOK))
+	})
+
+	It("reports a panicking check as failed", func() {
+		s.Health.AddReadinessCheck("broken", func(context.Context) error { panic("nil map") })
+		rr := get("/readyz")
+		Expect(rr.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rr.Body.String()).To(MatchJSON(`{"status":"unavailable","failed":["broken"]}`))
+		Expect(logs.String()).To(ContainSubstring("nil map"))
+	})
+
+	It("serves the endpoints at configured paths", func() {
+		r := q.New()
+		r.GET("/livez", func(c *q.Context) { c.Text(http.StatusOK, "app") })
+		s = q.NewServer(q.ServerConfig{Health: &q.HealthConfig{LivenessPath: "/healthz/live", ReadinessPath: "/healthz/ready"}}, r, nil)
+		s.Health.AddLivenessCheck("deadlock", func(context.Context) error { return errors.New("stalled") })
+		Expect(get("/healthz/live").Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(get("/healthz/ready").Code).To(Equal(http.StatusOK))
+		Expect(get("/livez").Body.String()).To(Equal("app"))
+	})
+
+	It("is disabled unless configured", func() {
+		r := q.New()
+		r.GET("/livez", func(c *q.Context) { c.Text(http.StatusOK, "app") })
+		s = q.NewServer(q.ServerConfig{}, r, nil)
+		Expect(s.Health).To(BeNil())
+		Expect(get("/livez").Body.String()).To(Equal("app"))
+		Expect(get("/readyz").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,99 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CanonicalHost creates a middleware that redirects requests whose host is
+// not host (e.g. "www.example.com" or "example.com:8443") to the same path
+// and query on host. The comparison is case-insensitive. Behind a proxy
+// listed in Router.TrustedProxies, X-Forwarded-Host and X-Forwarded-Proto
+// are honored; otherwise they are ignored. The redirect target always uses
+// the configured host, never a client-supplied one, so it cannot be steered
+// off-site. A code of 0 means 301.
+func CanonicalHost(host string, code int) Middleware {
+	if code == 0 {
+		code = http.StatusMovedPermanently
+	}
+	canonical := strings.ToLower(host)
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			reqHost := c.R.Host
+			if fh := c.forwardedHeader("X-Forwarded-Host"); fh != "" {
+				reqHost = fh
+			}
+			if strings.ToLower(reqHost) == canonical {
+				next(c)
+				return
+			}
+			scheme := "http"
+			if c.R.TLS != nil {
+				scheme = "https"
+			}
+			if fp := strings.ToLower(c.forwardedHeader("X-Forwarded-Proto")); fp == "http" || fp == "https" {
+				scheme = fp
+			}
+			target := scheme + "://" + host + "/" + strings.TrimLeft(c.R.URL.EscapedPath(), "/")
+			if q := c.R.URL.RawQuery; q != "" {
+				target += "?" + q
+			}
+			c.Redirect(code, target)
+		}
+	}
+}
+
+// TenantFromHost creates a middleware that derives the tenant id from the
+// request host's subdomain under baseDomain and stores it with
+// c.Set("tenant", id). For baseDomain "app.com", "acme.app.com" yields
+// "acme". The apex domain and hosts outside baseDomain pass through with no
+// tenant set. Nested subdomains ("a.b.app.com") and, when valid is non-nil,
+// tenants it rejects respond 404. X-Forwarded-Host is honored only behind a
+// proxy listed in Router.TrustedProxies.
+func TenantFromHost(baseDomain string, valid func(tenant string) bool) Middleware {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			host := c.R.Host
+			if fh := c.forwardedHeader("X-Forwarded-Host"); fh != "" {
+				host = fh
+			}
+			host = strings.TrimSuffix(strings.ToLower(stripPort(host)), ".")
+			tenant, ok := strings.CutSuffix(host, suffix)
+			if !ok {
+				next(c)
+				return
+			}
+			if tenant == "" || strings.Contains(tenant, ".") || (valid != nil && !valid(tenant)) {
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: c.statusMessage(http.StatusNotFound)})
+				return
+			}
+			c.Set("tenant", tenant)
+			next(c)
+		}
+	}
+}
+
+// stripPort removes a trailing :port from host, leaving IPv6 literals intact.
+func stripPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		return host[:i]
+	}
+	return host
+}
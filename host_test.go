@@ -0,0 +1,141 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("CanonicalHost Middleware", func() {
+	newRouter := func() *q.Router {
+		r := q.New()
+		r.Use(q.CanonicalHost("example.com", 0))
+		r.GET("/*", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
+		return r
+	}
+
+	It("redirects a non-canonical host preserving path and query", func() {
+		req := httptest.NewRequest(http.MethodGet, "http://www.example.com/docs/a?x=1&y=2", nil)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("http://example.com/docs/a?x=1&y=2"))
+	})
+
+	It("passes the canonical host through", func() {
+		req := httptest.NewRequest(http.MethodGet, "http://Example.com/docs", nil)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("ok"))
+	})
+
+	It("uses the configured redirect code", func() {
+		r := q.New()
+		r.Use(q.CanonicalHost("example.com", http.StatusPermanentRedirect))
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusOK) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil))
+		Expect(rr.Code).To(Equal(http.StatusPermanentRedirect))
+	})
+
+	It("never redirects off the canonical host", func() {
+		req := httptest.NewRequest(http.MethodGet, "http://www.example.com//evil.com/x", nil)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		Expect(rr.Header().Get("Location")).To(Equal("http://example.com/evil.com/x"))
+	})
+
+	It("honors X-Forwarded-Host and X-Forwarded-Proto from a trusted proxy", func() {
+		r := newRouter()
+		r.TrustedProxies = []string{"10.0.0.0/8"}
+
+		req := httptest.NewRequest(http.MethodGet, "http://internal:8080/p", nil)
+		req.RemoteAddr = "10.1.2.3:5555"
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+
+		req = httptest.NewRequest(http.MethodGet, "http://internal:8080/p", nil)
+		req.RemoteAddr = "10.1.2.3:5555"
+		req.Header.Set("X-Forwarded-Host", "www.example.com")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("https://example.com/p"))
+	})
+
+	It("ignores X-Forwarded-Host from an untrusted peer", func() {
+		req := httptest.NewRequest(http.MethodGet, "http://www.example.com/p", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		req.Header.Set("X-Forwarded-Host", "example.com")
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+	})
+})
+
+var _ = Describe("TenantFromHost Middleware", func() {
+	newRouter := func(valid func(string) bool) *q.Router {
+		r := q.New()
+		r.Use(q.TenantFromHost("app.com", valid))
+		r.GET("/", func(c *q.Context) {
+			tenant, ok := c.Get("tenant")
+			if !ok {
+				tenant = "none"
+			}
+			c.Text(http.StatusOK, tenant.(string))
+		})
+		return r
+	}
+	get := func(r *q.Router, host string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("extracts the tenant from a subdomain", func() {
+		rr := get(newRouter(nil), "Acme.App.com:8080")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("acme"))
+	})
+
+	It("sets no tenant for the apex or a foreign host", func() {
+		Expect(get(newRouter(nil), "app.com").Body.String()).To(Equal("none"))
+		Expect(get(newRouter(nil), "localhost:8080").Body.String()).To(Equal("none"))
+		Expect(get(newRouter(nil), "evilapp.com").Body.String()).To(Equal("none"))
+	})
+
+	It("404s tenants rejected by the validator and nested subdomains", func() {
+		r := newRouter(func(t string) bool { return t == "acme" })
+		Expect(get(r, "acme.app.com").Code).To(Equal(http.StatusOK))
+		rr := get(r, "globex.app.com")
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"not found"}`))
+		Expect(get(newRouter(nil), "a.b.app.com").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,207 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyConfig configures the Idempotency middleware.
+type IdempotencyConfig struct {
+	// Header is the request header carrying the client-supplied key.
+	// Default: "Idempotency-Key".
+	Header string
+
+	// Methods lists the methods the middleware applies to. Safe methods are
+	// better served by Cache. Default: POST, PUT, PATCH, DELETE.
+	Methods []string
+
+	// TTL is how long a stored response can be replayed. Default: 24 hours.
+	TTL time.Duration
+
+	// MaxBodySize is the largest request or response body, in bytes, that is
+	// fingerprinted or stored. A request with a larger body runs the handler
+	// as if it carried no key: it is neither checked nor stored, so a retry
+	// runs the handler again. Default: 1 MB.
+	MaxBodySize int
+
+	// MaxEntries bounds the default in-memory store. Ignored when Store is
+	// set. Default: 1000.
+	MaxEntries int
+
+	// Store holds stored responses. It may be shared with Cache; keys do not
+	// collide. Default: an in-memory store bounded by MaxEntries.
+	Store CacheStore
+
+	// KeyFunc extracts the client identity that scopes keys, so one client
+	// cannot replay or block another's key. Use an API key identity or JWT
+	// subject when clients share an address. When nil, the default uses
+	// Context.ClientIP, as in RateLimit.
+	KeyFunc func(*Context) string
+}
+
+// Idempotency creates a middleware that makes unsafe requests retry-safe. The
+// first request carrying a given Idempotency-Key runs the handler and its
+// response (any status below 500) is stored; later requests with the same
+// key, client, method, and path replay it with an Idempotent-Replayed: true header.
+// A concurrent request with an in-flight key receives 409 Conflict, and
+// reusing a key with a different request body receives 422.
+//
+// Idempotency keys on the client-supplied key and ignores safe methods,
+// while Cache keys on the URL and only handles GET, so the two compose on
+// the same router in either order.
+func Idempotency(cfg IdempotencyConfig) Middleware {
+	if cfg.Header == "" {
+		cfg.Header = "Idempotency-Key"
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryCacheStore(cfg.MaxEntries)
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = defaultKeyFunc
+	}
+	methods := toSet(cfg.Methods)
+
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]struct{})
+	)
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			idemKey := c.R.Header.Get(cfg.Header)
+			if _, ok := methods[c.R.Method]; !ok || idemKey == "" {
+				next(c)
+				return
+			}
+			// Scope by client, method, and path so a key cannot replay another
+			// client's response or another endpoint, and prefix so a shared
+			// store never collides with Cache keys.
+			key := "idempotency " + c.R.Method + " " + c.R.URL.Path + "\n" + cfg.KeyFunc(c) + "\n" + idemKey
+
+			hash, ok, err := fingerprintBody(c, cfg.MaxBodySize)
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "bad request"})
+				return
+			}
+			if !ok {
+				next(c)
+				return
+			}
+
+			if res, ok := cfg.Store.Get(key); ok {
+				replayIdempotent(c, res, hash)
+				return
+			}
+
+			mu.Lock()
+			if _, busy := inFlight[key]; busy {
+				mu.Unlock()
+				c.JSON(http.StatusConflict, ErrorResponse{Error: "request with this idempotency key is in progress"})
+				return
+			}
+			// Re-check under mu: a request holding the key may have stored its
+			// response and released it since the first lookup.
+			if res, ok := cfg.Store.Get(key); ok {
+				mu.Unlock()
+				replayIdempotent(c, res, hash)
+				return
+			}
+			inFlight[key] = struct{}{}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(inFlight, key)
+				mu.Unlock()
+			}()
+
+			before := c.W.Header().Clone()
+			rec := &cacheRecorder{ResponseWriter: c.W, limit: cfg.MaxBodySize}
+			original := c.W
+			c.W = rec
+			next(c)
+			c.W = original
+
+			if res := rec.capture(before); res != nil && res.Status < http.StatusInternalServerError {
+				res.RequestHash = hash
+				cfg.Store.Set(key, res, cfg.TTL)
+			}
+		}
+	}
+}
+
+// replayIdempotent writes a stored response, or 422 when it was produced by a
+// request with a different body.
+func replayIdempotent(c *Context, res *CachedResponse, hash string) {
+	if res.RequestHash != hash {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "idempotency key reused with a different request"})
+		return
+	}
+	c.W.Header().Set("Idempotent-Replayed", "true")
+	writeCached(c, res)
+}
+
+var errBodyTooLarge = errors.New("quokka: request body too large")
+
+// fingerprintBody hashes the request body and restores it for the handler.
+// ok is false, with the body left whole for the handler, when the body is
+// larger than limit.
+func fingerprintBody(c *Context, limit int) (hash string, ok bool, err error) {
+	h := sha256.New()
+	if c.R.Body != nil && c.R.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(c.R.Body, int64(limit)+1))
+		if err != nil {
+			_ = c.R.Body.Close()
+			return "", false, err
+		}
+		if len(body) > limit {
+			c.R.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), c.R.Body), c.R.Body}
+			return "", false, nil
+		}
+		_ = c.R.Body.Close()
+		h.Write(body)
+		c.R.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
@@ -0,0 +1,227 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Idempotency Middleware", func() {
+	send := func(r *q.Router, method, target, key, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("replays the stored response for a repeated key", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{}))
+		r.POST("/orders", func(c *q.Context) {
+			n := calls.Add(1)
+			c.SetHeader("Location", "/orders/1")
+			c.JSON(http.StatusCreated, map[string]int32{"n": n})
+		})
+
+		first := send(r, http.MethodPost, "/orders", "k1", `{"item":"a"}`)
+		Expect(first.Code).To(Equal(http.StatusCreated))
+		second := send(r, http.MethodPost, "/orders", "k1", `{"item":"a"}`)
+		Expect(second.Code).To(Equal(http.StatusCreated))
+		Expect(second.Header().Get("Idempotent-Replayed")).To(Equal("true"))
+		Expect(second.Header().Get("Location")).To(Equal("/orders/1"))
+		Expect(second.Body.String()).To(Equal(first.Body.String()))
+		Expect(calls.Load()).To(Equal(int32(1)))
+
+		send(r, http.MethodPost, "/orders", "k2", `{"item":"a"}`)
+		send(r, http.MethodPost, "/orders", "", `{"item":"a"}`)
+		Expect(calls.Load()).To(Equal(int32(3)))
+	})
+
+	It("rejects a key reused with a different body", func() {
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{}))
+		r.POST("/orders", func(c *q.Context) { c.Status(http.StatusCreated) })
+
+		send(r, http.MethodPost, "/orders", "k", `{"item":"a"}`)
+		rr := send(r, http.MethodPost, "/orders", "k", `{"item":"b"}`)
+		Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("runs oversized requests without fingerprinting or storing them", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{MaxBodySize: 8}))
+		r.POST("/upload", func(c *q.Context) {
+			calls.Add(1)
+			b, _ := io.ReadAll(c.R.Body)
+			c.Text(http.StatusCreated, string(b))
+		})
+
+		body := strings.Repeat("x", 20)
+		first := send(r, http.MethodPost, "/upload", "big", body)
+		Expect(first.Code).To(Equal(http.StatusCreated))
+		Expect(first.Body.String()).To(Equal(body))
+		second := send(r, http.MethodPost, "/upload", "big", body)
+		Expect(second.Code).To(Equal(http.StatusCreated))
+		Expect(second.Header().Get("Idempotent-Replayed")).To(BeEmpty())
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("does not store server errors", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{}))
+		r.POST("/fail", func(c *q.Context) { calls.Add(1); c.Status(http.StatusServiceUnavailable) })
+
+		send(r, http.MethodPost, "/fail", "k", "")
+		send(r, http.MethodPost, "/fail", "k", "")
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("returns 409 while a request with the same key is in flight", func() {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{}))
+		r.POST("/slow", func(c *q.Context) {
+			close(started)
+			<-release
+			c.Status(http.StatusCreated)
+		})
+
+		done := make(chan int)
+		go func() { done <- send(r, http.MethodPost, "/slow", "k", "").Code }()
+		<-started
+		Expect(send(r, http.MethodPost, "/slow", "k", "").Code).To(Equal(http.StatusConflict))
+		close(release)
+		Expect(<-done).To(Equal(http.StatusCreated))
+	})
+
+	It("composes with Cache on a shared store", func() {
+		var posts, gets atomic.Int32
+		store := q.NewMemoryCacheStore(100)
+		r := q.New()
+		r.Use(
+			q.Cache(q.CacheConfig{Store: store}),
+			q.Idempotency(q.IdempotencyConfig{Store: store}),
+		)
+		r.POST("/items", func(c *q.Context) {
+			n := posts.Add(1)
+			c.JSON(http.StatusCreated, map[string]int32{"created": n})
+		})
+		r.GET("/items", func(c *q.Context) {
+			n := gets.Add(1)
+			c.JSON(http.StatusOK, map[string]int32{"listed": n})
+		})
+
+		// POST replays via idempotency, never via the URL cache.
+		p1 := send(r, http.MethodPost, "/items", "abc", `{"name":"x"}`)
+		p2 := send(r, http.MethodPost, "/items", "abc", `{"name":"x"}`)
+		Expect(p2.Header().Get("Idempotent-Replayed")).To(Equal("true"))
+		Expect(p2.Header().Get("X-Cache")).To(BeEmpty())
+		Expect(p2.Body.String()).To(Equal(p1.Body.String()))
+		Expect(posts.Load()).To(Equal(int32(1)))
+
+		// A different key on the same URL is a new request, not a cache hit.
+		send(r, http.MethodPost, "/items", "def", `{"name":"x"}`)
+		Expect(posts.Load()).To(Equal(int32(2)))
+
+		// GET serves from the URL cache; an Idempotency-Key on a GET is ignored.
+		g1 := send(r, http.MethodGet, "/items", "", "")
+		g2 := send(r, http.MethodGet, "/items", "abc", "")
+		Expect(g1.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(g2.Header().Get("X-Cache")).To(Equal("HIT"))
+		Expect(g2.Header().Get("Idempotent-Replayed")).To(BeEmpty())
+		Expect(g2.Body.String()).To(Equal(g1.Body.String()))
+		Expect(gets.Load()).To(Equal(int32(1)))
+	})
+
+	It("replays a response stored after its first lookup missed", func() {
+		var calls atomic.Int32
+		store := &missOnceStore{CacheStore: q.NewMemoryCacheStore(10)}
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{Store: store}))
+		r.POST("/orders", func(c *q.Context) { calls.Add(1); c.Status(http.StatusCreated) })
+
+		send(r, http.MethodPost, "/orders", "k", "")
+		// The next lookup misses as if it ran before the first request
+		// stored its response and released the key.
+		store.miss.Store(true)
+		rr := send(r, http.MethodPost, "/orders", "k", "")
+		Expect(rr.Code).To(Equal(http.StatusCreated))
+		Expect(rr.Header().Get("Idempotent-Replayed")).To(Equal("true"))
+		Expect(calls.Load()).To(Equal(int32(1)))
+	})
+
+	It("scopes keys by client", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{}))
+		r.POST("/orders", func(c *q.Context) { calls.Add(1); c.Status(http.StatusCreated) })
+
+		for _, addr := range []string{"192.0.2.1:1234", "192.0.2.2:1234", "192.0.2.1:5678"} {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.RemoteAddr = addr
+			req.Header.Set("Idempotency-Key", "k")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("scopes keys with a custom KeyFunc", func() {
+		var calls atomic.Int32
+		r := q.New()
+		r.Use(q.Idempotency(q.IdempotencyConfig{
+			KeyFunc: func(c *q.Context) string { return c.Header("X-API-Key") },
+		}))
+		r.POST("/orders", func(c *q.Context) { calls.Add(1); c.Status(http.StatusCreated) })
+
+		for _, client := range []string{"alice", "bob", "alice"} {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.Header.Set("X-API-Key", client)
+			req.Header.Set("Idempotency-Key", "k")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+})
+
+// missOnceStore reports a miss for one lookup after miss is set.
+type missOnceStore struct {
+	q.CacheStore
+	miss atomic.Bool
+}
+
+func (s *missOnceStore) Get(key string) (*q.CachedResponse, bool) {
+	if s.miss.CompareAndSwap(true, false) {
+		return nil, false
+	}
+	return s.CacheStore.Get(key)
+}
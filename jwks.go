@@ -0,0 +1,313 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// jwksMaxBytes bounds the size of a fetched JWKS document.
+const jwksMaxBytes = 1 << 20
+
+// jwksFetchTimeout bounds each JWKS fetch.
+const jwksFetchTimeout = 10 * time.Second
+
+// JWKSOption configures JWKSKeyfunc.
+type JWKSOption func(*jwksConfig)
+
+type jwksConfig struct {
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	client             *http.Client
+}
+
+// JWKSRefreshInterval sets how long fetched keys are used before the JWKS is
+// fetched again. Default: 1 hour.
+func JWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(c *jwksConfig) { c.refreshInterval = d }
+}
+
+// JWKSMinRefreshInterval sets the minimum time between fetch attempts, so
+// forged kids cannot flood the JWKS endpoint and a failing endpoint is not
+// retried on every request. Default: 1 minute.
+func JWKSMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(c *jwksConfig) { c.minRefreshInterval = d }
+}
+
+// JWKSHTTPClient sets the client used to fetch the JWKS. Default:
+// http.DefaultClient. Each fetch is bounded to 10 seconds.
+func JWKSHTTPClient(client *http.Client) JWKSOption {
+	return func(c *jwksConfig) { c.client = client }
+}
+
+// JWKSKeyfunc fetches the JSON Web Key Set at jwksURL and returns a
+// jwt.Keyfunc for JWTConfig.Keyfunc that selects the key matching the token
+// header's kid and alg. RSA, EC (P-256, P-384, P-521) and Ed25519 keys are
+// supported; keys marked for a use other than "sig" are ignored. Keys are
+// cached by kid. After the refresh interval they are fetched again in the
+// background while the cached keys keep serving requests; a token naming an
+// unknown kid waits for a fetch. If a refresh fails the cached keys stay in
+// use and fetches are retried at most once per minimum refresh interval. It
+// returns an error when the initial fetch fails.
+//
+//	keyfunc, err := quokka.JWKSKeyfunc("https://idp.example.com/.well-known/jwks.json")
+//	if err != nil { ... }
+//	r.Use(quokka.JWTAuth(quokka.JWTConfig{Keyfunc: keyfunc}))
+func JWKSKeyfunc(jwksURL string, opts ...JWKSOption) (jwt.Keyfunc, error) {
+	cfg := jwksConfig{
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+		client:             http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ks := &jwks{url: jwksURL, cfg: cfg}
+	if err := ks.refresh(0); err != nil {
+		return nil, err
+	}
+	return ks.keyfunc, nil
+}
+
+// jwks caches the keys of one JWKS endpoint.
+type jwks struct {
+	url        string
+	cfg        jwksConfig
+	refreshMu  sync.Mutex  // serializes fetches
+	refreshing atomic.Bool // a background refresh is running
+	mu         sync.RWMutex
+	keys       []jwk
+	fetchedAt  time.Time // last successful fetch
+	attemptAt  time.Time // last fetch attempt, successful or not
+}
+
+// jwk is a parsed signing key.
+type jwk struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+func (ks *jwks) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg, _ := token.Header["alg"].(string)
+
+	ks.mu.RLock()
+	stale := time.Since(ks.fetchedAt) > ks.cfg.refreshInterval
+	ks.mu.RUnlock()
+	if stale && ks.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer ks.refreshing.Store(false)
+			_ = ks.refresh(ks.cfg.minRefreshInterval)
+		}()
+	}
+	if key, ok := ks.find(kid, alg); ok {
+		return key, nil
+	}
+	if kid != "" {
+		// Even a skipped refresh may have waited on another request's fetch
+		// that brought in the key.
+		_ = ks.refresh(ks.cfg.minRefreshInterval)
+		if key, ok := ks.find(kid, alg); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("quokka: no JWKS key for kid %q and alg %q", kid, alg)
+}
+
+// find returns the key with the given kid that can verify alg. Without a
+// kid, the key is used only if exactly one key can verify alg.
+func (ks *jwks) find(kid, alg string) (crypto.PublicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	var found crypto.PublicKey
+	n := 0
+	for _, k := range ks.keys {
+		if (kid != "" && k.kid != kid) || (k.alg != "" && k.alg != alg) || !keyMatchesAlg(k.key, alg) {
+			continue
+		}
+		if kid != "" {
+			return k.key, true
+		}
+		found = k.key
+		n++
+	}
+	return found, n == 1
+}
+
+// keyMatchesAlg reports whether key's type can verify the JWS algorithm alg.
+func keyMatchesAlg(key crypto.PublicKey, alg string) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+// errJWKSRefreshSkipped reports that a fetch was attempted too recently.
+var errJWKSRefreshSkipped = errors.New("quokka: JWKS fetched too recently")
+
+// refresh fetches the JWKS and replaces the cached keys, unless the last
+// attempt was less than minAge ago. Callers that waited on another fetch
+// therefore reuse its result instead of fetching again.
+func (ks *jwks) refresh(minAge time.Duration) error {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+
+	ks.mu.Lock()
+	if !ks.attemptAt.IsZero() && time.Since(ks.attemptAt) < minAge {
+		ks.mu.Unlock()
+		return errJWKSRefreshSkipped
+	}
+	ks.attemptAt = time.Now()
+	ks.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return fmt.Errorf("quokka: fetching JWKS: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.cfg.client.Do(req) // #nosec G107 -- URL is configured by the application
+	if err != nil {
+		return fmt.Errorf("quokka: fetching JWKS: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("quokka: fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+	var doc struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&doc); err != nil {
+		return fmt.Errorf("quokka: decoding JWKS: %w", err)
+	}
+	keys := make([]jwk, 0, len(doc.Keys))
+	for _, raw := range doc.Keys {
+		if k, err := parseJWK(raw); err == nil {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("quokka: JWKS contains no usable signing keys")
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+// parseJWK parses one JSON Web Key (RFC 7517, RFC 7518, RFC 8037).
+func parseJWK(raw json.RawMessage) (jwk, error) {
+	var k struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return jwk{}, err
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return jwk{}, errors.New("not a signing key")
+	}
+	out := jwk{kid: k.Kid, alg: k.Alg}
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return jwk{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return jwk{}, errors.New("invalid RSA exponent")
+		}
+		exp := new(big.Int).SetBytes(e)
+		out.key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return jwk{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return jwk{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return jwk{}, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return jwk{}, errors.New("invalid EC coordinates")
+		}
+		pub, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return jwk{}, err
+		}
+		out.key = pub
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return jwk{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return jwk{}, errors.New("invalid Ed25519 key")
+		}
+		out.key = ed25519.PublicKey(x)
+	default:
+		return jwk{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	return out, nil
+}
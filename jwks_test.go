@@ -0,0 +1,262 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+}
+
+func ecJWK(kid string, pub *ecdsa.PublicKey) map[string]string {
+	b, err := pub.Bytes()
+	Expect(err).NotTo(HaveOccurred())
+	n := (len(b) - 1) / 2
+	return map[string]string{"kty": "EC", "kid": kid, "crv": pub.Curve.Params().Name, "x": b64(b[1 : 1+n]), "y": b64(b[1+n:])}
+}
+
+func edJWK(kid string, pub ed25519.PublicKey) map[string]string {
+	return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(pub)}
+}
+
+var _ = Describe("JWKSKeyfunc", func() {
+	var (
+		rsaKey  *rsa.PrivateKey
+		ecKey   *ecdsa.PrivateKey
+		edKey   ed25519.PrivateKey
+		mu      sync.Mutex
+		keys    []map[string]string
+		fetches atomic.Int32
+		srv     *httptest.Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		_, edKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		keys = []map[string]string{
+			rsaJWK("rsa-1", &rsaKey.PublicKey),
+			ecJWK("ec-1", &ecKey.PublicKey),
+			edJWK("ed-1", edKey.Public().(ed25519.PublicKey)),
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		}
+		fetches.Store(0)
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fetches.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		}))
+		DeferCleanup(srv.Close)
+	})
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		tok := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "u1", "exp": time.Now().Add(time.Minute).Unix()})
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(key)
+		Expect(err).NotTo(HaveOccurred())
+		return s
+	}
+
+	do := func(keyfunc jwt.Keyfunc, token string) int {
+		r := q.New()
+		r.Use(q.JWTAuth(q.JWTConfig{Keyfunc: keyfunc}))
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusOK) })
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	It("verifies RS256, ES256 and EdDSA tokens by kid", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))).To(Equal(http.StatusOK))
+		Expect(do(keyfunc, sign(jwt.SigningMethodES256, "ec-1", ecKey))).To(Equal(http.StatusOK))
+		Expect(do(keyfunc, sign(jwt.SigningMethodEdDSA, "ed-1", edKey))).To(Equal(http.StatusOK))
+		Expect(fetches.Load()).To(Equal(int32(1)))
+	})
+
+	It("rejects tokens whose alg does not match the key", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(keyfunc, sign(jwt.SigningMethodHS256, "rsa-1", []byte("secret")))).To(Equal(http.StatusUnauthorized))
+		Expect(do(keyfunc, sign(jwt.SigningMethodES256, "rsa-1", ecKey))).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("selects the only matching key when the token has no kid", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(keyfunc, sign(jwt.SigningMethodEdDSA, "", edKey))).To(Equal(http.StatusOK))
+	})
+
+	It("refetches the JWKS for an unknown kid, at most once per minimum interval", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL, q.JWKSMinRefreshInterval(0))
+		Expect(err).NotTo(HaveOccurred())
+
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		mu.Lock()
+		keys = append(keys, rsaJWK("rsa-2", &rotated.PublicKey))
+		mu.Unlock()
+		Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "rsa-2", rotated))).To(Equal(http.StatusOK))
+		Expect(fetches.Load()).To(Equal(int32(2)))
+
+		limited, err := q.JWKSKeyfunc(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(limited, sign(jwt.SigningMethodRS256, "unknown", rotated))).To(Equal(http.StatusUnauthorized))
+		Expect(fetches.Load()).To(Equal(int32(3)))
+	})
+
+	It("refetches the JWKS after the refresh interval", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL, q.JWKSRefreshInterval(time.Nanosecond), q.JWKSMinRefreshInterval(0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))).To(Equal(http.StatusOK))
+		Eventually(fetches.Load).Should(BeNumerically(">=", 2))
+	})
+
+	It("returns an error when the initial fetch fails", func() {
+		bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer bad.Close()
+		_, err := q.JWKSKeyfunc(bad.URL, q.JWKSHTTPClient(bad.Client()))
+		Expect(err).To(MatchError(ContainSubstring("unexpected status 500")))
+	})
+
+	It("serves cached keys while a stale JWKS is refreshed in the background", func() {
+		var blocked atomic.Bool
+		release := make(chan struct{})
+		var slowFetches atomic.Int32
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			slowFetches.Add(1)
+			if blocked.Load() {
+				<-release
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		}))
+		DeferCleanup(slow.Close)
+		DeferCleanup(func() { close(release) })
+
+		keyfunc, err := q.JWKSKeyfunc(slow.URL, q.JWKSRefreshInterval(time.Nanosecond), q.JWKSMinRefreshInterval(0))
+		Expect(err).NotTo(HaveOccurred())
+		blocked.Store(true)
+		Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))).To(Equal(http.StatusOK))
+		Eventually(slowFetches.Load).Should(Equal(int32(2)))
+	})
+
+	It("backs off after a failed refresh", func() {
+		var failing atomic.Bool
+		var attempts atomic.Int32
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			attempts.Add(1)
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		}))
+		DeferCleanup(flaky.Close)
+
+		keyfunc, err := q.JWKSKeyfunc(flaky.URL, q.JWKSMinRefreshInterval(50*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(60 * time.Millisecond)
+		failing.Store(true)
+		for i := 0; i < 3; i++ {
+			Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "unknown", rsaKey))).To(Equal(http.StatusUnauthorized))
+		}
+		Expect(attempts.Load()).To(Equal(int32(2)))
+		Expect(do(keyfunc, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))).To(Equal(http.StatusOK))
+	})
+
+	It("fetches once for concurrent tokens with the same unknown kid", func() {
+		keyfunc, err := q.JWKSKeyfunc(srv.URL, q.JWKSMinRefreshInterval(50*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		mu.Lock()
+		keys = append(keys, rsaJWK("rsa-2", &rotated.PublicKey))
+		mu.Unlock()
+		time.Sleep(60 * time.Millisecond)
+
+		token := sign(jwt.SigningMethodRS256, "rsa-2", rotated)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				Expect(do(keyfunc, token)).To(Equal(http.StatusOK))
+			}()
+		}
+		wg.Wait()
+		Expect(fetches.Load()).To(Equal(int32(2)))
+	})
+
+	It("verifies ES384, ES512 and PS256 tokens through JWTAuth", func() {
+		p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		pss := rsaJWK("pss-1", &rsaKey.PublicKey)
+		pss["alg"] = "PS256"
+		mu.Lock()
+		keys = append(keys, ecJWK("ec-384", &p384.PublicKey), ecJWK("ec-521", &p521.PublicKey), pss)
+		mu.Unlock()
+
+		keyfunc, err := q.JWKSKeyfunc(srv.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(do(keyfunc, sign(jwt.SigningMethodES384, "ec-384", p384))).To(Equal(http.StatusOK))
+		Expect(do(keyfunc, sign(jwt.SigningMethodES512, "ec-521", p521))).To(Equal(http.StatusOK))
+		Expect(do(keyfunc, sign(jwt.SigningMethodPS256, "pss-1", rsaKey))).To(Equal(http.StatusOK))
+		Expect(do(keyfunc, sign(jwt.SigningMethodES384, "ec-1", p384))).To(Equal(http.StatusUnauthorized))
+	})
+})
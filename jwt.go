@@ -19,7 +19,9 @@ package quokka
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -49,16 +51,83 @@ func JWTClaims(ctx context.Context) (jwt.MapClaims, bool) {
 // JWTConfig configures the JWT middleware.
 // Provide at least a Keyfunc to resolve the verification key.
 // Issuer, Audience, Skew fields can enforce issuer/audience and clock skew.
-// If Optional is true, requests without Authorization header pass through unmodified.
-// Only Bearer tokens are considered.
+// If Optional is true, requests without a token pass through unmodified.
+// TokenLookup selects where tokens are read from; in the Authorization header
+// only Bearer tokens are considered.
 // Errors result in 401 with WWW-Authenticate and JSON error payload.
-// Note: This middleware does not perform authorization beyond claim validation.
+// Note: This middleware does not perform authorization beyond claim validation;
+// add RequireScopes, RequireRoles, or RequireClaimValues after it for that.
 type JWTConfig struct {
 	Keyfunc  jwt.Keyfunc
 	Issuer   string
 	Audience string
 	Skew     time.Duration
 	Optional bool
+
+	// TokenLookup lists comma-separated token sources tried in order, each
+	// "header:<name>", "cookie:<name>" or "query:<name>", e.g.
+	// "header:Authorization,cookie:jwt,query:access_token". The first
+	// non-empty source wins; header values must use the Bearer scheme.
+	// Default: "header:Authorization".
+	TokenLookup string
+
+	// IsRevoked, when set, is called after the token is validated, e.g. to
+	// look up its "jti" claim in a denylist. Revoked tokens get 401. If it
+	// returns an error the request gets 401 too, unless RevocationFailOpen
+	// is true; the error is logged either way.
+	IsRevoked func(ctx context.Context, claims jwt.MapClaims) (bool, error)
+
+	// RevocationFailOpen lets requests through when IsRevoked fails.
+	RevocationFailOpen bool
+}
+
+// tokenSource is one entry of JWTConfig.TokenLookup.
+type tokenSource struct {
+	kind string // "header", "cookie" or "query"
+	name string
+}
+
+// parseTokenLookup parses a TokenLookup spec, panicking on malformed entries
+// so misconfiguration fails at startup.
+func parseTokenLookup(spec string) []tokenSource {
+	var sources []tokenSource
+	for _, part := range strings.Split(spec, ",") {
+		kind, name, ok := strings.Cut(strings.TrimSpace(part), ":")
+		kind, name = strings.TrimSpace(kind), strings.TrimSpace(name)
+		if !ok || name == "" || (kind != "header" && kind != "cookie" && kind != "query") {
+			panic("quokka: invalid JWTConfig.TokenLookup entry " + strconv.Quote(part) +
+				`: want "header:<name>", "cookie:<name>" or "query:<name>"`)
+		}
+		sources = append(sources, tokenSource{kind: kind, name: name})
+	}
+	return sources
+}
+
+// lookupToken returns the token from the first non-empty source. found is
+// false when every source is empty; a header value not using the Bearer
+// scheme yields an empty token with found true.
+func lookupToken(c *Context, sources []tokenSource) (token string, found bool) {
+	for _, src := range sources {
+		var v string
+		switch src.kind {
+		case "header":
+			v = c.R.Header.Get(src.name)
+			if v != "" {
+				token, _ = bearerToken(v)
+				return token, true
+			}
+		case "cookie":
+			if ck, err := c.R.Cookie(src.name); err == nil {
+				v = ck.Value
+			}
+		case "query":
+			v = c.Query(src.name)
+		}
+		if v != "" {
+			return v, true
+		}
+	}
+	return "", false
 }
 
 // JWTAuth creates a middleware that validates Bearer JWTs and injects claims into the request context.
@@ -66,26 +135,32 @@ func JWTAuth(cfg JWTConfig) Middleware {
 	if cfg.Skew == 0 {
 		cfg.Skew = 30 * time.Second
 	}
+	if cfg.TokenLookup == "" {
+		cfg.TokenLookup = "header:Authorization"
+	}
+	sources := parseTokenLookup(cfg.TokenLookup)
+	missing := "missing token"
+	if len(sources) == 1 && sources[0].kind == "header" {
+		missing = "missing " + sources[0].name + " header"
+	}
 	return func(next Handler) Handler {
 		return func(c *Context) {
-			authz := c.R.Header.Get("Authorization")
-			if authz == "" {
+			tokStr, found := lookupToken(c, sources)
+			if !found {
 				if cfg.Optional {
 					next(c)
 					return
 				}
-				unauthorized(c, "missing Authorization header")
+				unauthorized(c, missing)
 				return
 			}
-			parts := strings.SplitN(authz, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			if tokStr == "" {
 				unauthorized(c, "invalid Authorization scheme")
 				return
 			}
-			tokStr := parts[1]
 
 			opts := []jwt.ParserOption{
-				jwt.WithValidMethods([]string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "EdDSA"}),
+				jwt.WithValidMethods([]string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
 				jwt.WithLeeway(cfg.Skew),
 			}
 			if cfg.Issuer != "" {
@@ -96,18 +171,30 @@ func JWTAuth(cfg JWTConfig) Middleware {
 			}
 			parser := jwt.NewParser(opts...)
 
-			var claims jwt.MapClaims
 			tok, err := parser.ParseWithClaims(tokStr, jwt.MapClaims{}, cfg.Keyfunc)
 			if err != nil {
 				unauthorized(c, fmt.Sprintf("token parse/verify failed: %v", err))
 				return
 			}
-			var ok bool
-			claims, ok = tok.Claims.(jwt.MapClaims)
+			claims, ok := tok.Claims.(jwt.MapClaims)
 			if !ok || !tok.Valid {
 				unauthorized(c, "invalid token claims")
 				return
 			}
+			if cfg.IsRevoked != nil {
+				revoked, err := cfg.IsRevoked(c.R.Context(), claims)
+				if err != nil {
+					slog.Error("token revocation check failed", slog.Any("err", err))
+					if !cfg.RevocationFailOpen {
+						unauthorized(c, "token revocation check failed")
+						return
+					}
+				}
+				if revoked {
+					unauthorized(c, "token has been revoked")
+					return
+				}
+			}
 
 			// store claims in context and proceed
 			c.R = c.R.WithContext(WithJWTClaims(c.R.Context(), claims))
@@ -116,6 +203,67 @@ func JWTAuth(cfg JWTConfig) Middleware {
 	}
 }
 
+// RequireScopes creates a middleware that allows only requests whose JWT
+// claims, placed in the context by JWTAuth, grant every scope in scopes. The
+// "scope" claim may be a space-separated string (RFC 8693) or an array.
+// Requests without claims get 401; missing scopes get 403.
+func RequireScopes(scopes ...string) Middleware {
+	return RequireClaimValues("scope", scopes...)
+}
+
+// RequireRoles is like RequireScopes but checks the "roles" claim.
+func RequireRoles(roles ...string) Middleware {
+	return RequireClaimValues("roles", roles...)
+}
+
+// RequireClaimValues creates a middleware that allows only requests whose
+// JWT claim holds every value in values, for identity providers that use
+// other claim names (e.g. "scp" or "groups"). The claim may be a
+// space-separated string or an array of strings.
+func RequireClaimValues(claim string, values ...string) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			claims, ok := JWTClaims(c.R.Context())
+			if !ok {
+				unauthorized(c, "missing token claims")
+				return
+			}
+			granted := claimValues(claims[claim])
+			for _, v := range values {
+				if _, ok := granted[v]; !ok {
+					c.W.Header().Set("WWW-Authenticate", "Bearer error=\"insufficient_scope\"")
+					c.JSON(http.StatusForbidden, ErrorResponse{Error: "forbidden", Message: "missing required " + claim + ": " + v})
+					return
+				}
+			}
+			next(c)
+		}
+	}
+}
+
+// claimValues returns the set of values held by a space-separated string or
+// array claim.
+func claimValues(v any) map[string]struct{} {
+	set := make(map[string]struct{})
+	switch vals := v.(type) {
+	case string:
+		for _, s := range strings.Fields(vals) {
+			set[s] = struct{}{}
+		}
+	case []string:
+		for _, s := range vals {
+			set[s] = struct{}{}
+		}
+	case []any:
+		for _, e := range vals {
+			if s, ok := e.(string); ok {
+				set[s] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
 func unauthorized(c *Context, desc string) {
 	c.W.Header().Set("WWW-Authenticate", "Bearer error=\"invalid_token\", error_description=\""+escapeAuthParam(desc)+"\"")
 	c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: desc})
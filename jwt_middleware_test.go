@@ -17,11 +17,13 @@
 package quokka_test
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -81,6 +83,7 @@ var _ = Describe("JWT Middleware", func() {
 		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
 		Expect(rr.Header().Get("WWW-Authenticate")).To(ContainSubstring("Bearer"))
 		Expect(rr.Body.String()).To(ContainSubstring("unauthorized"))
+		Expect(rr.Body.String()).To(ContainSubstring("missing Authorization header"))
 	})
 
 	It("allows optional mode to pass through without token", func() {
@@ -352,4 +355,154 @@ var _ = Describe("JWT Middleware", func() {
 		r.ServeHTTP(rr, req)
 		Expect(rr.Code).To(Equal(http.StatusOK))
 	})
+
+	Describe("RequireScopes and RequireRoles", func() {
+		sign := func(claims jwt.MapClaims) string {
+			claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
+			s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+			Expect(err).NotTo(HaveOccurred())
+			return s
+		}
+		do := func(r *q.Router, path, token string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+		var r *q.Router
+
+		BeforeEach(func() {
+			r = q.New()
+			auth := q.JWTAuth(q.JWTConfig{Keyfunc: keyfunc, Optional: true})
+			ok := func(c *q.Context) { c.Status(http.StatusOK) }
+			r.GET("/orders", ok, auth, q.RequireScopes("orders:read", "orders:write"))
+			r.GET("/admin", ok, auth, q.RequireRoles("admin"))
+			r.GET("/groups", ok, auth, q.RequireClaimValues("groups", "ops"))
+		})
+
+		It("allows tokens with sufficient scopes and roles", func() {
+			tok := sign(jwt.MapClaims{"scope": "orders:read profile orders:write", "roles": []string{"user", "admin"}, "groups": "ops"})
+			Expect(do(r, "/orders", tok).Code).To(Equal(http.StatusOK))
+			Expect(do(r, "/admin", tok).Code).To(Equal(http.StatusOK))
+			Expect(do(r, "/groups", tok).Code).To(Equal(http.StatusOK))
+		})
+
+		It("answers 403 when a required scope or role is missing", func() {
+			tok := sign(jwt.MapClaims{"scope": "orders:read", "roles": []string{"user"}})
+			rr := do(r, "/orders", tok)
+			Expect(rr.Code).To(Equal(http.StatusForbidden))
+			Expect(rr.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="insufficient_scope"`))
+			Expect(rr.Body.String()).To(ContainSubstring("orders:write"))
+			Expect(do(r, "/admin", tok).Code).To(Equal(http.StatusForbidden))
+			Expect(do(r, "/groups", tok).Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("answers 401 when no token claims are present", func() {
+			rr := do(r, "/orders", "")
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Header().Get("WWW-Authenticate")).To(ContainSubstring("Bearer"))
+		})
+	})
+
+	Describe("TokenLookup", func() {
+		var (
+			r     *q.Router
+			token string
+		)
+
+		BeforeEach(func() {
+			r = q.New()
+			r.Use(q.JWTAuth(q.JWTConfig{Keyfunc: keyfunc, TokenLookup: "header:Authorization, cookie:jwt, query:access_token"}))
+			r.GET("/p", func(c *q.Context) { c.Status(http.StatusOK) })
+			var err error
+			token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()}).SignedString(secret)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reads the token from a cookie", func() {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/p", nil)
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+		})
+
+		It("reads the token from a query parameter", func() {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/p?access_token="+token, nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+		})
+
+		It("still accepts a Bearer header, which wins over later sources", func() {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/p?access_token=bogus", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+
+			rr = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/p?access_token="+token, nil)
+			req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+			r.ServeHTTP(rr, req)
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("answers 401 when no source has a token", func() {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/p", nil))
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Body.String()).To(ContainSubstring("missing token"))
+		})
+
+		It("panics at construction naming a malformed TokenLookup entry", func() {
+			for spec, entry := range map[string]string{
+				"form:token":                   `"form:token"`,
+				"header:Authorization,cookie":  `"cookie"`,
+				"header:Authorization,":        `""`,
+				"query:access_token, header: ": `" header: "`,
+			} {
+				Expect(func() { q.JWTAuth(q.JWTConfig{Keyfunc: keyfunc, TokenLookup: spec}) }).
+					To(PanicWith(ContainSubstring("invalid JWTConfig.TokenLookup entry "+entry)), spec)
+			}
+		})
+	})
+
+	Describe("IsRevoked", func() {
+		signJTI := func(jti string) string {
+			s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"jti": jti, "exp": time.Now().Add(time.Minute).Unix()}).SignedString(secret)
+			Expect(err).NotTo(HaveOccurred())
+			return s
+		}
+		do := func(cfg q.JWTConfig, token string) *httptest.ResponseRecorder {
+			r := q.New()
+			r.Use(q.JWTAuth(cfg))
+			r.GET("/p", func(c *q.Context) { c.Status(http.StatusOK) })
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/p", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(rr, req)
+			return rr
+		}
+		denylist := func(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+			Expect(ctx).NotTo(BeNil())
+			return claims["jti"] == "revoked-1", nil
+		}
+
+		It("rejects a revoked jti and accepts others", func() {
+			cfg := q.JWTConfig{Keyfunc: keyfunc, IsRevoked: denylist}
+			rr := do(cfg, signJTI("revoked-1"))
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Body.String()).To(ContainSubstring("revoked"))
+			Expect(do(cfg, signJTI("active-1")).Code).To(Equal(http.StatusOK))
+		})
+
+		It("fails closed on errors unless RevocationFailOpen is set", func() {
+			failing := func(context.Context, jwt.MapClaims) (bool, error) { return false, errors.New("denylist unavailable") }
+			Expect(do(q.JWTConfig{Keyfunc: keyfunc, IsRevoked: failing}, signJTI("a")).Code).To(Equal(http.StatusUnauthorized))
+			Expect(do(q.JWTConfig{Keyfunc: keyfunc, IsRevoked: failing, RevocationFailOpen: true}, signJTI("a")).Code).To(Equal(http.StatusOK))
+		})
+	})
 })
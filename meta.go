@@ -0,0 +1,109 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+// Meta attaches metadata to the route, merging it over any metadata the
+// route already has (including its group's):
+//
+//	r.GET("/admin", h).Meta(map[string]any{"scope": "admin"})
+//
+// Any middleware, including router-level middleware added with Use, can read
+// the values via Context.RouteMeta.
+func (rt *Route) Meta(meta map[string]any) *Route {
+	rt.r.mu.Lock()
+	defer rt.r.mu.Unlock()
+	rt.n.setMeta(rt.Method, meta)
+	return rt
+}
+
+// Meta attaches metadata to every method on the route, including methods
+// registered before the call; see Route.Meta.
+func (b *RouteBuilder) Meta(meta map[string]any) *RouteBuilder {
+	b.meta = mergeMeta(b.meta, meta)
+	for _, m := range b.methods {
+		b.r.handleWithPrefix(b.prefix, m, b.path, b.handlers[m], b.meta, b.mw...)
+	}
+	return b
+}
+
+// Meta attaches metadata to routes registered on the group after the call.
+// Route values override group values for the same key.
+func (g *Group) Meta(meta map[string]any) *Group {
+	g.meta = mergeMeta(g.meta, meta)
+	return g
+}
+
+// setMeta merges meta over the metadata stored for method. The stored map is
+// replaced, not modified, since requests in flight may hold it. Callers must
+// hold the router's lock.
+func (n *node) setMeta(method string, meta map[string]any) {
+	if len(meta) == 0 {
+		return
+	}
+	if n.meta == nil {
+		n.meta = make(map[string]map[string]any)
+	}
+	n.meta[method] = mergeMeta(n.meta[method], meta)
+}
+
+// mergeMeta returns a new map holding base overlaid with meta.
+func mergeMeta(base, meta map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(meta))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range meta {
+		out[k] = v
+	}
+	return out
+}
+
+// MetaPublic is the route metadata key that marks a route as public.
+const MetaPublic = "public"
+
+// Public marks the route as public, e.g. r.GET("/health", h).Public(). It is
+// shorthand for Meta(map[string]any{MetaPublic: true}).
+func (rt *Route) Public() *Route {
+	return rt.Meta(map[string]any{MetaPublic: true})
+}
+
+// Public marks every method on the route as public; see Route.Public.
+func (b *RouteBuilder) Public() *RouteBuilder {
+	return b.Meta(map[string]any{MetaPublic: true})
+}
+
+// Public marks routes registered on the group after the call as public.
+func (g *Group) Public() *Group {
+	return g.Meta(map[string]any{MetaPublic: true})
+}
+
+// IsPublic reports whether the matched route carries public: true metadata.
+func IsPublic(c *Context) bool {
+	v, ok := c.RouteMeta(MetaPublic)
+	public, _ := v.(bool)
+	return ok && public
+}
+
+// AuthUnlessPublic applies auth to every route except those marked with
+// Public (or public: true metadata), so a single router-level auth
+// middleware can protect an app while leaving selected endpoints open:
+//
+//	r.Use(quokka.AuthUnlessPublic(quokka.JWTAuth(cfg)))
+//	r.GET("/health", health).Public()
+func AuthUnlessPublic(auth Middleware) Middleware {
+	return Unless(IsPublic, auth)
+}
@@ -0,0 +1,154 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Route metadata", func() {
+	It("exposes route metadata to the handler", func() {
+		r := q.New()
+		var ttl any
+		var ok bool
+		r.GET("/items", func(c *q.Context) {
+			ttl, ok = c.RouteMeta("cache_ttl")
+			c.Status(http.StatusOK)
+		}).Meta(map[string]any{"cache_ttl": 30})
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+		Expect(ok).To(BeTrue())
+		Expect(ttl).To(Equal(30))
+	})
+
+	It("lets router-level middleware enforce a per-route scope", func() {
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) {
+				if scope, ok := c.RouteMeta("scope"); ok && c.Header("X-Scope") != scope {
+					c.Status(http.StatusForbidden)
+					return
+				}
+				next(c)
+			}
+		})
+		r.GET("/admin", func(c *q.Context) { c.Status(http.StatusOK) }).Meta(map[string]any{"scope": "admin"})
+		r.GET("/public", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		Expect(rr.Code).To(Equal(http.StatusForbidden))
+
+		rr = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("X-Scope", "admin")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/public", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("merges group and route metadata per method", func() {
+		r := q.New()
+		g := r.Group("/api").Meta(map[string]any{"tier": "api", "scope": "read"})
+		var got map[string]any
+		capture := func(c *q.Context) {
+			tier, _ := c.RouteMeta("tier")
+			scope, _ := c.RouteMeta("scope")
+			got = map[string]any{"tier": tier, "scope": scope}
+			c.Status(http.StatusOK)
+		}
+		g.GET("/items", capture)
+		g.POST("/items", capture).Meta(map[string]any{"scope": "write"})
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		Expect(got).To(Equal(map[string]any{"tier": "api", "scope": "read"}))
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/items", nil))
+		Expect(got).To(Equal(map[string]any{"tier": "api", "scope": "write"}))
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/api/items", nil))
+		Expect(got).To(Equal(map[string]any{"tier": "api", "scope": "read"}))
+	})
+
+	It("applies builder metadata to every method and clears it on re-registration", func() {
+		r := q.New()
+		var got []any
+		capture := func(c *q.Context) { v, _ := c.RouteMeta("k"); got = append(got, v); c.Status(http.StatusOK) }
+		r.Route("/items").GET(capture).Meta(map[string]any{"k": 1}).POST(capture)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items", nil))
+		Expect(got).To(Equal([]any{1, 1}))
+
+		r.GET("/items", capture)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+		Expect(got[2]).To(BeNil())
+	})
+
+	It("marks groups public", func() {
+		r := q.New()
+		var public bool
+		g := r.Group("/docs").Public()
+		g.GET("/intro", func(c *q.Context) { public = q.IsPublic(c); c.Status(http.StatusOK) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/docs/intro", nil))
+		Expect(public).To(BeTrue())
+	})
+
+	It("AuthUnlessPublic skips auth only for public routes", func() {
+		r := q.New()
+		var authRuns int
+		auth := func(next q.Handler) q.Handler {
+			return func(c *q.Context) {
+				authRuns++
+				if c.Header("Authorization") == "" {
+					c.Status(http.StatusUnauthorized)
+					return
+				}
+				next(c)
+			}
+		}
+		r.Use(q.AuthUnlessPublic(auth))
+		r.GET("/health", func(c *q.Context) { c.Status(http.StatusOK) }).Public()
+		r.GET("/docs", func(c *q.Context) { c.Status(http.StatusOK) }).Meta(map[string]any{"public": true})
+		r.GET("/account", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		for path, want := range map[string]int{
+			"/health":  http.StatusOK,
+			"/docs":    http.StatusOK,
+			"/account": http.StatusUnauthorized,
+		} {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(rr.Code).To(Equal(want), path)
+		}
+		Expect(authRuns).To(Equal(1))
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/account", nil)
+		req.Header.Set("Authorization", "Bearer x")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,109 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"io"
+	"net/http"
+)
+
+// SizeSample describes the request and response sizes of one request.
+type SizeSample struct {
+	Route         string // matched route pattern; empty when no route matched
+	Method        string
+	Status        int
+	RequestBytes  int64 // Content-Length, or bytes read when the length is unknown
+	ResponseBytes int64 // body bytes written by the handler chain
+}
+
+// SizeMetrics creates a middleware that reports request and response body
+// sizes labeled by route pattern and method. observe is called once per
+// request after the handler returns; feed it into a histogram, e.g.
+//
+//	quokka.SizeMetrics(func(s quokka.SizeSample) {
+//		reqSize.WithLabelValues(s.Route, s.Method).Observe(float64(s.RequestBytes))
+//		respSize.WithLabelValues(s.Route, s.Method).Observe(float64(s.ResponseBytes))
+//	})
+//
+// Register it outside compression middleware to observe uncompressed sizes
+// or inside to observe bytes on the wire.
+func SizeMetrics(observe func(SizeSample)) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			body := &countingBody{ReadCloser: c.R.Body}
+			if c.R.Body != nil {
+				c.R.Body = body
+			}
+			sw := &sizeWriter{ResponseWriter: c.W}
+			original := c.W
+			c.W = sw
+			defer func() { c.W = original }()
+
+			next(c)
+
+			reqBytes := c.R.ContentLength
+			if reqBytes < 0 {
+				reqBytes = body.n
+			}
+			status := c.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			observe(SizeSample{
+				Route:         c.RoutePattern(),
+				Method:        c.R.Method,
+				Status:        status,
+				RequestBytes:  reqBytes,
+				ResponseBytes: sw.n,
+			})
+		}
+	}
+}
+
+// countingBody counts bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// sizeWriter counts response body bytes written through it.
+type sizeWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *sizeWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming compatibility.
+func (w *sizeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *sizeWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,76 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("SizeMetrics", func() {
+	It("observes request and response sizes by route pattern", func() {
+		var samples []q.SizeSample
+		r := q.New()
+		r.Use(q.SizeMetrics(func(s q.SizeSample) { samples = append(samples, s) }))
+		r.POST("/users/:id/notes", func(c *q.Context) {
+			_, _ = io.ReadAll(c.R.Body)
+			c.Text(http.StatusCreated, "0123456789")
+		})
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users/42/notes", strings.NewReader("hello world")))
+		Expect(rr.Code).To(Equal(http.StatusCreated))
+		Expect(samples).To(Equal([]q.SizeSample{{
+			Route:         "/users/:id/notes",
+			Method:        http.MethodPost,
+			Status:        http.StatusCreated,
+			RequestBytes:  11,
+			ResponseBytes: 10,
+		}}))
+	})
+
+	It("counts bytes read when Content-Length is unknown", func() {
+		var sample q.SizeSample
+		r := q.New()
+		r.Use(q.SizeMetrics(func(s q.SizeSample) { sample = s }))
+		r.PUT("/blob", func(c *q.Context) { _, _ = io.ReadAll(c.R.Body); c.Status(http.StatusNoContent) })
+
+		req := httptest.NewRequest(http.MethodPut, "/blob", io.NopCloser(strings.NewReader("chunked-body")))
+		req.ContentLength = -1
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(sample.RequestBytes).To(Equal(int64(12)))
+		Expect(sample.ResponseBytes).To(BeZero())
+		Expect(sample.Status).To(Equal(http.StatusNoContent))
+	})
+
+	It("reports an empty route for unmatched requests", func() {
+		var sample q.SizeSample
+		r := q.New()
+		r.Use(q.SizeMetrics(func(s q.SizeSample) { sample = s }))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+		Expect(sample.Route).To(BeEmpty())
+		Expect(sample.Status).To(Equal(http.StatusNotFound))
+		Expect(sample.ResponseBytes).To(BeNumerically(">", 0))
+	})
+})
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 	"sync/atomic"
 	"time"
 )
@@ -41,14 +42,71 @@ func randomID() string {
 	return hex.EncodeToString(b)
 }
 
-// chain composes middlewares around a final handler
+// loggerOrDefault returns l, or slog.Default() when l is nil. Every
+// constructor that accepts a *slog.Logger goes through it so nil is always
+// safe to pass.
+func loggerOrDefault(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// chain composes middlewares around a final handler. Each handler a middleware
+// receives as next is guarded, so once the Context is aborted, calling next
+// returns immediately and the remaining middleware and the final handler are
+// skipped.
 func chain(mw []Middleware, h Handler) Handler {
+	h = skipIfAborted(h)
 	for i := len(mw) - 1; i >= 0; i-- {
-		h = mw[i](h)
+		h = skipIfAborted(mw[i](h))
 	}
 	return h
 }
 
+func skipIfAborted(h Handler) Handler {
+	return func(c *Context) {
+		if c.IsAborted() {
+			return
+		}
+		h(c)
+	}
+}
+
+// NamedMiddleware wraps mw so that its name is recorded on the Context when it
+// runs. The recorded names are available via Context.AppliedMiddleware, which
+// is useful for auditing that a given middleware (e.g. auth) ran on a route.
+func NamedMiddleware(name string, mw Middleware) Middleware {
+	return func(next Handler) Handler {
+		h := mw(next)
+		return func(c *Context) {
+			c.applied = append(c.applied, name)
+			h(c)
+		}
+	}
+}
+
+// When applies mw only to requests for which cond returns true; other
+// requests go straight to the next handler. cond runs per request after
+// routing, so it may inspect Context.RouteMeta or Context.RoutePattern.
+func When(cond func(*Context) bool, mw Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := mw(next)
+		return func(c *Context) {
+			if cond(c) {
+				wrapped(c)
+				return
+			}
+			next(c)
+		}
+	}
+}
+
+// Unless applies mw to every request except those for which cond returns true.
+func Unless(cond func(*Context) bool, mw Middleware) Middleware {
+	return When(func(c *Context) bool { return !cond(c) }, mw)
+}
+
 // LoggerConfig configures the Logger middleware.
 type LoggerConfig struct {
 	// Logger is the slog.Logger used for output. When set, Output is ignored.
@@ -68,39 +126,91 @@ type LoggerConfig struct {
 	// Sanitize enables redaction of sensitive path parameters, query parameters,
 	// and headers in log output. nil means no sanitization.
 	Sanitize *SanitizeConfig
+
+	// Level is the level of request lines. Default: slog.LevelInfo. It is
+	// also the minimum level of the handler created for Output or Dir.
+	Level slog.Leveler
+
+	// SlowThreshold, when positive, logs requests that take longer than this
+	// at WARN with a slow=true attribute. Faster requests are logged at Level.
+	// 5xx responses are always logged at ERROR.
+	SlowThreshold time.Duration
+
+	// AttemptHeader names the request header carrying the client's retry
+	// attempt number. When it holds a positive integer the value is logged
+	// as attempt=N and stored in the request context (see Attempt), so
+	// retries sharing an X-Request-Id can be told apart. Default: "X-Attempt".
+	AttemptHeader string
+
+	// SpanContext, when true, logs trace_id and span_id from an OpenTelemetry
+	// span active in the request context, such as one started by
+	// instrumentation wrapping the router. The ids are also available to
+	// handlers via TraceIDs. Ids recorded by the OTel middleware are always
+	// logged and take precedence.
+	SpanContext bool
+
+	// SkipPaths lists request paths (exact match, e.g. "/health") for which
+	// no log line is written. Request ids are still assigned.
+	SkipPaths []string
 }
 
-// Logger provides structured access logging with request id.
+// Logger provides structured access logging with request id. Requests traced
+// by OTel, registered after Logger, also log trace_id and span_id.
 func Logger(cfg LoggerConfig) Middleware {
+	if cfg.Level == nil {
+		cfg.Level = slog.LevelInfo
+	}
 	logger := cfg.Logger
 	if logger == nil {
+		opts := &slog.HandlerOptions{Level: cfg.Level}
 		switch {
 		case cfg.Output != nil:
-			logger = slog.New(slog.NewTextHandler(cfg.Output, nil))
+			logger = slog.New(slog.NewTextHandler(cfg.Output, opts))
 		case cfg.Dir != "":
 			path := filepath.Join(cfg.Dir, "access.log")
 			f, err := OpenLogFile(path)
 			if err != nil {
 				panic("quokka: cannot open log file " + path + ": " + err.Error())
 			}
-			logger = slog.New(slog.NewTextHandler(f, nil))
-		default:
-			logger = slog.Default()
+			logger = slog.New(slog.NewTextHandler(f, opts))
 		}
 	}
+	logger = loggerOrDefault(logger)
+
+	if cfg.AttemptHeader == "" {
+		cfg.AttemptHeader = "X-Attempt"
+	}
 
 	var san *Sanitizer
 	if cfg.Sanitize != nil {
 		san = NewSanitizer(*cfg.Sanitize)
 	}
 
+	skip := make(map[string]struct{}, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = struct{}{}
+	}
+
 	return func(next Handler) Handler {
 		return func(c *Context) {
 			id := c.R.Header.Get("X-Request-Id")
 			if id == "" {
 				id = randomID()
 			}
-			c.R = c.R.WithContext(WithRequestID(c.R.Context(), id))
+			ctx := WithRequestID(c.R.Context(), id)
+			if cfg.SpanContext {
+				ctx = withSpanTraceIDs(ctx)
+			}
+			c.R = c.R.WithContext(ctx)
+			attempt := 0
+			if n, err := strconv.Atoi(c.R.Header.Get(cfg.AttemptHeader)); err == nil && n > 0 {
+				attempt = n
+				c.R = c.R.WithContext(WithAttempt(c.R.Context(), attempt))
+			}
+			if _, ok := skip[c.R.URL.Path]; ok {
+				next(c)
+				return
+			}
 			start := time.Now()
 			next(c)
 			dur := time.Since(start)
@@ -109,13 +219,28 @@ func Logger(cfg LoggerConfig) Middleware {
 				status = http.StatusOK
 			}
 			logPath := san.Path(c.R.URL.Path, c.params)
-			logger.Info("request",
+			attrs := []slog.Attr{
 				slog.String("id", id),
 				slog.String("method", c.R.Method),
 				slog.String("path", logPath),
 				slog.Int("status", status),
 				slog.String("duration", dur.String()),
-			)
+			}
+			if attempt > 0 {
+				attrs = append(attrs, slog.Int("attempt", attempt))
+			}
+			if traceID, spanID, ok := TraceIDs(c.R.Context()); ok {
+				attrs = append(attrs, slog.String("trace_id", traceID), slog.String("span_id", spanID))
+			}
+			level := cfg.Level.Level()
+			if cfg.SlowThreshold > 0 && dur > cfg.SlowThreshold {
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.Bool("slow", true))
+			}
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(c.R.Context(), level, "request", attrs...)
 		}
 	}
 }
@@ -141,17 +266,22 @@ func OpenLogFile(path string) (*os.File, error) {
 	return os.OpenFile(safePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
 }
 
-// Recover gracefully handles panics and returns 500
+// Recover gracefully handles panics and returns 500. The response carries the
+// request id (from Logger, or the X-Request-Id header) so clients can report
+// it; register Recover after Logger so the id is available. Browsers get the
+// Router.ErrorPages page instead when one is configured.
 func Recover(logger *slog.Logger) Middleware {
-	if logger == nil {
-		logger = slog.Default()
-	}
+	logger = loggerOrDefault(logger)
 	return func(next Handler) Handler {
 		return func(c *Context) {
 			defer func() {
 				if r := recover(); r != nil {
-					logger.Error("panic recovered", slog.Any("err", r), slog.String("stack", string(debug.Stack())))
-					c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+					id, ok := RequestID(c.R.Context())
+					if !ok {
+						id = c.R.Header.Get("X-Request-Id")
+					}
+					logger.Error("panic recovered", slog.String("id", logSanitizer.Replace(id)), slog.Any("err", r), slog.String("stack", string(debug.Stack()))) // #nosec G706 -- newlines stripped by logSanitizer
+					c.serverError(http.StatusInternalServerError, ErrorResponse{Error: "internal server error", RequestID: id})
 				}
 			}()
 			next(c)
@@ -161,14 +291,39 @@ func Recover(logger *slog.Logger) Middleware {
 
 // Timeout aborts long-running requests
 func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) { runWithTimeout(c, d, next) }
+	}
+}
+
+// TimeoutFromHeader applies a per-request deadline read from header (e.g.
+// "X-Request-Timeout: 2s", parsed with time.ParseDuration). Requested values
+// above max are capped to max; a missing, malformed, or non-positive value
+// falls back to def. A max of 0 means no cap.
+func TimeoutFromHeader(header string, max, def time.Duration) Middleware {
 	return func(next Handler) Handler {
 		return func(c *Context) {
-			if d > 0 {
-				ctx, cancel := context.WithTimeout(c.R.Context(), d)
-				defer cancel()
-				c.R = c.R.WithContext(ctx)
+			d := def
+			if v := c.R.Header.Get(header); v != "" {
+				if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+					d = parsed
+				}
 			}
-			next(c)
+			if max > 0 && d > max {
+				d = max
+			}
+			runWithTimeout(c, d, next)
 		}
 	}
 }
+
+// runWithTimeout calls next with a request context that expires after d.
+// A non-positive d leaves the context unchanged.
+func runWithTimeout(c *Context, d time.Duration, next Handler) {
+	if d > 0 {
+		ctx, cancel := context.WithTimeout(c.R.Context(), d)
+		defer cancel()
+		c.R = c.R.WithContext(ctx)
+	}
+	next(c)
+}
@@ -18,6 +18,8 @@ package quokka_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
@@ -29,6 +31,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
 
 	q "github.com/jrgalyan/quokka"
 )
@@ -64,6 +67,81 @@ var _ = Describe("Middleware", func() {
 		Expect(rr.Body.String()).To(ContainSubstring("internal server error"))
 	})
 
+	It("Recover serves the ErrorPages page to browsers and JSON to other clients", func() {
+		r := q.New()
+		r.ErrorPages = http.FS(memFS{"/500.html": "<h1>oops</h1>"})
+		r.Use(q.Recover(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		r.GET("/p", func(c *q.Context) { panic("boom") })
+
+		req := httptest.NewRequest(http.MethodGet, "/p", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rr.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+		Expect(rr.Body.String()).To(Equal("<h1>oops</h1>"))
+
+		req = httptest.NewRequest(http.MethodGet, "/p", nil)
+		req.Header.Set("Accept", "application/json")
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+		Expect(rr.Body.String()).To(ContainSubstring("internal server error"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/p", nil))
+		Expect(rr.Body.String()).To(ContainSubstring("internal server error"))
+	})
+
+	It("Recover falls back to 5xx.html", func() {
+		r := q.New()
+		r.ErrorPages = http.FS(memFS{"/5xx.html": "server error"})
+		r.Use(q.Recover(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		r.GET("/p", func(c *q.Context) { panic("boom") })
+		req := httptest.NewRequest(http.MethodGet, "/p", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rr.Body.String()).To(Equal("server error"))
+	})
+
+	It("Recover includes the request id in the 500 body", func() {
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: io.Discard}), q.Recover(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		r.GET("/p", func(c *q.Context) { panic("boom") })
+
+		req := httptest.NewRequest(http.MethodGet, "/p", nil)
+		req.Header.Set("X-Request-Id", "req-123")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rr.Body.String()).To(MatchJSON(`{"error":"internal server error","request_id":"req-123"}`))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/p", nil))
+		var body q.ErrorResponse
+		Expect(json.Unmarshal(rr.Body.Bytes(), &body)).To(Succeed())
+		Expect(body.RequestID).NotTo(BeEmpty())
+	})
+
+	It("Recover and Logger fall back to slog.Default for nil loggers", func() {
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		DeferCleanup(slog.SetDefault, prev)
+
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Logger: nil}), q.Recover(nil))
+		r.GET("/p", func(c *q.Context) { panic("boom") })
+		rr := httptest.NewRecorder()
+		Expect(func() { r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/p", nil)) }).NotTo(Panic())
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(buf.String()).To(ContainSubstring("panic recovered"))
+		Expect(buf.String()).To(ContainSubstring("msg=request"))
+	})
+
 	It("Timeout applies deadline to request context", func() {
 		r := q.New()
 		r.Use(q.Timeout(50 * time.Millisecond))
@@ -304,4 +382,283 @@ var _ = Describe("Middleware", func() {
 		_, err := os.Stat(filepath.Join(dir, "access.log"))
 		Expect(err).To(HaveOccurred()) // file not created
 	})
+
+	It("AppliedMiddleware reports named middleware in chain order", func() {
+		r := q.New()
+		noop := func(next q.Handler) q.Handler { return next }
+		r.Use(q.NamedMiddleware("recover", q.Recover(nil)), noop)
+		var applied []string
+		r.GET("/named", func(c *q.Context) {
+			applied = c.AppliedMiddleware()
+			c.Status(http.StatusOK)
+		}, q.NamedMiddleware("auth", noop), q.NamedMiddleware("timeout", q.Timeout(time.Second)))
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/named", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(applied).To(Equal([]string{"recover", "auth", "timeout"}))
+	})
+
+	It("AppliedMiddleware is empty when no named middleware ran", func() {
+		r := q.New()
+		var applied []string
+		r.GET("/plain", func(c *q.Context) {
+			applied = c.AppliedMiddleware()
+			c.Status(http.StatusOK)
+		})
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/plain", nil))
+		Expect(applied).To(BeEmpty())
+	})
+
+	Describe("TimeoutFromHeader", func() {
+		deadlineFor := func(header string) time.Duration {
+			r := q.New()
+			r.Use(q.TimeoutFromHeader("X-Request-Timeout", 5*time.Second, time.Second))
+			var remaining time.Duration
+			r.GET("/t", func(c *q.Context) {
+				if dl, ok := c.Context().Deadline(); ok {
+					remaining = time.Until(dl)
+				}
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/t", nil)
+			if header != "" {
+				req.Header.Set("X-Request-Timeout", header)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			return remaining
+		}
+
+		It("applies a valid header value", func() {
+			d := deadlineFor("2s")
+			Expect(d).To(BeNumerically("~", 2*time.Second, 200*time.Millisecond))
+		})
+
+		It("caps values above max", func() {
+			d := deadlineFor("1h")
+			Expect(d).To(BeNumerically("~", 5*time.Second, 200*time.Millisecond))
+		})
+
+		It("uses the default when the header is missing or malformed", func() {
+			Expect(deadlineFor("")).To(BeNumerically("~", time.Second, 200*time.Millisecond))
+			Expect(deadlineFor("soon")).To(BeNumerically("~", time.Second, 200*time.Millisecond))
+		})
+	})
+
+	It("Logger logs slow requests at WARN with slow=true", func() {
+		var buf bytes.Buffer
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: &buf, SlowThreshold: 10 * time.Millisecond}))
+		r.GET("/slow", func(c *q.Context) { time.Sleep(20 * time.Millisecond); c.Status(http.StatusOK) })
+		r.GET("/fast", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		Expect(buf.String()).To(ContainSubstring("level=WARN"))
+		Expect(buf.String()).To(ContainSubstring("slow=true"))
+
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+		Expect(buf.String()).To(ContainSubstring("level=INFO"))
+		Expect(buf.String()).NotTo(ContainSubstring("slow=true"))
+	})
+
+	It("Logger logs at the configured level and 5xx responses at ERROR", func() {
+		var buf bytes.Buffer
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: &buf, Level: slog.LevelDebug, SlowThreshold: 10 * time.Millisecond}))
+		r.GET("/ok", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.GET("/fail", func(c *q.Context) { c.Status(http.StatusInternalServerError) })
+		r.GET("/slowfail", func(c *q.Context) { time.Sleep(20 * time.Millisecond); c.Status(http.StatusBadGateway) })
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		Expect(buf.String()).To(ContainSubstring("level=DEBUG"))
+
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+		Expect(buf.String()).To(ContainSubstring("level=ERROR"))
+		Expect(buf.String()).To(ContainSubstring("status=500"))
+
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slowfail", nil))
+		Expect(buf.String()).To(ContainSubstring("level=ERROR"))
+		Expect(buf.String()).To(ContainSubstring("slow=true"))
+	})
+
+	It("Logger logs the retry attempt and exposes it to handlers", func() {
+		var buf bytes.Buffer
+		var seen int
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: &buf}))
+		r.GET("/x", func(c *q.Context) {
+			seen, _ = q.Attempt(c.Context())
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set("X-Request-Id", "req-1")
+		req.Header.Set("X-Attempt", "3")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(buf.String()).To(ContainSubstring("id=req-1"))
+		Expect(buf.String()).To(ContainSubstring("attempt=3"))
+		Expect(seen).To(Equal(3))
+
+		buf.Reset()
+		seen = 0
+		req = httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set("X-Attempt", "nope")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(buf.String()).NotTo(ContainSubstring("attempt="))
+		Expect(seen).To(BeZero())
+	})
+
+	It("Logger skips SkipPaths and falls back to slog.Default", func() {
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		DeferCleanup(slog.SetDefault, prev)
+
+		var id string
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{SkipPaths: []string{"/health"}}))
+		r.GET("/health", func(c *q.Context) { id, _ = q.RequestID(c.Context()); c.Status(http.StatusOK) })
+		r.GET("/x", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+		Expect(buf.String()).To(BeEmpty())
+		Expect(id).NotTo(BeEmpty())
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/deep", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+		Expect(buf.String()).To(ContainSubstring("path=/health/deep"))
+		Expect(buf.String()).To(ContainSubstring("path=/x"))
+	})
+
+	It("Logger reads a custom attempt header", func() {
+		var buf bytes.Buffer
+		r := q.New()
+		r.Use(q.Logger(q.LoggerConfig{Output: &buf, AttemptHeader: "Retry-Count"}))
+		r.GET("/x", func(c *q.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set("Retry-Count", "2")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(buf.String()).To(ContainSubstring("attempt=2"))
+	})
+
+	It("Logger logs trace and span ids from an active span when SpanContext is set", func() {
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		}))
+		serve := func(cfg q.LoggerConfig, ctx context.Context) string {
+			var buf bytes.Buffer
+			cfg.Output = &buf
+			r := q.New()
+			r.Use(q.Logger(cfg))
+			r.GET("/x", func(c *q.Context) { c.Status(http.StatusOK) })
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil).WithContext(ctx))
+			return buf.String()
+		}
+
+		out := serve(q.LoggerConfig{SpanContext: true}, ctx)
+		Expect(out).To(ContainSubstring("trace_id=4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(out).To(ContainSubstring("span_id=00f067aa0ba902b7"))
+
+		Expect(serve(q.LoggerConfig{SpanContext: true}, context.Background())).NotTo(ContainSubstring("trace_id="))
+		Expect(serve(q.LoggerConfig{}, ctx)).NotTo(ContainSubstring("trace_id="))
+	})
+
+	Describe("Abort", func() {
+		It("prevents later middleware and the final handler from running", func() {
+			var ran []string
+			r := q.New()
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) {
+					ran = append(ran, "outer")
+					next(c)
+					ran = append(ran, "outer-after")
+				}
+			})
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) {
+					c.AbortWithStatus(http.StatusUnauthorized)
+					next(c) // skipped: the context is aborted
+				}
+			})
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) { ran = append(ran, "inner"); next(c) }
+			})
+			r.GET("/x", func(c *q.Context) {
+				ran = append(ran, "handler")
+				c.Text(http.StatusOK, "secret")
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Body.String()).To(BeEmpty())
+			Expect(ran).To(Equal([]string{"outer", "outer-after"}))
+		})
+
+		It("skips route middleware and handlers after Abort or Fail", func() {
+			handled := false
+			guard := func(next q.Handler) q.Handler {
+				return func(c *q.Context) {
+					if c.Header("X-Token") == "" {
+						c.Fail(http.StatusForbidden, "no_token", "token required")
+					}
+					next(c)
+				}
+			}
+			r := q.New()
+			r.GET("/x", func(c *q.Context) { handled = true }, guard)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+			Expect(rr.Code).To(Equal(http.StatusForbidden))
+			Expect(handled).To(BeFalse())
+
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			req.Header.Set("X-Token", "t")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			Expect(handled).To(BeTrue())
+		})
+
+		It("Abort marks the context without writing a response", func() {
+			var aborted bool
+			r := q.New()
+			r.Use(func(next q.Handler) q.Handler {
+				return func(c *q.Context) { c.Abort(); next(c); aborted = c.IsAborted() }
+			})
+			r.GET("/x", func(c *q.Context) { c.Status(http.StatusTeapot) })
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+			Expect(aborted).To(BeTrue())
+			Expect(rr.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	It("When and Unless apply middleware conditionally", func() {
+		r := q.New()
+		tag := func(v string) q.Middleware {
+			return func(next q.Handler) q.Handler {
+				return func(c *q.Context) { c.SetHeader("X-Tag", v); next(c) }
+			}
+		}
+		isAdmin := func(c *q.Context) bool { return c.RoutePattern() == "/admin" }
+		r.Use(q.When(isAdmin, tag("admin")), q.Unless(isAdmin, tag("other")))
+		r.GET("/admin", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.GET("/home", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		Expect(rr.Header().Get("X-Tag")).To(Equal("admin"))
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/home", nil))
+		Expect(rr.Header().Get("X-Tag")).To(Equal("other"))
+	})
 })
@@ -0,0 +1,113 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentation names the tracer obtained from the provider.
+const otelInstrumentation = "github.com/jrgalyan/quokka"
+
+// OTelConfig configures the OTel middleware.
+type OTelConfig struct {
+	// TracerProvider creates the request spans. Default:
+	// otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+
+	// Propagator extracts the incoming trace context. Default: the global
+	// propagator, or W3C Trace Context (traceparent) when none is set.
+	Propagator propagation.TextMapPropagator
+}
+
+// OTel creates a middleware that starts a server span per request, continuing
+// any trace propagated in the request headers. The span is named after the
+// method and matched route pattern (e.g. "GET /users/:id") and carries
+// http.method, http.route and http.status_code attributes; 5xx responses and
+// panics mark it as an error. Panics are re-raised after recording, so
+// register OTel after Recover. The trace and span ids are stored with
+// WithTraceIDs for Logger, which must be registered before OTel:
+//
+//	r.Use(quokka.Logger(quokka.LoggerConfig{}), quokka.Recover(nil), quokka.OTel(quokka.OTelConfig{}))
+func OTel(cfg OTelConfig) Middleware {
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.Propagator == nil {
+		cfg.Propagator = otel.GetTextMapPropagator()
+		if len(cfg.Propagator.Fields()) == 0 {
+			cfg.Propagator = propagation.TraceContext{}
+		}
+	}
+	tracer := cfg.TracerProvider.Tracer(otelInstrumentation)
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			route := c.RoutePattern()
+			name := c.R.Method
+			if route != "" {
+				name += " " + route
+			}
+			ctx := cfg.Propagator.Extract(c.R.Context(), propagation.HeaderCarrier(c.R.Header))
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attribute.String("http.method", c.R.Method)),
+			)
+			defer span.End()
+			if route != "" {
+				span.SetAttributes(attribute.String("http.route", route))
+			}
+			c.R = c.R.WithContext(withSpanTraceIDs(ctx))
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					span.SetAttributes(attribute.Int("http.status_code", http.StatusInternalServerError))
+					span.RecordError(fmt.Errorf("panic: %v", rec), trace.WithStackTrace(true))
+					span.SetStatus(codes.Error, "panic")
+					panic(rec)
+				}
+			}()
+			next(c)
+
+			status := c.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+	}
+}
+
+// withSpanTraceIDs records the ids of the span active in ctx, if any, with
+// WithTraceIDs so they can be read back via TraceIDs.
+func withSpanTraceIDs(ctx context.Context) context.Context {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return WithTraceIDs(ctx, sc.TraceID().String(), sc.SpanID().String())
+	}
+	return ctx
+}
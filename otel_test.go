@@ -0,0 +1,132 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("OTel", func() {
+	var (
+		sr *tracetest.SpanRecorder
+		r  *q.Router
+	)
+
+	BeforeEach(func() {
+		sr = tracetest.NewSpanRecorder()
+		r = q.New()
+		r.Use(q.OTel(q.OTelConfig{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))}))
+	})
+
+	attrs := func(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range s.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	It("creates a server span named after the route pattern", func() {
+		r.GET("/users/:id", func(c *q.Context) { c.Text(http.StatusOK, c.Param("id")) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+
+		spans := sr.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name()).To(Equal("GET /users/:id"))
+		Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindServer))
+		a := attrs(spans[0])
+		Expect(a["http.route"].AsString()).To(Equal("/users/:id"))
+		Expect(a["http.method"].AsString()).To(Equal("GET"))
+		Expect(a["http.status_code"].AsInt64()).To(Equal(int64(200)))
+		Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+	})
+
+	It("continues an incoming traceparent", func() {
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusNoContent) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		span := sr.Ended()[0]
+		Expect(span.SpanContext().TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(span.Parent().SpanID().String()).To(Equal("00f067aa0ba902b7"))
+		Expect(span.Parent().IsRemote()).To(BeTrue())
+	})
+
+	It("marks 5xx responses as errors and names unmatched spans by method", func() {
+		r.GET("/boom", func(c *q.Context) { c.Status(http.StatusBadGateway) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+		spans := sr.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spans[1].Name()).To(Equal("GET"))
+		Expect(attrs(spans[1])).NotTo(HaveKey(attribute.Key("http.route")))
+		Expect(attrs(spans[1])["http.status_code"].AsInt64()).To(Equal(int64(404)))
+	})
+
+	It("records panics as span errors and re-raises them", func() {
+		rr := q.New()
+		rr.Use(q.Recover(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))),
+			q.OTel(q.OTelConfig{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))}))
+		rr.GET("/panic", func(c *q.Context) { panic("kaboom") })
+		rec := httptest.NewRecorder()
+		rr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+
+		span := sr.Ended()[0]
+		Expect(span.Status().Code).To(Equal(codes.Error))
+		Expect(span.Events()).NotTo(BeEmpty())
+		Expect(span.Events()[0].Name).To(Equal("exception"))
+		Expect(attrs(span)["http.status_code"].AsInt64()).To(Equal(int64(500)))
+	})
+
+	It("exposes trace ids to handlers and Logger", func() {
+		var buf bytes.Buffer
+		lr := q.New()
+		lr.Use(q.Logger(q.LoggerConfig{Output: &buf}),
+			q.OTel(q.OTelConfig{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))}))
+		var traceID, spanID string
+		lr.GET("/", func(c *q.Context) {
+			traceID, spanID, _ = q.TraceIDs(c.R.Context())
+			c.Status(http.StatusOK)
+		})
+		lr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		sc := sr.Ended()[0].SpanContext()
+		Expect(traceID).To(Equal(sc.TraceID().String()))
+		Expect(spanID).To(Equal(sc.SpanID().String()))
+		Expect(buf.String()).To(ContainSubstring("trace_id=" + traceID))
+		Expect(buf.String()).To(ContainSubstring("span_id=" + spanID))
+	})
+})
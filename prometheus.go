@@ -0,0 +1,169 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsConfig configures the Metrics middleware.
+type MetricsConfig struct {
+	// Registerer receives the collectors. Defaults to
+	// prometheus.DefaultRegisterer. Collectors already registered by an
+	// earlier Metrics call with the same config are reused.
+	Registerer prometheus.Registerer
+
+	// Namespace prefixes metric names, e.g. "shop" yields
+	// shop_http_requests_total.
+	Namespace string
+
+	// Buckets are the latency histogram buckets in seconds. Defaults to
+	// prometheus.DefBuckets.
+	Buckets []float64
+
+	// Labels names extra labels added to every metric. Each value is read
+	// from the route metadata key of the same name, e.g. with
+	// Labels: []string{"api_version"} a route registered with
+	// Meta(map[string]any{"api_version": "v2"}) records api_version="v2".
+	// Routes without the key record an empty value.
+	Labels []string
+}
+
+// MetaMetrics is the route metadata key that, set to false, excludes a route
+// from Metrics, e.g. for high-cardinality or sensitive endpoints.
+const MetaMetrics = "metrics"
+
+// Metrics creates a middleware that records Prometheus request metrics:
+//
+//   - http_requests_total: counter by method, route and status
+//   - http_requests_in_flight: gauge by method and route
+//   - http_request_duration_seconds: histogram by method, route and status
+//
+// The route label is the registered pattern (e.g. "/users/:id"), not the raw
+// path, so label cardinality stays bounded; it is empty for requests that
+// matched no route. Nonstandard methods are recorded as "OTHER". Routes with
+// metrics: false metadata (see MetaMetrics) are not recorded. Expose the
+// metrics with MetricsHandler.
+func Metrics(cfg MetricsConfig) Middleware {
+	if cfg.Registerer == nil {
+		cfg.Registerer = prometheus.DefaultRegisterer
+	}
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = prometheus.DefBuckets
+	}
+	requests := registerCollector(cfg.Registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: cfg.Namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests.",
+	}, append([]string{"method", "route", "status"}, cfg.Labels...)))
+	inFlight := registerCollector(cfg.Registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: cfg.Namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	}, append([]string{"method", "route"}, cfg.Labels...)))
+	duration := registerCollector(cfg.Registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: cfg.Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds.",
+		Buckets:   cfg.Buckets,
+	}, append([]string{"method", "route", "status"}, cfg.Labels...)))
+
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			if v, ok := c.RouteMeta(MetaMetrics); ok && v == false {
+				next(c)
+				return
+			}
+			method := metricsMethod(c.R.Method)
+			route := c.RoutePattern()
+			extra := metricsLabelValues(c, cfg.Labels)
+			gauge := inFlight.WithLabelValues(append([]string{method, route}, extra...)...)
+			gauge.Inc()
+			start := time.Now()
+			defer func() {
+				gauge.Dec()
+				status := c.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				code := strconv.Itoa(status)
+				values := append([]string{method, route, code}, extra...)
+				requests.WithLabelValues(values...).Inc()
+				duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			}()
+			next(c)
+		}
+	}
+}
+
+// MetricsHandler returns a handler serving the metrics in g in the
+// Prometheus exposition format. A nil g uses prometheus.DefaultGatherer.
+//
+//	r.GET("/metrics", quokka.MetricsHandler(nil))
+func MetricsHandler(g prometheus.Gatherer) Handler {
+	if g == nil {
+		g = prometheus.DefaultGatherer
+	}
+	h := promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	return func(c *Context) {
+		h.ServeHTTP(c.W, c.R)
+	}
+}
+
+// registerCollector registers c with reg, returning the already registered
+// collector instead when an identical one exists.
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// metricsLabelValues reads the values of the extra labels from the route
+// metadata.
+func metricsLabelValues(c *Context, labels []string) []string {
+	values := make([]string, len(labels))
+	for i, l := range labels {
+		if v, ok := c.RouteMeta(l); ok {
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
+
+// metricsMethod bounds the method label to the standard HTTP methods.
+func metricsMethod(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	}
+	return "OTHER"
+}
@@ -0,0 +1,158 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		reg *prometheus.Registry
+		r   *q.Router
+	)
+
+	BeforeEach(func() {
+		reg = prometheus.NewRegistry()
+		r = q.New()
+		r.Use(q.Metrics(q.MetricsConfig{Registerer: reg}))
+	})
+
+	do := func(method, path string) int {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr.Code
+	}
+
+	scrape := func() string {
+		scraper := q.New()
+		scraper.GET("/metrics", q.MetricsHandler(reg))
+		rr := httptest.NewRecorder()
+		scraper.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		return rr.Body.String()
+	}
+
+	It("counts requests by method, route pattern and status", func() {
+		r.GET("/users/:id", func(c *q.Context) { c.Text(http.StatusOK, c.Param("id")) })
+		r.POST("/users", func(c *q.Context) { c.Status(http.StatusCreated) })
+
+		Expect(do(http.MethodGet, "/users/1")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodGet, "/users/2")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodPost, "/users")).To(Equal(http.StatusCreated))
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`http_requests_total{method="GET",route="/users/:id",status="200"} 2`))
+		Expect(body).To(ContainSubstring(`http_requests_total{method="POST",route="/users",status="201"} 1`))
+		Expect(body).To(ContainSubstring(`http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`))
+		Expect(body).NotTo(ContainSubstring(`route="/users/1"`))
+	})
+
+	It("tracks in-flight requests", func() {
+		var during float64
+		r.GET("/slow", func(c *q.Context) {
+			during = testutil.ToFloat64(inFlight(reg, "GET", "/slow"))
+			c.Status(http.StatusOK)
+		})
+		do(http.MethodGet, "/slow")
+		Expect(during).To(Equal(1.0))
+		Expect(testutil.ToFloat64(inFlight(reg, "GET", "/slow"))).To(Equal(0.0))
+	})
+
+	It("bounds labels for unmatched routes and unknown methods", func() {
+		r.GET("/ok", func(c *q.Context) { c.Status(http.StatusOK) })
+		Expect(do(http.MethodGet, "/nope/123")).To(Equal(http.StatusNotFound))
+		Expect(do("BREW", "/ok")).To(Equal(http.StatusMethodNotAllowed))
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`http_requests_total{method="GET",route="",status="404"} 1`))
+		Expect(body).To(ContainSubstring(`http_requests_total{method="OTHER",route="",status="405"} 1`))
+	})
+
+	It("reuses collectors registered by an earlier call", func() {
+		other := q.New()
+		Expect(func() { other.Use(q.Metrics(q.MetricsConfig{Registerer: reg})) }).NotTo(Panic())
+		other.GET("/a", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.GET("/a", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		other.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+		do(http.MethodGet, "/a")
+		Expect(scrape()).To(ContainSubstring(`http_requests_total{method="GET",route="/a",status="200"} 2`))
+	})
+
+	It("skips routes with metrics: false metadata", func() {
+		r.GET("/internal", func(c *q.Context) { c.Status(http.StatusOK) }).Meta(map[string]any{q.MetaMetrics: false})
+		r.GET("/a", func(c *q.Context) { c.Status(http.StatusOK) })
+		do(http.MethodGet, "/internal")
+		do(http.MethodGet, "/a")
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`route="/a"`))
+		Expect(body).NotTo(ContainSubstring(`route="/internal"`))
+	})
+
+	It("adds custom labels from route metadata", func() {
+		reg := prometheus.NewRegistry()
+		r := q.New()
+		r.Use(q.Metrics(q.MetricsConfig{Registerer: reg, Labels: []string{"api_version"}}))
+		r.GET("/v2/items", func(c *q.Context) { c.Status(http.StatusOK) }).Meta(map[string]any{"api_version": "v2"})
+		r.GET("/legacy", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/items", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/legacy", nil))
+
+		Expect(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP http_requests_total Total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{api_version="",method="GET",route="/legacy",status="200"} 1
+http_requests_total{api_version="v2",method="GET",route="/v2/items",status="200"} 1
+`), "http_requests_total")).To(Succeed())
+	})
+
+	It("applies the namespace", func() {
+		reg := prometheus.NewRegistry()
+		r := q.New()
+		r.Use(q.Metrics(q.MetricsConfig{Registerer: reg, Namespace: "shop"}))
+		r.GET("/", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		n, err := testutil.GatherAndCount(reg, "shop_http_requests_total")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+	})
+})
+
+// inFlight returns the in-flight gauge child for method and route in reg.
+func inFlight(reg *prometheus.Registry, method, route string) prometheus.Gauge {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	}, []string{"method", "route"})
+	err := reg.Register(g)
+	var are prometheus.AlreadyRegisteredError
+	Expect(err).To(BeAssignableToTypeOf(are))
+	are = err.(prometheus.AlreadyRegisteredError)
+	return are.ExistingCollector.(*prometheus.GaugeVec).WithLabelValues(method, route)
+}
@@ -0,0 +1,77 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// ReverseProxy forwards the current request to target and relays the upstream
+// response to the client. The request body and headers are passed through and
+// X-Forwarded-Host/Proto are set. X-Forwarded-For gets the peer address
+// appended; the incoming chain is kept only when the peer is listed in
+// Router.TrustedProxies, so clients cannot forge hops. When the response
+// writer is wrapped by a compression middleware, at any depth, compression
+// is bypassed so the upstream's encoding is relayed as-is rather than
+// compressed a second time. Upstream failures produce a 502.
+func (c *Context) ReverseProxy(target *url.URL) {
+	if c.wrote {
+		return
+	}
+	if cw := findCompressWriter(c.W); cw != nil {
+		cw.bypass = true
+	}
+	trusted := c.trustedProxy()
+
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			if trusted {
+				pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			}
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			c.status = resp.StatusCode
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Error("reverse proxy error", slog.String("target", target.Redacted()), slog.Any("err", err))
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: "bad gateway"})
+		},
+	}
+	rp.ServeHTTP(c.W, c.R)
+	c.wrote = true
+}
+
+// findCompressWriter walks w's Unwrap chain to the compression writer, if any.
+func findCompressWriter(w http.ResponseWriter) *compressResponseWriter {
+	for w != nil {
+		if cw, ok := w.(*compressResponseWriter); ok {
+			return cw
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
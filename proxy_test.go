@@ -0,0 +1,163 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("ReverseProxy", func() {
+	It("relays the upstream response", func() {
+		var gotPath, gotHeader, gotXFF, gotBody string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			gotHeader = r.Header.Get("X-Custom")
+			gotXFF = r.Header.Get("X-Forwarded-For")
+			b, _ := io.ReadAll(r.Body)
+			gotBody = string(b)
+			w.Header().Set("X-Upstream", "yes")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("from upstream"))
+		}))
+		defer upstream.Close()
+		target, err := url.Parse(upstream.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		r := q.New()
+		r.TrustedProxies = []string{"192.0.2.0/24"}
+		r.POST("/api/*", func(c *q.Context) { c.ReverseProxy(target) })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/items", bytes.NewBufferString("payload"))
+		req.Header.Set("X-Custom", "abc")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusCreated))
+		Expect(rr.Header().Get("X-Upstream")).To(Equal("yes"))
+		Expect(rr.Body.String()).To(Equal("from upstream"))
+		Expect(gotPath).To(Equal("/api/items"))
+		Expect(gotHeader).To(Equal("abc"))
+		Expect(gotBody).To(Equal("payload"))
+		Expect(gotXFF).To(Equal("203.0.113.7, 192.0.2.1"))
+	})
+
+	It("drops a forged X-Forwarded-For from an untrusted peer", func() {
+		var gotXFF string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotXFF = r.Header.Get("X-Forwarded-For")
+		}))
+		defer upstream.Close()
+		target, _ := url.Parse(upstream.URL)
+
+		r := q.New()
+		r.GET("/api", func(c *q.Context) { c.ReverseProxy(target) })
+
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.RemoteAddr = "198.51.100.9:4321"
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(gotXFF).To(Equal("198.51.100.9"))
+	})
+
+	It("does not double-compress behind the Gzip middleware", func() {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, _ = gz.Write(bytes.Repeat([]byte("upstream data "), 100))
+		Expect(gz.Close()).To(Succeed())
+
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(compressed.Bytes())
+		}))
+		defer upstream.Close()
+		target, _ := url.Parse(upstream.URL)
+
+		r := q.New()
+		r.Use(q.Gzip(q.GzipConfig{}))
+		r.GET("/proxy", func(c *q.Context) { c.ReverseProxy(target) })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/proxy", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		zr, err := gzip.NewReader(rr.Body)
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(zr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(HavePrefix("upstream data "))
+	})
+
+	It("returns 502 when the upstream is unreachable", func() {
+		upstream := httptest.NewServer(http.NotFoundHandler())
+		target, _ := url.Parse(upstream.URL)
+		upstream.Close()
+
+		r := q.New()
+		r.GET("/down", func(c *q.Context) { c.ReverseProxy(target) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/down", nil))
+		Expect(rr.Code).To(Equal(http.StatusBadGateway))
+		Expect(rr.Body.String()).To(ContainSubstring("bad gateway"))
+	})
+
+	It("bypasses compression when other writers wrap the Gzip writer", func() {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write(bytes.Repeat([]byte("upstream data "), 100))
+		}))
+		defer upstream.Close()
+		target, _ := url.Parse(upstream.URL)
+
+		var skip string
+		r := q.New()
+		r.Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) {
+				next(c)
+				res, _ := c.Compression()
+				skip = res.SkipReason
+			}
+		})
+		r.Use(q.Gzip(q.GzipConfig{}), q.ETag(q.ETagConfig{}))
+		r.GET("/proxy", func(c *q.Context) { c.ReverseProxy(target) })
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/proxy", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		r.ServeHTTP(rr, req)
+
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rr.Body.String()).To(HavePrefix("upstream data "))
+		Expect(skip).To(Equal(q.SkipReasonProxied))
+	})
+})
@@ -0,0 +1,131 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the IP address of the client that originated the request.
+// When the direct peer is listed in Router.TrustedProxies, X-Forwarded-For is
+// walked from right to left, skipping trusted hops, and the first untrusted
+// address is returned; if every hop is trusted, the leftmost is returned.
+// Otherwise X-Forwarded-For is ignored, since any client can set it, and the
+// host part of RemoteAddr is returned.
+func (c *Context) ClientIP() string {
+	peer, ok := remoteAddr(c.R.RemoteAddr)
+	if !ok {
+		return c.R.RemoteAddr
+	}
+	if c.router == nil || !isTrusted(peer, c.router.trustedPrefixes()) {
+		return peer.String()
+	}
+	var hops []string
+	for _, v := range c.R.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !isTrusted(client, c.router.trustedPrefixes()) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// trustedProxy reports whether the direct peer of the request is listed in
+// the router's TrustedProxies, meaning its X-Forwarded-* headers may be
+// believed.
+func (c *Context) trustedProxy() bool {
+	if c.router == nil || len(c.router.TrustedProxies) == 0 {
+		return false
+	}
+	addr, ok := remoteAddr(c.R.RemoteAddr)
+	return ok && isTrusted(addr, c.router.trustedPrefixes())
+}
+
+// remoteAddr parses the IP from an "ip:port" or bare "ip" RemoteAddr.
+func remoteAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// isTrusted reports whether addr falls within any of the trusted prefixes.
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedPrefixes returns Router.TrustedProxies parsed into prefixes, parsing
+// them on the first call. It panics if an entry is invalid.
+func (r *Router) trustedPrefixes() []netip.Prefix {
+	r.trustedOnce.Do(func() {
+		r.trusted, r.trustedErr = parseTrustedProxies(r.TrustedProxies)
+	})
+	if r.trustedErr != nil {
+		panic(r.trustedErr)
+	}
+	return r.trusted
+}
+
+// parseTrustedProxies parses CIDRs and bare IPs, a bare IP becoming a
+// single-address prefix.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if p, err := netip.ParsePrefix(e); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("quokka: invalid TrustedProxies entry %q", e)
+		}
+		ip = ip.Unmap()
+		out = append(out, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return out, nil
+}
+
+// forwardedHeader returns the first comma-separated value of the named
+// X-Forwarded-* header when the peer is a trusted proxy, or "".
+func (c *Context) forwardedHeader(name string) string {
+	if !c.trustedProxy() {
+		return ""
+	}
+	v, _, _ := strings.Cut(c.R.Header.Get(name), ",")
+	return strings.TrimSpace(v)
+}
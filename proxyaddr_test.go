@@ -0,0 +1,77 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("ClientIP", func() {
+	clientIP := func(trusted []string, remote string, xff ...string) string {
+		var got string
+		r := q.New()
+		r.TrustedProxies = trusted
+		r.GET("/", func(c *q.Context) { got = c.ClientIP() })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		for _, v := range xff {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	It("returns RemoteAddr and ignores X-Forwarded-For from an untrusted peer", func() {
+		Expect(clientIP(nil, "203.0.113.5:4000", "1.1.1.1")).To(Equal("203.0.113.5"))
+		Expect(clientIP([]string{"10.0.0.0/8"}, "203.0.113.5:4000", "1.1.1.1")).To(Equal("203.0.113.5"))
+	})
+
+	It("uses the forwarded client behind a single trusted proxy", func() {
+		Expect(clientIP([]string{"10.0.0.1"}, "10.0.0.1:4000", "198.51.100.7")).To(Equal("198.51.100.7"))
+	})
+
+	It("skips trusted hops in a chain of two proxies", func() {
+		trusted := []string{"10.0.0.0/8", "172.16.0.0/12"}
+		Expect(clientIP(trusted, "10.0.0.1:4000", "6.6.6.6, 198.51.100.7, 172.16.0.9")).To(Equal("198.51.100.7"))
+		Expect(clientIP(trusted, "10.0.0.1:4000", "6.6.6.6", "198.51.100.7, 172.16.0.9")).To(Equal("198.51.100.7"))
+	})
+
+	It("returns the leftmost hop when every hop is trusted", func() {
+		Expect(clientIP([]string{"10.0.0.0/8"}, "10.0.0.1:4000", "10.2.2.2, 10.3.3.3")).To(Equal("10.2.2.2"))
+	})
+
+	It("returns the peer when X-Forwarded-For is absent or malformed", func() {
+		Expect(clientIP([]string{"10.0.0.0/8"}, "10.0.0.1:4000")).To(Equal("10.0.0.1"))
+		Expect(clientIP([]string{"10.0.0.0/8"}, "10.0.0.1:4000", "not-an-ip")).To(Equal("10.0.0.1"))
+	})
+
+	It("rejects an invalid TrustedProxies entry at Freeze", func() {
+		r := q.New()
+		r.TrustedProxies = []string{"10.0.0.0/8", "10.0.0.0/99"}
+		Expect(r.Freeze).To(PanicWith(MatchError(ContainSubstring("10.0.0.0/99"))))
+	})
+
+	It("handles IPv6 peers", func() {
+		Expect(clientIP([]string{"::1/128"}, "[::1]:4000", "2001:db8::1")).To(Equal("2001:db8::1"))
+	})
+})
@@ -17,11 +17,11 @@
 package quokka
 
 import (
+	"context"
+	"log/slog"
 	"math"
-	"net"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -36,17 +36,32 @@ type RateLimitConfig struct {
 	// Must be >= 1. Default: 20.
 	Burst int
 
-	// CleanupInterval is how often stale entries are removed from the map.
-	// Default: 1 minute.
+	// CleanupInterval is how often stale entries are removed from the
+	// default in-memory store. Ignored when Store is set. Default: 1 minute.
 	CleanupInterval time.Duration
 
-	// StaleAfter is the duration after which an idle client entry is removed.
+	// StaleAfter is the duration after which an idle client entry is removed
+	// from the default in-memory store. Ignored when Store is set.
 	// Default: 5 minutes.
 	StaleAfter time.Duration
 
 	// KeyFunc extracts a client key from the request. When nil, the default
-	// uses the first IP in X-Forwarded-For, falling back to RemoteAddr.
+	// uses Context.ClientIP, which honors X-Forwarded-For only from
+	// Router.TrustedProxies.
 	KeyFunc func(*Context) string
+
+	// Store holds the token buckets, e.g. in Redis to enforce one limit
+	// across several instances. Default: a MemoryRateLimitStore.
+	Store RateLimitStore
+}
+
+// RateLimitStore decides whether a request may proceed. Implementations must
+// be safe for concurrent use. Allow consumes one token from the bucket for
+// key, which refills at rate tokens per second up to burst. When denied,
+// retryAfter is the time until a token is available. If Allow returns an
+// error the request is let through and the error logged.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
 }
 
 type bucket struct {
@@ -56,91 +71,209 @@ type bucket struct {
 
 // RateLimit creates a middleware that enforces per-client rate limiting using a
 // token bucket algorithm. When the limit is exceeded a 429 Too Many Requests
-// response is returned with a Retry-After header.
+// response is returned with a Retry-After header. Responses carry
+// X-RateLimit-Limit (the burst), and with the default store
+// X-RateLimit-Remaining (whole tokens left) and X-RateLimit-Reset (the Unix
+// time at which a token is next available); with a custom Store the latter
+// two are only set on 429. Each call starts its own cleanup goroutine; to
+// apply limits to many routes, share a RateLimiter.
 func RateLimit(cfg RateLimitConfig) Middleware {
+	return NewRateLimiter(cfg).Middleware()
+}
+
+// RateLimiter holds token buckets for any number of routes or groups, with a
+// single store and cleanup goroutine. Construct it once and attach Middleware
+// or Limit where needed:
+//
+//	rl := quokka.NewRateLimiter(quokka.RateLimitConfig{Rate: 20, Burst: 40})
+//	api := r.Group("/api", rl.Middleware())
+//	r.POST("/login", login, rl.Limit(0.2, 5))
+//
+// Each attachment counts requests separately, so a client's traffic to
+// /login does not consume its /api budget. Routes in a group sharing one
+// attachment share a budget. Store keys are "<n>:<client key>", with n
+// numbering the attachments in creation order.
+type RateLimiter struct {
+	cfg    RateLimitConfig
+	store  RateLimitStore
+	memory *MemoryRateLimitStore // created by NewRateLimiter; nil with a custom Store
+	mu     sync.Mutex
+	scopes int
+}
+
+// NewRateLimiter returns a RateLimiter applying the defaults of cfg. Without
+// a Store it creates a MemoryRateLimitStore, whose cleanup goroutine Stop
+// ends.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
 	if cfg.Rate <= 0 {
 		cfg.Rate = 10
 	}
 	if cfg.Burst < 1 {
 		cfg.Burst = 20
 	}
-	if cfg.CleanupInterval <= 0 {
-		cfg.CleanupInterval = time.Minute
-	}
-	if cfg.StaleAfter <= 0 {
-		cfg.StaleAfter = 5 * time.Minute
-	}
 	if cfg.KeyFunc == nil {
 		cfg.KeyFunc = defaultKeyFunc
 	}
+	l := &RateLimiter{cfg: cfg, store: cfg.Store}
+	if l.store == nil {
+		l.memory = NewMemoryRateLimitStore(cfg.CleanupInterval, cfg.StaleAfter)
+		l.store = l.memory
+	}
+	return l
+}
+
+// Stop ends the cleanup goroutine of the store created by NewRateLimiter. The
+// middleware keeps working, but idle entries are no longer removed. A custom
+// Store is left alone.
+func (l *RateLimiter) Stop() {
+	if l.memory != nil {
+		l.memory.Stop()
+	}
+}
 
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*bucket)
-	)
+// Middleware returns a rate limiting middleware using the limiter's Rate
+// and Burst.
+func (l *RateLimiter) Middleware() Middleware {
+	return l.Limit(l.cfg.Rate, l.cfg.Burst)
+}
 
-	// Background goroutine to remove stale entries.
-	go func() {
-		ticker := time.NewTicker(cfg.CleanupInterval)
-		defer ticker.Stop()
-		for range ticker.C {
-			mu.Lock()
-			now := time.Now()
-			for k, b := range clients {
-				if now.Sub(b.lastSeen) > cfg.StaleAfter {
-					delete(clients, k)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
+// Limit returns a rate limiting middleware allowing rate requests per second
+// with bursts of burst. A non-positive rate or burst uses the limiter's value.
+func (l *RateLimiter) Limit(rate float64, burst int) Middleware {
+	if rate <= 0 {
+		rate = l.cfg.Rate
+	}
+	if burst < 1 {
+		burst = l.cfg.Burst
+	}
+	l.mu.Lock()
+	l.scopes++
+	prefix := strconv.Itoa(l.scopes) + ":"
+	l.mu.Unlock()
 
 	return func(next Handler) Handler {
 		return func(c *Context) {
-			key := cfg.KeyFunc(c)
+			key := prefix + l.cfg.KeyFunc(c)
 			now := time.Now()
 
-			mu.Lock()
-			b, ok := clients[key]
-			if !ok {
-				b = &bucket{tokens: float64(cfg.Burst), lastSeen: now}
-				clients[key] = b
+			var (
+				allowed   bool
+				remaining = -1
+				wait      time.Duration
+			)
+			if l.memory != nil {
+				allowed, remaining, wait = l.memory.take(key, rate, burst, now)
+			} else {
+				var err error
+				allowed, wait, err = l.store.Allow(c.R.Context(), key, rate, burst)
+				if err != nil {
+					slog.Error("rate limit store failed", slog.Any("err", err))
+					allowed = true
+				}
 			}
 
-			// Refill tokens based on elapsed time.
-			elapsed := now.Sub(b.lastSeen).Seconds()
-			b.tokens += elapsed * cfg.Rate
-			if b.tokens > float64(cfg.Burst) {
-				b.tokens = float64(cfg.Burst)
-			}
-			b.lastSeen = now
-
-			if b.tokens < 1 {
-				retryAfter := int(math.Ceil((1 - b.tokens) / cfg.Rate))
-				mu.Unlock()
-				c.SetHeader("Retry-After", strconv.Itoa(retryAfter))
-				c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
-				return
+			c.SetHeader("X-RateLimit-Limit", strconv.Itoa(burst))
+			if remaining >= 0 || !allowed {
+				secs := int64(math.Ceil(wait.Seconds()))
+				c.SetHeader("X-RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+				c.SetHeader("X-RateLimit-Reset", strconv.FormatInt(now.Unix()+secs, 10))
+				if !allowed {
+					c.SetHeader("Retry-After", strconv.FormatInt(secs, 10))
+					c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
+					return
+				}
 			}
-
-			b.tokens--
-			mu.Unlock()
 			next(c)
 		}
 	}
 }
 
-func defaultKeyFunc(c *Context) string {
-	if xff := c.R.Header.Get("X-Forwarded-For"); xff != "" {
-		// Use the first (client) IP from the chain.
-		if i := strings.IndexByte(xff, ','); i > 0 {
-			return strings.TrimSpace(xff[:i])
+func defaultKeyFunc(c *Context) string { return c.ClientIP() }
+
+// MemoryRateLimitStore is an in-process RateLimitStore. Idle buckets are
+// removed by a background goroutine until Stop is called.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryRateLimitStore creates a MemoryRateLimitStore that removes buckets
+// idle for staleAfter (default 5 minutes) every cleanupInterval (default 1
+// minute).
+func NewMemoryRateLimitStore(cleanupInterval, staleAfter time.Duration) *MemoryRateLimitStore {
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Minute
+	}
+	if staleAfter <= 0 {
+		staleAfter = 5 * time.Minute
+	}
+	s := &MemoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+		stop:    make(chan struct{}),
+	}
+	go s.cleanup(cleanupInterval, staleAfter)
+	return s
+}
+
+// cleanup periodically removes stale buckets until Stop is called.
+func (s *MemoryRateLimitStore) cleanup(interval, staleAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			now := time.Now()
+			for k, b := range s.buckets {
+				if now.Sub(b.lastSeen) > staleAfter {
+					delete(s.buckets, k)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
 		}
-		return strings.TrimSpace(xff)
 	}
-	host, _, err := net.SplitHostPort(c.R.RemoteAddr)
-	if err != nil {
-		return c.R.RemoteAddr
+}
+
+// Stop ends the cleanup goroutine.
+func (s *MemoryRateLimitStore) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Allow implements RateLimitStore.
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	allowed, _, wait := s.take(key, rate, burst, time.Now())
+	return allowed, wait, nil
+}
+
+// take consumes a token for key if one is available, returning whether it
+// was, the whole tokens left, and the time until a token is available.
+func (s *MemoryRateLimitStore) take(key string, rate float64, burst int, now time.Time) (allowed bool, remaining int, wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	// Refill tokens based on elapsed time.
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens += elapsed * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.lastSeen = now
+
+	allowed = b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / rate * float64(time.Second))
 	}
-	return host
+	return allowed, int(b.tokens), wait
 }
@@ -17,10 +17,14 @@
 package quokka_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -81,6 +85,29 @@ var _ = Describe("RateLimit", func() {
 		Expect(seconds).To(BeNumerically(">=", 1))
 	})
 
+	It("sets X-RateLimit headers that decrement across requests", func() {
+		r := q.New()
+		r.Use(q.RateLimit(q.RateLimitConfig{Rate: 0.5, Burst: 3}))
+		r.GET("/", handler)
+
+		for _, want := range []string{"2", "1", "0"} {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Header().Get("X-RateLimit-Limit")).To(Equal("3"))
+			Expect(rr.Header().Get("X-RateLimit-Remaining")).To(Equal(want))
+		}
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rr.Header().Get("X-RateLimit-Remaining")).To(Equal("0"))
+		reset, err := strconv.ParseInt(rr.Header().Get("X-RateLimit-Reset"), 10, 64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reset).To(BeNumerically(">", time.Now().Unix()))
+		Expect(reset).To(BeNumerically("<=", time.Now().Unix()+2))
+	})
+
 	It("returns JSON error body on 429", func() {
 		r := q.New()
 		r.Use(q.RateLimit(q.RateLimitConfig{Rate: 1, Burst: 1}))
@@ -145,8 +172,9 @@ var _ = Describe("RateLimit", func() {
 		Expect(rr.Code).To(Equal(http.StatusOK))
 	})
 
-	It("uses X-Forwarded-For for client identification", func() {
+	It("uses X-Forwarded-For from a trusted proxy for client identification", func() {
 		r := q.New()
+		r.TrustedProxies = []string{"192.0.2.0/24"}
 		r.Use(q.RateLimit(q.RateLimitConfig{Rate: 1, Burst: 1}))
 		r.GET("/", handler)
 
@@ -163,6 +191,32 @@ var _ = Describe("RateLimit", func() {
 		req.Header.Set("X-Forwarded-For", "10.0.0.1, 172.16.0.1")
 		r.ServeHTTP(rr, req)
 		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+
+		// A different client behind the same proxy has its own bucket
+		rr = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0.2")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("ignores X-Forwarded-For from an untrusted peer", func() {
+		r := q.New()
+		r.Use(q.RateLimit(q.RateLimitConfig{Rate: 1, Burst: 1}))
+		r.GET("/", handler)
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+
+		// Spoofing a new XFF does not earn a fresh bucket
+		rr = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0.2")
+		r.ServeHTTP(rr, req)
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
 	})
 
 	It("supports custom KeyFunc", func() {
@@ -197,4 +251,96 @@ var _ = Describe("RateLimit", func() {
 		r.ServeHTTP(rr, req)
 		Expect(rr.Code).To(Equal(http.StatusOK))
 	})
+
+	It("applies different limits per attachment of one shared RateLimiter", func() {
+		rl := q.NewRateLimiter(q.RateLimitConfig{Rate: 0.001, Burst: 3})
+		DeferCleanup(rl.Stop)
+		r := q.New()
+		api := r.Group("/api", rl.Middleware())
+		api.GET("/a", handler)
+		api.GET("/b", handler)
+		r.POST("/login", handler, rl.Limit(0.001, 1))
+
+		do := func(method, path string) int {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+			return rr.Code
+		}
+
+		Expect(do(http.MethodPost, "/login")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodPost, "/login")).To(Equal(http.StatusTooManyRequests))
+
+		// The group's budget is separate from /login and shared by its routes.
+		Expect(do(http.MethodGet, "/api/a")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodGet, "/api/b")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodGet, "/api/a")).To(Equal(http.StatusOK))
+		Expect(do(http.MethodGet, "/api/b")).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("delegates to a custom Store", func() {
+		store := &denyAfterStore{n: 2}
+		r := q.New()
+		r.Use(q.RateLimit(q.RateLimitConfig{Rate: 5, Burst: 7, Store: store}))
+		r.GET("/", handler)
+
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Header().Get("X-RateLimit-Limit")).To(Equal("7"))
+			Expect(rr.Header().Get("X-RateLimit-Remaining")).To(BeEmpty())
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(rr.Header().Get("Retry-After")).To(Equal("3"))
+		Expect(rr.Header().Get("X-RateLimit-Remaining")).To(Equal("0"))
+		Expect(store.keys).To(HaveLen(3))
+		Expect(store.keys[0]).To(Equal("1:192.0.2.1@5/7"))
+	})
+
+	It("lets requests through when the Store fails", func() {
+		store := &denyAfterStore{err: errors.New("redis down")}
+		r := q.New()
+		r.Use(q.RateLimit(q.RateLimitConfig{Store: store}))
+		r.GET("/", handler)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(store.calls).To(Equal(1))
+	})
+
+	It("keeps limiting after Stop", func() {
+		rl := q.NewRateLimiter(q.RateLimitConfig{Rate: 0.001, Burst: 1})
+		rl.Stop()
+		rl.Stop()
+		r := q.New()
+		r.GET("/", handler, rl.Middleware())
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rr.Code).To(Equal(http.StatusTooManyRequests))
+	})
 })
+
+// denyAfterStore is a fake RateLimitStore that allows n calls, then denies.
+type denyAfterStore struct {
+	mu    sync.Mutex
+	n     int
+	calls int
+	keys  []string
+	err   error
+}
+
+func (s *denyAfterStore) Allow(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.keys = append(s.keys, fmt.Sprintf("%s@%g/%d", key, rate, burst))
+	if s.err != nil {
+		return false, 0, s.err
+	}
+	return s.calls <= s.n, 3 * time.Second, nil
+}
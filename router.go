@@ -18,8 +18,14 @@ package quokka
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -38,8 +44,33 @@ type Router struct {
 	mw          []Middleware
 	notFound    Handler
 	methodNA    Handler
-	MaxBodySize int64  // max request body bytes for BindJSON; 0 means 10MB default
-	UploadDir   string // base directory for SaveFile; required for path confinement
+	codecs      map[string]Codec  // media type -> codec for Bind and Render
+	frozen      bool              // set by Freeze; further registration panics
+	groups      []*Group          // consulted for per-group trailing-slash redirects
+	names       map[string]string // route name -> pattern, set by Route.Name
+	trustedOnce sync.Once         // guards trusted and trustedErr
+	trusted     []netip.Prefix    // TrustedProxies, parsed by trustedPrefixes
+	trustedErr  error             // first invalid TrustedProxies entry
+	MaxBodySize int64             // max request body bytes for BindJSON; 0 means 10MB default
+	UploadDir   string            // base directory for SaveFile; required for path confinement
+
+	// MaxMultipartMemory is the number of bytes of a multipart body kept in
+	// memory by FormFile, FormFiles, and MultipartForm; file parts beyond it
+	// are stored in temporary files. 0 means the 32 MB default. Bound the
+	// total body size with the BodyLimit middleware.
+	MaxMultipartMemory int64
+
+	// MaxPartSize bounds each part of a multipart body parsed by FormFile and
+	// FormFiles. A part exceeding it is rejected with 413 as soon as the limit
+	// is crossed, before it is buffered. 0 disables the per-part check.
+	MaxPartSize int64
+
+	// MaxQueryParams bounds the number of query string parameters accepted
+	// per request; requests exceeding it are rejected with 400 before the
+	// query is parsed. Parameters are counted as url.ParseQuery stores them,
+	// so empty pairs and pairs containing a semicolon are ignored. 0 or a
+	// negative value disables the check.
+	MaxQueryParams int
 
 	// RedirectTrailingSlash, when true, causes the router to issue a 301
 	// redirect when a request path has a trailing slash but the registered
@@ -47,25 +78,99 @@ type Router struct {
 	// preserved across the redirect.
 	RedirectTrailingSlash bool
 
+	// HandleOPTIONS, when true, answers OPTIONS requests for paths without an
+	// explicit OPTIONS handler with 204 and an Allow header listing the
+	// methods registered on the path (plus HEAD when GET is registered, and
+	// OPTIONS itself). Router-level middleware such as CORS still runs.
+	HandleOPTIONS bool
+
+	// ErrorPages, when set, supplies static pages for 5xx responses written
+	// by Recover to clients preferring text/html: "/<status>.html" (e.g.
+	// "/500.html"), falling back to "/5xx.html". Other clients, and statuses
+	// without a page, get the JSON ErrorResponse.
+	ErrorPages http.FileSystem
+
+	// StrictNegotiation, when true, makes Context.Negotiate answer 406 Not
+	// Acceptable when neither the Accept nor the Accept-Charset header allows
+	// any representation it can produce, instead of falling back to JSON.
+	StrictNegotiation bool
+
+	// IfMatchRequired, when true, makes Context.RequireIfMatch answer 428
+	// Precondition Required to PUT, PATCH and DELETE requests that carry no
+	// If-Match header. By default such requests are allowed through.
+	IfMatchRequired bool
+
+	// StrictSlash, when true, treats /users and /users/ as distinct: a
+	// request only matches if its trailing-slash form was registered, and the
+	// other form gets 404. By default trailing slashes are ignored when
+	// matching. RedirectTrailingSlash, when also set, still redirects first.
+	StrictSlash bool
+
+	// UseRawPath, when true, routes on the request's escaped path so that an
+	// encoded slash (%2F) inside a segment does not split it. Matched
+	// parameter values are unescaped, so c.Param("name") for /files/a%2Fb
+	// registered as /files/:name returns "a/b".
+	UseRawPath bool
+
+	// Debug enables development-mode checks, such as warning when a response
+	// Content-Type does not match the sniffed body type. Keep it off in
+	// production.
+	Debug bool
+
+	// HideMethodNotAllowed, when true, answers a request whose path exists
+	// but whose method has no handler with 404 instead of 405, so clients
+	// cannot probe which paths exist. The default 405 is the correct
+	// response per RFC 9110.
+	HideMethodNotAllowed bool
+
+	// TrustedProxies lists the CIDRs (or bare IPs) of reverse proxies whose
+	// X-Forwarded-* headers are believed. Requests from any other peer have
+	// those headers ignored. The list is parsed once, by Freeze or on first
+	// use, so later changes have no effect. An invalid entry panics there;
+	// call Freeze at startup to catch it before serving.
+	TrustedProxies []string
+
+	// StatusMessages overrides the Error text, keyed by status code, written
+	// by the default 404 and 405 handlers and by Context.Fail and
+	// Context.UnprocessableEntity. Codes without an entry keep the default:
+	// the lowercase status text, e.g. "not found".
+	StatusMessages map[int]string
+
 	// ErrorHandler, when set, is called instead of the default notFound and
 	// methodNA handlers. It receives the Context, the HTTP status code
 	// (404 or 405), and a sentinel error (ErrNotFound or ErrMethodNotAllowed).
+	// It also renders errors returned by JSONHandlers, receiving the status
+	// from an *HTTPError or 500 otherwise.
 	ErrorHandler func(*Context, int, error)
 }
 
 type node struct {
+	pattern  string // registered route path, e.g. /users/:id
 	segment  string
 	param    bool
+	name     string         // param name without the leading colon or constraint
+	re       *regexp.Regexp // constraint from :name(regexp); nil for plain params
 	wildcard bool
 	children []*node
-	handlers map[string]Handler // method -> handler
+	handlers map[string]Handler        // method -> handler
+	meta     map[string]map[string]any // method -> route metadata from Route.Meta
+	allow    [2]string                 // Allow headers without and with auto OPTIONS, precomputed by Freeze
+
+	// plainPath and slashPath record whether the route was registered
+	// without or with a trailing slash; consulted when StrictSlash is set.
+	plainPath bool
+	slashPath bool
 }
 
 // New creates a new Router.
 func New() *Router {
-	r := &Router{root: &node{handlers: make(map[string]Handler)}}
-	r.notFound = func(c *Context) { c.JSON(http.StatusNotFound, ErrorResponse{Error: "not found"}) }
-	r.methodNA = func(c *Context) { c.JSON(http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"}) }
+	r := &Router{root: &node{pattern: "/", handlers: make(map[string]Handler)}, codecs: defaultCodecs()}
+	r.notFound = func(c *Context) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: c.statusMessage(http.StatusNotFound)})
+	}
+	r.methodNA = func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, ErrorResponse{Error: c.statusMessage(http.StatusMethodNotAllowed)})
+	}
 	return r
 }
 
@@ -90,66 +195,243 @@ func (r *Router) MethodNotAllowed(h Handler) {
 	r.methodNA = h
 }
 
-// Handle registers a route handler for method and path.
-func (r *Router) Handle(method, p string, h Handler, mw ...Middleware) {
-	r.handleWithPrefix("", method, p, h, mw...)
+// Handle registers a route handler for method and path. The returned Route
+// can be named for URL generation.
+func (r *Router) Handle(method, p string, h Handler, mw ...Middleware) *Route {
+	return r.handleWithPrefix("", method, p, h, nil, mw...)
 }
 
-func (r *Router) handleWithPrefix(prefix, method, p string, h Handler, mw ...Middleware) {
+// joinPrefix joins a group prefix and a route path, keeping the path's
+// trailing slash.
+func joinPrefix(prefix, p string) string {
+	if prefix == "" {
+		return p
+	}
+	trailing := len(p) > 1 && strings.HasSuffix(p, "/")
+	p = path.Join("/", prefix, p)
+	if trailing {
+		p += "/"
+	}
+	return p
+}
+
+// handleWithPrefix registers h under prefix+p, replacing any handler and
+// metadata previously registered for method on that path.
+func (r *Router) handleWithPrefix(prefix, method, p string, h Handler, meta map[string]any, mw ...Middleware) *Route {
 	if h == nil {
 		panic("quokka: nil handler")
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.frozen {
+		panic("quokka: route registered after Freeze: " + method + " " + p)
+	}
 	if p == "" || p[0] != '/' {
 		panic("path must start with /")
 	}
-	if prefix != "" {
-		p = path.Join("/", prefix, p)
-	}
+	trailing := len(p) > 1 && strings.HasSuffix(p, "/")
+	p = joinPrefix(prefix, p)
 	parts := splitPath(p)
 	n := r.root
-	for _, seg := range parts {
+	for i, seg := range parts {
 		child := matchChild(n, seg)
 		if child == nil {
-			child = &node{segment: seg, param: strings.HasPrefix(seg, ":"), wildcard: seg == "*", handlers: make(map[string]Handler)}
+			child = &node{
+				pattern:  "/" + strings.Join(parts[:i+1], "/"),
+				segment:  seg,
+				param:    strings.HasPrefix(seg, ":"),
+				wildcard: seg == "*",
+				handlers: make(map[string]Handler),
+			}
+			if child.param {
+				var expr string
+				child.name, expr = parseParam(seg)
+				if expr != "" {
+					re, err := regexp.Compile("^(?:" + expr + ")$")
+					if err != nil {
+						panic("quokka: invalid constraint in " + seg + ": " + err.Error())
+					}
+					child.re = re
+				}
+			}
 			n.children = append(n.children, child)
 		}
 		n = child
 	}
+	if trailing {
+		n.slashPath = true
+	} else {
+		n.plainPath = true
+	}
+	method = strings.ToUpper(method)
 	h = chain(mw, h)
-	n.handlers[strings.ToUpper(method)] = h
+	n.handlers[method] = h
+	delete(n.meta, method)
+	n.setMeta(method, meta)
+	return &Route{r: r, n: n, Method: method, Pattern: p}
+}
+
+// Freeze finalizes the route tree once all routes are registered. It orders
+// each node's children static-first, then params, then wildcards, so lookups
+// scan candidates in priority order, and precomputes each node's Allow
+// header for 405 and OPTIONS responses. It also parses TrustedProxies,
+// panicking on an invalid entry.
+// Registering a route after Freeze panics, which catches accidental late
+// registration.
+func (r *Router) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+	r.root.freeze()
+	r.trustedPrefixes()
+}
+
+func (n *node) freeze() {
+	sort.SliceStable(n.children, func(i, j int) bool { return n.children[i].rank() < n.children[j].rank() })
+	if len(n.handlers) > 0 {
+		n.allow = [2]string{n.buildAllow(false), n.buildAllow(true)}
+	}
+	for _, ch := range n.children {
+		ch.freeze()
+	}
+}
+
+// RouteInfo describes a registered route, as returned by Router.Routes.
+type RouteInfo struct {
+	Method  string // upper-case HTTP method
+	Pattern string // full registered path, e.g. /api/users/:id
+
+	// AutoHEAD is set for HEAD entries served by the route's GET handler
+	// because no explicit HEAD handler was registered.
+	AutoHEAD bool
+}
+
+// Routes lists every registered route, including group prefixes and the
+// implicit HEAD for GET-only routes, sorted by pattern and then method.
+func (r *Router) Routes() []RouteInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []RouteInfo
+	var walk func(n *node)
+	walk = func(n *node) {
+		for m := range n.handlers {
+			out = append(out, RouteInfo{Method: m, Pattern: n.pattern})
+		}
+		if _, get := n.handlers[http.MethodGet]; get {
+			if _, head := n.handlers[http.MethodHead]; !head {
+				out = append(out, RouteInfo{Method: http.MethodHead, Pattern: n.pattern, AutoHEAD: true})
+			}
+		}
+		for _, ch := range n.children {
+			walk(ch)
+		}
+	}
+	walk(r.root)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Pattern != out[j].Pattern {
+			return out[i].Pattern < out[j].Pattern
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+// rank orders node kinds for matching: static, param, constrained param,
+// wildcard.
+func (n *node) rank() int {
+	switch {
+	case n.wildcard:
+		return 3
+	case n.re != nil:
+		return 2
+	case n.param:
+		return 1
+	}
+	return 0
+}
+
+// parseParam splits a param segment such as ":id" or ":id(\d+)" into its name
+// and optional regexp constraint.
+func parseParam(seg string) (name, expr string) {
+	name = seg[1:]
+	if i := strings.IndexByte(name, '('); i >= 0 && strings.HasSuffix(name, ")") {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// JSONHandler is a handler that returns its response instead of writing it.
+// See HandleJSON.
+type JSONHandler func(*Context) (int, any, error)
+
+// HandleJSON adapts h to a Handler. A nil error writes the returned value as
+// JSON with the returned status. A non-nil error is passed to the router's
+// ErrorHandler when set; otherwise an *HTTPError is written as an
+// ErrorResponse with its status and message, and any other error is logged
+// and answered with 500.
+func HandleJSON(h JSONHandler) Handler {
+	return func(c *Context) {
+		code, v, err := h(c)
+		if err == nil {
+			c.JSON(code, v)
+			return
+		}
+		status := http.StatusInternalServerError
+		var he *HTTPError
+		if errors.As(err, &he) {
+			status = he.Status
+		}
+		if c.router != nil && c.router.ErrorHandler != nil {
+			c.router.ErrorHandler(c, status, err)
+			return
+		}
+		if he != nil {
+			c.JSON(status, ErrorResponse{Error: he.Message})
+			return
+		}
+		slog.Error("handler error", slog.String("path", logSanitizer.Replace(c.R.URL.Path)), slog.String("err", logSanitizer.Replace(err.Error()))) // #nosec G706 -- newlines stripped by logSanitizer
+		c.JSON(status, ErrorResponse{Error: "internal server error"})
+	}
+}
+
+// GETJSON registers a JSONHandler for GET requests to the given path.
+func (r *Router) GETJSON(p string, h JSONHandler, mw ...Middleware) *Route {
+	return r.GET(p, HandleJSON(h), mw...)
 }
 
 // GET registers a handler for GET requests to the given path.
-func (r *Router) GET(p string, h Handler, mw ...Middleware) { r.Handle(http.MethodGet, p, h, mw...) }
+func (r *Router) GET(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodGet, p, h, mw...)
+}
 
 // POST registers a handler for POST requests to the given path.
-func (r *Router) POST(p string, h Handler, mw ...Middleware) {
-	r.Handle(http.MethodPost, p, h, mw...)
+func (r *Router) POST(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodPost, p, h, mw...)
 }
 
 // PUT registers a handler for PUT requests to the given path.
-func (r *Router) PUT(p string, h Handler, mw ...Middleware) { r.Handle(http.MethodPut, p, h, mw...) }
+func (r *Router) PUT(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodPut, p, h, mw...)
+}
 
 // DELETE registers a handler for DELETE requests to the given path.
-func (r *Router) DELETE(p string, h Handler, mw ...Middleware) {
-	r.Handle(http.MethodDelete, p, h, mw...)
+func (r *Router) DELETE(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodDelete, p, h, mw...)
 }
 
 // PATCH registers a handler for PATCH requests to the given path.
-func (r *Router) PATCH(p string, h Handler, mw ...Middleware) {
-	r.Handle(http.MethodPatch, p, h, mw...)
+func (r *Router) PATCH(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodPatch, p, h, mw...)
 }
 
 // OPTIONS registers a handler for OPTIONS requests to the given path.
-func (r *Router) OPTIONS(p string, h Handler, mw ...Middleware) {
-	r.Handle(http.MethodOptions, p, h, mw...)
+func (r *Router) OPTIONS(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodOptions, p, h, mw...)
 }
 
 // HEAD registers a handler for HEAD requests to the given path.
-func (r *Router) HEAD(p string, h Handler, mw ...Middleware) {
-	r.Handle(http.MethodHead, p, h, mw...)
+func (r *Router) HEAD(p string, h Handler, mw ...Middleware) *Route {
+	return r.Handle(http.MethodHead, p, h, mw...)
 }
 
 // Group represents a route group with a common prefix and middleware.
@@ -157,54 +439,166 @@ type Group struct {
 	r      *Router
 	prefix string
 	mw     []Middleware
+	meta   map[string]any
+
+	// RedirectTrailingSlash, when non-nil, overrides
+	// Router.RedirectTrailingSlash for paths under the group's prefix. When
+	// nil the group inherits the router setting, including later changes.
+	// When overriding groups are nested by prefix, the longest matching
+	// prefix decides.
+	RedirectTrailingSlash *bool
 }
 
 // Group creates a new route group.
 func (r *Router) Group(prefix string, mw ...Middleware) *Group {
-	return &Group{r: r, prefix: strings.Trim(prefix, "/"), mw: mw}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g := &Group{r: r, prefix: strings.Trim(prefix, "/"), mw: mw}
+	r.groups = append(r.groups, g)
+	return g
+}
+
+// redirectTrailingSlash reports whether a trailing-slash request for the
+// trimmed path p should be redirected, consulting the overriding group with
+// the longest prefix that contains p and falling back to the router setting.
+// Callers must hold r.mu for reading.
+func (r *Router) redirectTrailingSlash(p string) bool {
+	redirect := r.RedirectTrailingSlash
+	best := -1
+	for _, g := range r.groups {
+		if g.RedirectTrailingSlash == nil {
+			continue
+		}
+		pfx := "/" + g.prefix
+		if g.prefix != "" && p != pfx && !strings.HasPrefix(p, pfx+"/") {
+			continue
+		}
+		if len(g.prefix) > best {
+			best = len(g.prefix)
+			redirect = *g.RedirectTrailingSlash
+		}
+	}
+	return redirect
 }
 
 // Use adds middleware to group.
 func (g *Group) Use(mw ...Middleware) { g.mw = append(g.mw, mw...) }
 
 // Handle registers a handler within the group.
-func (g *Group) Handle(method, p string, h Handler, mw ...Middleware) {
+func (g *Group) Handle(method, p string, h Handler, mw ...Middleware) *Route {
 	fullMW := append([]Middleware{}, g.mw...)
 	fullMW = append(fullMW, mw...)
-	g.r.handleWithPrefix(g.prefix, method, p, h, fullMW...)
+	return g.r.handleWithPrefix(g.prefix, method, p, h, g.meta, fullMW...)
+}
+
+// GETJSON registers a JSONHandler for GET requests within the group.
+func (g *Group) GETJSON(p string, h JSONHandler, mw ...Middleware) *Route {
+	return g.GET(p, HandleJSON(h), mw...)
 }
 
 // GET registers a handler for GET requests within the group.
-func (g *Group) GET(p string, h Handler, mw ...Middleware) { g.Handle(http.MethodGet, p, h, mw...) }
+func (g *Group) GET(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodGet, p, h, mw...)
+}
 
 // POST registers a handler for POST requests within the group.
-func (g *Group) POST(p string, h Handler, mw ...Middleware) {
-	g.Handle(http.MethodPost, p, h, mw...)
+func (g *Group) POST(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPost, p, h, mw...)
 }
 
 // PUT registers a handler for PUT requests within the group.
-func (g *Group) PUT(p string, h Handler, mw ...Middleware) { g.Handle(http.MethodPut, p, h, mw...) }
+func (g *Group) PUT(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPut, p, h, mw...)
+}
 
 // DELETE registers a handler for DELETE requests within the group.
-func (g *Group) DELETE(p string, h Handler, mw ...Middleware) {
-	g.Handle(http.MethodDelete, p, h, mw...)
+func (g *Group) DELETE(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodDelete, p, h, mw...)
 }
 
 // PATCH registers a handler for PATCH requests within the group.
-func (g *Group) PATCH(p string, h Handler, mw ...Middleware) {
-	g.Handle(http.MethodPatch, p, h, mw...)
+func (g *Group) PATCH(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPatch, p, h, mw...)
 }
 
 // OPTIONS registers a handler for OPTIONS requests within the group.
-func (g *Group) OPTIONS(p string, h Handler, mw ...Middleware) {
-	g.Handle(http.MethodOptions, p, h, mw...)
+func (g *Group) OPTIONS(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodOptions, p, h, mw...)
 }
 
 // HEAD registers a handler for HEAD requests within the group.
-func (g *Group) HEAD(p string, h Handler, mw ...Middleware) {
-	g.Handle(http.MethodHead, p, h, mw...)
+func (g *Group) HEAD(p string, h Handler, mw ...Middleware) *Route {
+	return g.Handle(http.MethodHead, p, h, mw...)
 }
 
+// RouteBuilder registers handlers for several methods on a single path with
+// shared middleware. Create one with Router.Route or Group.Route.
+type RouteBuilder struct {
+	r        *Router
+	prefix   string
+	path     string
+	mw       []Middleware
+	meta     map[string]any
+	methods  []string
+	handlers map[string]Handler
+}
+
+// Route returns a builder for registering handlers on path p, e.g.
+// r.Route("/users/:id").GET(show).PUT(update).Use(auth).
+func (r *Router) Route(p string, mw ...Middleware) *RouteBuilder {
+	return &RouteBuilder{r: r, path: p, mw: mw, handlers: make(map[string]Handler)}
+}
+
+// Route returns a builder for path p within the group. Group middleware runs
+// before the builder's middleware.
+func (g *Group) Route(p string, mw ...Middleware) *RouteBuilder {
+	fullMW := append([]Middleware{}, g.mw...)
+	fullMW = append(fullMW, mw...)
+	return &RouteBuilder{r: g.r, prefix: g.prefix, path: p, mw: fullMW, meta: g.meta, handlers: make(map[string]Handler)}
+}
+
+// Use adds middleware to every method on the route, including methods
+// registered before the call.
+func (b *RouteBuilder) Use(mw ...Middleware) *RouteBuilder {
+	b.mw = append(b.mw, mw...)
+	for _, m := range b.methods {
+		b.r.handleWithPrefix(b.prefix, m, b.path, b.handlers[m], b.meta, b.mw...)
+	}
+	return b
+}
+
+// Handle registers h for method on the route.
+func (b *RouteBuilder) Handle(method string, h Handler) *RouteBuilder {
+	method = strings.ToUpper(method)
+	if _, ok := b.handlers[method]; !ok {
+		b.methods = append(b.methods, method)
+	}
+	b.handlers[method] = h
+	b.r.handleWithPrefix(b.prefix, method, b.path, h, b.meta, b.mw...)
+	return b
+}
+
+// GET registers a handler for GET requests on the route.
+func (b *RouteBuilder) GET(h Handler) *RouteBuilder { return b.Handle(http.MethodGet, h) }
+
+// POST registers a handler for POST requests on the route.
+func (b *RouteBuilder) POST(h Handler) *RouteBuilder { return b.Handle(http.MethodPost, h) }
+
+// PUT registers a handler for PUT requests on the route.
+func (b *RouteBuilder) PUT(h Handler) *RouteBuilder { return b.Handle(http.MethodPut, h) }
+
+// DELETE registers a handler for DELETE requests on the route.
+func (b *RouteBuilder) DELETE(h Handler) *RouteBuilder { return b.Handle(http.MethodDelete, h) }
+
+// PATCH registers a handler for PATCH requests on the route.
+func (b *RouteBuilder) PATCH(h Handler) *RouteBuilder { return b.Handle(http.MethodPatch, h) }
+
+// OPTIONS registers a handler for OPTIONS requests on the route.
+func (b *RouteBuilder) OPTIONS(h Handler) *RouteBuilder { return b.Handle(http.MethodOptions, h) }
+
+// HEAD registers a handler for HEAD requests on the route.
+func (b *RouteBuilder) HEAD(h Handler) *RouteBuilder { return b.Handle(http.MethodHead, h) }
+
 // ServeFiles serves static files under prefix from provided filesystem (GET and HEAD).
 func (r *Router) ServeFiles(prefix string, fs http.FileSystem) {
 	fileServer := http.FileServer(fs)
@@ -232,6 +626,23 @@ func (r *Router) File(p, fpath string) {
 	r.HEAD(p, h)
 }
 
+// Favicon serves the file at fpath as /favicon.ico, cacheable for a day.
+func (r *Router) Favicon(fpath string) {
+	h := func(c *Context) {
+		c.SetHeader("Cache-Control", "public, max-age=86400")
+		http.ServeFile(c.W, c.R, fpath)
+	}
+	r.GET("/favicon.ico", h)
+	r.HEAD("/favicon.ico", h)
+}
+
+// Robots serves content as text/plain at /robots.txt.
+func (r *Router) Robots(content string) {
+	h := func(c *Context) { c.Text(http.StatusOK, content) }
+	r.GET("/robots.txt", h)
+	r.HEAD("/robots.txt", h)
+}
+
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := newContext(w, req)
@@ -241,7 +652,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// Trailing slash redirect: if enabled and path ends with "/" (but is not
 	// the root), redirect to the trimmed path preserving the query string.
 	urlPath := req.URL.Path
-	if r.RedirectTrailingSlash && len(urlPath) > 1 && strings.HasSuffix(urlPath, "/") {
+	if len(urlPath) > 1 && strings.HasSuffix(urlPath, "/") && r.redirectTrailingSlash(strings.TrimRight(urlPath, "/")) {
 		target := strings.TrimRight(urlPath, "/")
 		// Guard against open redirect (GHSA-mqqf-5wvp-8fh8): backslashes in
 		// the path or a double-slash prefix are interpreted by browsers as a
@@ -260,37 +671,152 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 route:
 
-	n, params := r.find(urlPath)
+	h := r.resolve(c, urlPath)
+	if tooManyQueryParams(req.URL.RawQuery, r.MaxQueryParams) {
+		h = func(c *Context) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "too many query parameters"})
+		}
+	}
+	c.maxBodySize = r.MaxBodySize
+	c.uploadDir = r.UploadDir
+	c.maxPartSize = r.MaxPartSize
+	c.maxMultipartMemory = r.MaxMultipartMemory
+	c.router = r
+	c.debug = r.Debug
+	mw := r.mw
+	r.mu.RUnlock()
+
+	h = chain(mw, h)
+	h(c)
+}
+
+// tooManyQueryParams reports whether rawQuery holds more than limit
+// parameters. It scans the pairs without decoding them, so an oversized query
+// is rejected cheaply, and skips the pairs url.ParseQuery would drop: empty
+// ones and those containing a semicolon.
+func tooManyQueryParams(rawQuery string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	n := 0
+	for rawQuery != "" {
+		var pair string
+		pair, rawQuery, _ = strings.Cut(rawQuery, "&")
+		if pair == "" || strings.Contains(pair, ";") {
+			continue
+		}
+		if n++; n > limit {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve selects the handler for the request method and urlPath, storing any
+// matched path parameters on c. Callers must hold r.mu for reading.
+func (r *Router) resolve(c *Context, urlPath string) Handler {
+	var parts []string
+	if r.UseRawPath && c.R.URL.RawPath != "" {
+		parts = splitRawPath(c.R.URL.EscapedPath())
+	} else {
+		parts = splitPath(urlPath)
+	}
+	n, params := r.find(parts)
+	if r.StrictSlash && n != nil && n != r.root && !n.wildcard {
+		if strings.HasSuffix(urlPath, "/") {
+			if !n.slashPath {
+				n = nil
+			}
+		} else if !n.plainPath {
+			n = nil
+		}
+	}
 	var h Handler
 	if n == nil || len(n.handlers) == 0 {
 		h = r.errorHandler(http.StatusNotFound, ErrNotFound)
-	} else if handler, ok := n.handlers[strings.ToUpper(req.Method)]; ok {
+	} else if handler, ok := n.handlers[strings.ToUpper(c.R.Method)]; ok {
 		c.params = params
+		c.routePattern = n.pattern
+		c.routeMeta = n.meta[strings.ToUpper(c.R.Method)]
 		h = handler
-	} else if req.Method == http.MethodHead {
+	} else if c.R.Method == http.MethodHead {
 		// Auto HEAD: fall back to the GET handler if no explicit HEAD handler exists.
 		if getHandler, gok := n.handlers[http.MethodGet]; gok {
 			c.params = params
+			c.routePattern = n.pattern
+			c.routeMeta = n.meta[http.MethodGet]
 			h = getHandler
 		} else {
-			h = r.errorHandler(http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+			h = r.methodNotAllowed(n)
+		}
+	} else if c.R.Method == http.MethodOptions && r.HandleOPTIONS {
+		c.params = params
+		c.routePattern = n.pattern
+		allow := n.allowHeader(true)
+		h = func(c *Context) {
+			c.SetHeader("Allow", allow)
+			c.Status(http.StatusNoContent)
 		}
 	} else {
-		h = r.errorHandler(http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+		h = r.methodNotAllowed(n)
 	}
-	c.maxBodySize = r.MaxBodySize
-	c.uploadDir = r.UploadDir
-	mw := r.mw
-	r.mu.RUnlock()
+	return h
+}
 
-	h = chain(mw, h)
-	h(c)
+// methodNotAllowed returns the 405 handler for n, which sets the Allow header
+// before responding. With HideMethodNotAllowed the 404 handler is returned
+// unchanged so the registered methods are not revealed.
+func (r *Router) methodNotAllowed(n *node) Handler {
+	h := r.errorHandler(http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	if r.HideMethodNotAllowed {
+		return h
+	}
+	allow := n.allowHeader(r.HandleOPTIONS)
+	return func(c *Context) {
+		c.SetHeader("Allow", allow)
+		h(c)
+	}
+}
+
+// allowHeader returns the sorted, comma-separated methods served at n for an
+// Allow header, including HEAD when implied by GET, and OPTIONS when it is
+// registered or autoOptions is set. Frozen routers use the value precomputed
+// by Freeze.
+func (n *node) allowHeader(autoOptions bool) string {
+	if autoOptions && n.allow[1] != "" {
+		return n.allow[1]
+	}
+	if !autoOptions && n.allow[0] != "" {
+		return n.allow[0]
+	}
+	return n.buildAllow(autoOptions)
+}
+
+// buildAllow computes the Allow header value for allowHeader.
+func (n *node) buildAllow(autoOptions bool) string {
+	methods := make([]string, 0, len(n.handlers)+2)
+	for m := range n.handlers {
+		methods = append(methods, m)
+	}
+	if _, ok := n.handlers[http.MethodGet]; ok {
+		if _, ok := n.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	if _, ok := n.handlers[http.MethodOptions]; !ok && autoOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
 }
 
 // errorHandler returns the appropriate handler for the given status/error.
 // When a custom ErrorHandler is set it is used; otherwise the default
 // notFound/methodNA handlers are returned.
 func (r *Router) errorHandler(status int, err error) Handler {
+	if status == http.StatusMethodNotAllowed && r.HideMethodNotAllowed {
+		status, err = http.StatusNotFound, ErrNotFound
+	}
 	if r.ErrorHandler != nil {
 		eh := r.ErrorHandler
 		return func(c *Context) { eh(c, status, err) }
@@ -301,34 +827,59 @@ func (r *Router) errorHandler(status int, err error) Handler {
 	return r.notFound
 }
 
-func (r *Router) find(pathStr string) (*node, map[string]string) {
-	parts := splitPath(pathStr)
-	n := r.root
+func (r *Router) find(parts []string) (*node, map[string]string) {
 	params := map[string]string{}
-	for i := 0; i < len(parts); i++ {
-		p := parts[i]
-		var next *node
-		for _, ch := range n.children {
-			if ch.segment == p {
-				next = ch
-				break
+	n := r.root.match(parts, params)
+	if n == nil {
+		return nil, nil
+	}
+	return n, params
+}
+
+// match returns the node with handlers for parts below n. Static segments
+// are preferred over params, and params over wildcards; when a branch
+// dead-ends, the next candidate is tried. Params are recorded only along
+// the successful branch.
+func (n *node) match(parts []string, params map[string]string) *node {
+	if len(parts) == 0 {
+		if len(n.handlers) == 0 {
+			return nil
+		}
+		return n
+	}
+	seg := parts[0]
+	for _, ch := range n.children {
+		if ch.rank() == 0 && ch.segment == seg {
+			if m := ch.match(parts[1:], params); m != nil {
+				return m
 			}
-			if ch.param {
-				next = ch
-				params[ch.segment[1:]] = p
+			break
+		}
+	}
+	for _, ch := range n.children {
+		if ch.param && ch.re == nil {
+			if m := ch.match(parts[1:], params); m != nil {
+				params[ch.name] = seg
+				return m
 			}
-			if ch.wildcard {
-				next = ch
-				params["*"] = strings.Join(parts[i:], "/")
-				i = len(parts) - 1
+			break
+		}
+	}
+	for _, ch := range n.children {
+		if ch.re != nil && ch.re.MatchString(seg) {
+			if m := ch.match(parts[1:], params); m != nil {
+				params[ch.name] = seg
+				return m
 			}
 		}
-		if next == nil {
-			return nil, nil
+	}
+	for _, ch := range n.children {
+		if ch.wildcard && len(ch.handlers) > 0 {
+			params["*"] = strings.Join(parts, "/")
+			return ch
 		}
-		n = next
 	}
-	return n, params
+	return nil
 }
 
 func splitPath(p string) []string {
@@ -346,6 +897,18 @@ func splitPath(p string) []string {
 	return parts
 }
 
+// splitRawPath splits an escaped path on literal slashes and unescapes each
+// segment, so %2F survives as a slash within its segment.
+func splitRawPath(raw string) []string {
+	parts := splitPath(raw)
+	for i, s := range parts {
+		if u, err := url.PathUnescape(s); err == nil {
+			parts[i] = u
+		}
+	}
+	return parts
+}
+
 func matchChild(n *node, seg string) *node {
 	for _, ch := range n.children {
 		if ch.segment == seg {
@@ -356,9 +919,10 @@ func matchChild(n *node, seg string) *node {
 		}
 	}
 	// Detect conflicting param names at the same level (e.g. :id vs :userId).
-	if strings.HasPrefix(seg, ":") {
+	// Constrained params such as :id(\d+) may sit beside a plain param.
+	if strings.HasPrefix(seg, ":") && !strings.Contains(seg, "(") {
 		for _, ch := range n.children {
-			if ch.param {
+			if ch.param && ch.re == nil {
 				panic("quokka: conflicting param name " + seg + ", existing " + ch.segment)
 			}
 		}
@@ -372,6 +936,8 @@ type ctxKey string
 
 const (
 	ctxKeyRequestID ctxKey = "request_id"
+	ctxKeyAttempt   ctxKey = "attempt"
+	ctxKeyTraceIDs  ctxKey = "trace_ids"
 )
 
 // WithRequestID injects a request id into context
@@ -384,3 +950,28 @@ func RequestID(ctx context.Context) (string, bool) {
 	v, ok := ctx.Value(ctxKeyRequestID).(string)
 	return v, ok
 }
+
+// WithAttempt injects a client retry attempt number into context
+func WithAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, ctxKeyAttempt, n)
+}
+
+// Attempt extracts the client retry attempt number recorded by Logger, if any
+func Attempt(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(ctxKeyAttempt).(int)
+	return v, ok
+}
+
+// traceIDs holds hex-encoded trace and span ids.
+type traceIDs struct{ trace, span string }
+
+// WithTraceIDs injects hex-encoded trace and span ids into context
+func WithTraceIDs(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, ctxKeyTraceIDs, traceIDs{traceID, spanID})
+}
+
+// TraceIDs extracts the trace and span ids recorded by OTel, if any
+func TraceIDs(ctx context.Context) (traceID, spanID string, ok bool) {
+	v, ok := ctx.Value(ctxKeyTraceIDs).(traceIDs)
+	return v.trace, v.span, ok
+}
@@ -17,10 +17,13 @@
 package quokka_test
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -145,6 +148,30 @@ var _ = Describe("Router", func() {
 		Expect(rr.Body.String()).To(ContainSubstring("Apache License"))
 	})
 
+	It("serves an opt-in favicon and robots.txt", func() {
+		r := q.New()
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+
+		icon := filepath.Join(GinkgoT().TempDir(), "favicon.ico")
+		Expect(os.WriteFile(icon, []byte("\x00\x00\x01\x00icon"), 0o600)).To(Succeed())
+		r.Favicon(icon)
+		r.Robots("User-agent: *\nDisallow: /admin\n")
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(HaveSuffix("icon"))
+		Expect(rr.Header().Get("Cache-Control")).To(Equal("public, max-age=86400"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(rr.Body.String()).To(Equal("User-agent: *\nDisallow: /admin\n"))
+	})
+
 	It("handles concurrent requests safely", func() {
 		r := q.New()
 		r.GET("/count", func(c *q.Context) { c.Text(http.StatusOK, "ok") })
@@ -287,6 +314,77 @@ var _ = Describe("Router", func() {
 		Expect(rr.Header().Get("Location")).To(Equal("/api/users"))
 	})
 
+	It("lets a group disable trailing slash redirects while the router default is on", func() {
+		r := q.New()
+		off := false
+		r.RedirectTrailingSlash = true
+		api := r.Group("/api")
+		api.RedirectTrailingSlash = &off
+		api.GET("/users", func(c *q.Context) { c.Text(http.StatusOK, "users") })
+		r.GET("/about", func(c *q.Context) { c.Text(http.StatusOK, "about") })
+		r.GET("/apidocs", func(c *q.Context) { c.Text(http.StatusOK, "docs") })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/users/", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("users"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/about/", nil))
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("/about"))
+
+		// /apidocs shares a string prefix with /api but not a path segment.
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/apidocs/", nil))
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+	})
+
+	It("lets a group enable trailing slash redirects while the router default is off", func() {
+		r := q.New()
+		on := true
+		web := r.Group("/web")
+		web.RedirectTrailingSlash = &on
+		web.GET("/home", func(c *q.Context) { c.Text(http.StatusOK, "home") })
+		r.GET("/api/users", func(c *q.Context) { c.Text(http.StatusOK, "users") })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/web/home/", nil))
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("/web/home"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/users/", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	It("uses the longest matching group prefix for trailing slash redirects", func() {
+		r := q.New()
+		on, off := true, false
+		r.RedirectTrailingSlash = true
+		api := r.Group("/api")
+		api.RedirectTrailingSlash = &off
+		v2 := r.Group("/api/v2")
+		v2.RedirectTrailingSlash = &on
+		v2.GET("/items", func(c *q.Context) { c.Text(http.StatusOK, "items") })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v2/items/", nil))
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+	})
+
+	It("lets groups without an override follow later router changes", func() {
+		r := q.New()
+		api := r.Group("/api")
+		api.GET("/users", func(c *q.Context) { c.Text(http.StatusOK, "users") })
+		r.RedirectTrailingSlash = true
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/users/", nil))
+		Expect(rr.Code).To(Equal(http.StatusMovedPermanently))
+		Expect(rr.Header().Get("Location")).To(Equal("/api/users"))
+	})
+
 	It("preserves query string in trailing slash redirect", func() {
 		r := q.New()
 		r.RedirectTrailingSlash = true
@@ -402,6 +500,50 @@ var _ = Describe("Router", func() {
 		Expect(rr.Body.String()).To(ContainSubstring("CUSTOM_404"))
 	})
 
+	It("returns 404 instead of 405 when HideMethodNotAllowed is set", func() {
+		r := q.New()
+		r.HideMethodNotAllowed = true
+		var gotErr error
+		r.GET("/admin", func(c *q.Context) { c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+
+		r.ErrorHandler = func(c *q.Context, status int, err error) { gotErr = err; c.Status(status) }
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+		Expect(gotErr).To(MatchError(q.ErrNotFound))
+		Expect(rr.Header().Get("Allow")).To(BeEmpty())
+	})
+
+	It("sets the Allow header on 405 responses", func() {
+		r := q.New()
+		r.GET("/items", func(c *q.Context) { c.Status(http.StatusOK) })
+		r.POST("/items", func(c *q.Context) { c.Status(http.StatusCreated) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/items", nil))
+		Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rr.Header().Get("Allow")).To(Equal("GET, HEAD, POST"))
+
+		r.ErrorHandler = func(c *q.Context, status int, err error) { c.Status(status) }
+		r.HandleOPTIONS = true
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/items", nil))
+		Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rr.Header().Get("Allow")).To(Equal("GET, HEAD, OPTIONS, POST"))
+	})
+
+	It("keeps 405 for a wrong method by default", func() {
+		r := q.New()
+		r.GET("/admin", func(c *q.Context) { c.Status(http.StatusOK) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin", nil))
+		Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
 	It("calls ErrorHandler on 405 when set", func() {
 		r := q.New()
 		r.POST("/things", func(c *q.Context) { c.Status(http.StatusCreated) })
@@ -444,4 +586,496 @@ var _ = Describe("Router", func() {
 		Expect(rr.Code).To(Equal(http.StatusNotFound))
 		Expect(order).To(Equal([]string{"mw", "eh"}))
 	})
+
+	It("UseRawPath keeps encoded slashes within a param", func() {
+		r := q.New()
+		r.UseRawPath = true
+		var name, rest string
+		r.GET("/files/:name", func(c *q.Context) { name = c.Param("name"); c.Status(http.StatusOK) })
+		r.GET("/files/:name/meta", func(c *q.Context) { name = c.Param("name") + "|meta"; c.Status(http.StatusOK) })
+		r.GET("/raw/*", func(c *q.Context) { rest = c.Param("*"); c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/docs%2Freport.pdf", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(name).To(Equal("docs/report.pdf"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/a%2Fb/meta", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(name).To(Equal("a/b|meta"))
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/raw/x%2Fy/z", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rest).To(Equal("x/y/z"))
+	})
+
+	It("splits encoded slashes by default", func() {
+		r := q.New()
+		r.GET("/files/:name", func(c *q.Context) { c.Status(http.StatusOK) })
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/docs%2Freport.pdf", nil))
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Route builder registers methods with shared middleware", func() {
+		r := q.New()
+		var trace []string
+		mw := func(name string) q.Middleware {
+			return func(next q.Handler) q.Handler {
+				return func(c *q.Context) { trace = append(trace, name); next(c) }
+			}
+		}
+		r.Route("/users/:id", mw("a")).
+			GET(func(c *q.Context) { c.Text(http.StatusOK, "get "+c.Param("id")) }).
+			PUT(func(c *q.Context) { c.Text(http.StatusOK, "put "+c.Param("id")) }).
+			Use(mw("b")).
+			DELETE(func(c *q.Context) { c.Status(http.StatusNoContent) })
+
+		for _, tc := range []struct {
+			method string
+			code   int
+			body   string
+		}{
+			{http.MethodGet, http.StatusOK, "get 7"},
+			{http.MethodPut, http.StatusOK, "put 7"},
+			{http.MethodDelete, http.StatusNoContent, ""},
+		} {
+			trace = nil
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(tc.method, "/users/7", nil))
+			Expect(rr.Code).To(Equal(tc.code))
+			Expect(rr.Body.String()).To(Equal(tc.body))
+			Expect(trace).To(Equal([]string{"a", "b"}))
+		}
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users/7", nil))
+		Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("Group Route builder applies group prefix and middleware", func() {
+		r := q.New()
+		var trace []string
+		g := r.Group("/api", func(next q.Handler) q.Handler {
+			return func(c *q.Context) { trace = append(trace, "group"); next(c) }
+		})
+		g.Route("/items").Use(func(next q.Handler) q.Handler {
+			return func(c *q.Context) { trace = append(trace, "route"); next(c) }
+		}).GET(func(c *q.Context) { c.Status(http.StatusOK) })
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(trace).To(Equal([]string{"group", "route"}))
+	})
+
+	It("exposes the matched route pattern", func() {
+		r := q.New()
+		var pattern string
+		g := r.Group("/api")
+		g.GET("/users/:id", func(c *q.Context) { pattern = c.RoutePattern(); c.Status(http.StatusOK) })
+		r.GET("/files/*", func(c *q.Context) { pattern = c.RoutePattern(); c.Status(http.StatusOK) })
+		r.GET("/", func(c *q.Context) { pattern = c.RoutePattern(); c.Status(http.StatusOK) })
+
+		for path, want := range map[string]string{
+			"/api/users/9": "/api/users/:id",
+			"/files/a/b":   "/files/*",
+			"/":            "/",
+		} {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(pattern).To(Equal(want))
+		}
+	})
+
+	Describe("Freeze", func() {
+		It("panics on registration after Freeze", func() {
+			r := q.New()
+			r.GET("/a", func(c *q.Context) { c.Status(http.StatusOK) })
+			r.Freeze()
+			Expect(func() { r.GET("/b", func(c *q.Context) {}) }).To(PanicWith(ContainSubstring("after Freeze")))
+			Expect(func() { r.Group("/g").POST("/c", func(c *q.Context) {}) }).To(Panic())
+		})
+
+		It("matches static, param, and wildcard routes by priority", func() {
+			r := q.New()
+			var got string
+			r.GET("/files/*", func(c *q.Context) { got = "wild:" + c.Param("*"); c.Status(http.StatusOK) })
+			r.GET("/files/:name", func(c *q.Context) { got = "param:" + c.Param("name"); c.Status(http.StatusOK) })
+			r.GET("/files/readme", func(c *q.Context) { got = "static"; c.Status(http.StatusOK) })
+			r.Freeze()
+
+			for path, want := range map[string]string{
+				"/files/readme":  "static",
+				"/files/a.txt":   "param:a.txt",
+				"/files/dir/a.b": "wild:dir/a.b",
+			} {
+				rr := httptest.NewRecorder()
+				r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+				Expect(rr.Code).To(Equal(http.StatusOK), path)
+				Expect(got).To(Equal(want), path)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/files/readme", nil))
+			Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("serves the Allow header precomputed by Freeze", func() {
+			r := q.New()
+			r.HandleOPTIONS = true
+			r.PUT("/items/:id", func(c *q.Context) { c.Status(http.StatusOK) })
+			r.GET("/items/:id", func(c *q.Context) { c.Status(http.StatusOK) })
+			r.DELETE("/items/:id", func(c *q.Context) { c.Status(http.StatusOK) })
+			r.Freeze()
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items/1", nil))
+			Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rr.Header().Get("Allow")).To(Equal("DELETE, GET, HEAD, OPTIONS, PUT"))
+
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, "/items/1", nil))
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+			Expect(rr.Header().Get("Allow")).To(Equal("DELETE, GET, HEAD, OPTIONS, PUT"))
+
+			r.HandleOPTIONS = false
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items/1", nil))
+			Expect(rr.Header().Get("Allow")).To(Equal("DELETE, GET, HEAD, PUT"))
+		})
+	})
+
+	Describe("GETJSON", func() {
+		It("writes the returned status and value as JSON", func() {
+			r := q.New()
+			r.GETJSON("/users/:id", func(c *q.Context) (int, any, error) {
+				return http.StatusOK, map[string]string{"id": c.Param("id")}, nil
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+			Expect(rr.Body.String()).To(MatchJSON(`{"id":"7"}`))
+		})
+
+		It("renders a returned *HTTPError with its status and message", func() {
+			r := q.New()
+			api := r.Group("/api")
+			api.GETJSON("/users/:id", func(c *q.Context) (int, any, error) {
+				return 0, nil, q.NewHTTPError(http.StatusNotFound, "user not found")
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/users/7", nil))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"user not found"}`))
+		})
+
+		It("hides untyped errors behind a 500", func() {
+			r := q.New()
+			r.GETJSON("/boom", func(c *q.Context) (int, any, error) {
+				return 0, nil, errors.New("db password wrong")
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/boom", nil))
+			Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+			Expect(rr.Body.String()).NotTo(ContainSubstring("password"))
+		})
+
+		It("routes returned errors through the router ErrorHandler", func() {
+			r := q.New()
+			var gotStatus int
+			var gotErr error
+			r.ErrorHandler = func(c *q.Context, status int, err error) {
+				gotStatus, gotErr = status, err
+				c.Text(status, "custom")
+			}
+			cause := errors.New("locked")
+			r.GETJSON("/x", func(c *q.Context) (int, any, error) {
+				return 0, nil, &q.HTTPError{Status: http.StatusConflict, Message: "conflict", Err: cause}
+			})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/x", nil))
+			Expect(rr.Code).To(Equal(http.StatusConflict))
+			Expect(rr.Body.String()).To(Equal("custom"))
+			Expect(gotStatus).To(Equal(http.StatusConflict))
+			Expect(gotErr).To(MatchError(cause))
+		})
+	})
+
+	Context("Named routes and URL generation", func() {
+		It("builds URLs with escaped params and round-trips against the pattern", func() {
+			r := q.New()
+			var got map[string]string
+			r.GET("/users/:id/posts/:slug", func(c *q.Context) {
+				got = map[string]string{"id": c.Param("id"), "slug": c.Param("slug")}
+				c.Status(http.StatusOK)
+			}).Name("post.show")
+
+			u, err := r.URL("post.show", map[string]string{"id": "42", "slug": "hello world/2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/users/42/posts/hello%20world%2F2"))
+
+			r.UseRawPath = true
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, u, nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(got).To(Equal(map[string]string{"id": "42", "slug": "hello world/2"}))
+		})
+
+		It("substitutes wildcards and includes group prefixes", func() {
+			r := q.New()
+			g := r.Group("/api")
+			g.GET("/files/*", func(c *q.Context) {}).Name("files")
+			r.Route("/items/:id").GET(func(c *q.Context) {}).Name("item")
+
+			u, err := r.URL("files", map[string]string{"*": "docs/a b.txt"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/api/files/docs/a%20b.txt"))
+
+			u, err = r.URL("item", map[string]string{"id": "7"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/items/7"))
+		})
+
+		It("errors on missing params and unknown names", func() {
+			r := q.New()
+			r.GET("/users/:id", func(c *q.Context) {}).Name("user.show")
+			_, err := r.URL("user.show", nil)
+			Expect(err).To(MatchError(ContainSubstring(`missing parameter "id"`)))
+			_, err = r.URL("user.missing", nil)
+			Expect(err).To(MatchError(ContainSubstring("unknown route name")))
+		})
+
+		It("panics when a name is reused for another pattern", func() {
+			r := q.New()
+			r.GET("/a", func(c *q.Context) {}).Name("x")
+			r.POST("/a", func(c *q.Context) {}).Name("x")
+			Expect(func() { r.GET("/b", func(c *q.Context) {}).Name("x") }).To(Panic())
+		})
+	})
+
+	Context("Routes", func() {
+		It("lists routes across groups with full patterns and auto-HEAD", func() {
+			r := q.New()
+			h := func(c *q.Context) {}
+			r.GET("/", h)
+			r.POST("/login", h)
+			api := r.Group("/api")
+			api.GET("/users/:id", h)
+			api.PUT("/users/:id", h)
+			v2 := r.Group("/api/v2")
+			v2.GET("/files/*", h)
+			v2.HEAD("/files/*", h)
+
+			Expect(r.Routes()).To(Equal([]q.RouteInfo{
+				{Method: http.MethodGet, Pattern: "/"},
+				{Method: http.MethodHead, Pattern: "/", AutoHEAD: true},
+				{Method: http.MethodGet, Pattern: "/api/users/:id"},
+				{Method: http.MethodHead, Pattern: "/api/users/:id", AutoHEAD: true},
+				{Method: http.MethodPut, Pattern: "/api/users/:id"},
+				{Method: http.MethodGet, Pattern: "/api/v2/files/*"},
+				{Method: http.MethodHead, Pattern: "/api/v2/files/*"},
+				{Method: http.MethodPost, Pattern: "/login"},
+			}))
+		})
+	})
+
+	Context("Regex-constrained params", func() {
+		serve := func(r *q.Router, p string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, p, nil))
+			return rr
+		}
+
+		It("binds the param only when the segment matches", func() {
+			r := q.New()
+			r.GET(`/users/:id(\d+)`, func(c *q.Context) { c.Text(http.StatusOK, "user "+c.Param("id")) })
+
+			rr := serve(r, "/users/42")
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("user 42"))
+			Expect(serve(r, "/users/abc").Code).To(Equal(http.StatusNotFound))
+			Expect(serve(r, "/users/42x").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("falls back to another route when the constraint fails", func() {
+			r := q.New()
+			r.GET(`/files/:id(\d+)`, func(c *q.Context) { c.Text(http.StatusOK, "id "+c.Param("id")) })
+			r.GET(`/files/:name([a-z]+)/raw`, func(c *q.Context) { c.Text(http.StatusOK, "raw "+c.Param("name")) })
+			r.GET("/files/*", func(c *q.Context) { c.Text(http.StatusOK, "any "+c.Param("*")) })
+
+			Expect(serve(r, "/files/7").Body.String()).To(Equal("id 7"))
+			Expect(serve(r, "/files/doc/raw").Body.String()).To(Equal("raw doc"))
+			Expect(serve(r, "/files/doc").Body.String()).To(Equal("any doc"))
+		})
+
+		It("gives static and plain param children precedence", func() {
+			r := q.New()
+			r.GET(`/users/:id(\d+)`, func(c *q.Context) { c.Text(http.StatusOK, "constrained") })
+			r.GET("/users/42", func(c *q.Context) { c.Text(http.StatusOK, "static") })
+			r.GET("/users/:name", func(c *q.Context) { c.Text(http.StatusOK, "plain "+c.Param("name")) })
+
+			Expect(serve(r, "/users/42").Body.String()).To(Equal("static"))
+			Expect(serve(r, "/users/abc").Body.String()).To(Equal("plain abc"))
+			Expect(serve(r, "/users/7").Body.String()).To(Equal("plain 7"))
+			r.Freeze()
+			Expect(serve(r, "/users/42").Body.String()).To(Equal("static"))
+			Expect(serve(r, "/users/7").Body.String()).To(Equal("plain 7"))
+		})
+
+		It("panics on an invalid constraint", func() {
+			Expect(func() { q.New().GET(`/x/:id([)`, func(c *q.Context) {}) }).To(Panic())
+		})
+
+		It("validates constrained values in URL generation", func() {
+			r := q.New()
+			r.GET(`/users/:id(\d+)`, func(c *q.Context) {}).Name("user")
+			u, err := r.URL("user", map[string]string{"id": "42"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/users/42"))
+			_, err = r.URL("user", map[string]string{"id": "abc"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("StrictSlash", func() {
+		serve := func(r *q.Router, p string) int {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, p, nil))
+			return rr.Code
+		}
+		ok := func(c *q.Context) { c.Status(http.StatusOK) }
+
+		It("ignores trailing slashes by default", func() {
+			r := q.New()
+			r.GET("/api/users", ok)
+			Expect(serve(r, "/api/users/")).To(Equal(http.StatusOK))
+		})
+
+		It("returns 404 for an unregistered trailing-slash variant", func() {
+			r := q.New()
+			r.StrictSlash = true
+			r.GET("/api/users", ok)
+			Expect(serve(r, "/api/users")).To(Equal(http.StatusOK))
+			Expect(serve(r, "/api/users/")).To(Equal(http.StatusNotFound))
+		})
+
+		It("matches only the registered form, including within groups", func() {
+			r := q.New()
+			r.StrictSlash = true
+			g := r.Group("/api")
+			g.GET("/items/", ok)
+			r.GET("/both", ok)
+			r.GET("/both/", ok)
+			r.GET("/files/*", ok)
+
+			Expect(serve(r, "/api/items/")).To(Equal(http.StatusOK))
+			Expect(serve(r, "/api/items")).To(Equal(http.StatusNotFound))
+			Expect(serve(r, "/both")).To(Equal(http.StatusOK))
+			Expect(serve(r, "/both/")).To(Equal(http.StatusOK))
+			Expect(serve(r, "/files/a/")).To(Equal(http.StatusOK))
+		})
+
+		It("still redirects first when RedirectTrailingSlash is set", func() {
+			r := q.New()
+			r.StrictSlash = true
+			r.RedirectTrailingSlash = true
+			r.GET("/api/users", ok)
+			Expect(serve(r, "/api/users/")).To(Equal(http.StatusMovedPermanently))
+		})
+
+		It("keeps a registered trailing slash in generated URLs", func() {
+			r := q.New()
+			r.Group("/api").GET("/items/", ok).Name("items")
+			u, err := r.URL("items", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/api/items/"))
+
+			r.Group("/api").Route("/things/").GET(ok).Name("things")
+			u, err = r.URL("things", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal("/api/things/"))
+		})
+	})
+
+	Context("StatusMessages", func() {
+		It("overrides the default 404 and 405 messages", func() {
+			r := q.New()
+			r.StatusMessages = map[int]string{
+				http.StatusNotFound:         "resource_missing",
+				http.StatusMethodNotAllowed: "verb_unsupported",
+			}
+			r.GET("/x", func(c *q.Context) {})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/nope", nil))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"resource_missing"}`))
+
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/x", nil))
+			Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"verb_unsupported"}`))
+		})
+
+		It("applies to Fail and keeps defaults for unmapped codes", func() {
+			r := q.New()
+			r.StatusMessages = map[int]string{http.StatusConflict: "Konflikt"}
+			r.GET("/conflict", func(c *q.Context) { c.Fail(http.StatusConflict, "dup", "already exists") })
+			r.GET("/gone", func(c *q.Context) { c.Fail(http.StatusGone, "gone", "removed") })
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/conflict", nil))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"Konflikt","code":"dup","message":"already exists"}`))
+
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/gone", nil))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"gone","code":"gone","message":"removed"}`))
+
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing", nil))
+			Expect(rr.Body.String()).To(MatchJSON(`{"error":"not found"}`))
+		})
+	})
+
+	Context("HandleOPTIONS", func() {
+		options := func(r *q.Router, p string) *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, p, nil))
+			return rr
+		}
+		noop := func(c *q.Context) {}
+
+		It("responds 204 with the registered methods in Allow", func() {
+			r := q.New()
+			r.HandleOPTIONS = true
+			r.GET("/items", noop)
+			r.POST("/items", noop)
+			r.PUT("/items/:id", noop)
+
+			rr := options(r, "/items")
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+			Expect(rr.Header().Get("Allow")).To(Equal("GET, HEAD, OPTIONS, POST"))
+			Expect(options(r, "/items/7").Header().Get("Allow")).To(Equal("OPTIONS, PUT"))
+			Expect(options(r, "/missing").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("lets an explicit OPTIONS handler take precedence", func() {
+			r := q.New()
+			r.HandleOPTIONS = true
+			r.GET("/items", noop)
+			r.OPTIONS("/items", func(c *q.Context) { c.SetHeader("Allow", "GET"); c.Status(http.StatusOK) })
+			rr := options(r, "/items")
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(rr.Header().Get("Allow")).To(Equal("GET"))
+		})
+
+		It("is off by default", func() {
+			r := q.New()
+			r.GET("/items", noop)
+			Expect(options(r, "/items").Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
 })
@@ -0,0 +1,101 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Route is a registered route, returned by Handle and the method helpers so
+// it can be named for URL generation or given metadata.
+type Route struct {
+	r       *Router
+	n       *node
+	Method  string // upper-case HTTP method
+	Pattern string // full registered path, including any group prefix
+}
+
+// Name registers name for the route's pattern so Router.URL can build paths to
+// it, e.g. r.GET("/users/:id", show).Name("user.show"). Reusing a name for a
+// different pattern panics.
+func (rt *Route) Name(name string) *Route {
+	rt.r.mu.Lock()
+	defer rt.r.mu.Unlock()
+	if existing, ok := rt.r.names[name]; ok && existing != rt.Pattern {
+		panic("quokka: route name " + name + " already used for " + existing)
+	}
+	if rt.r.names == nil {
+		rt.r.names = make(map[string]string)
+	}
+	rt.r.names[name] = rt.Pattern
+	return rt
+}
+
+// Name registers name for the builder's path; see Route.Name.
+func (b *RouteBuilder) Name(name string) *RouteBuilder {
+	(&Route{r: b.r, Pattern: joinPrefix(b.prefix, b.path)}).Name(name)
+	return b
+}
+
+// URL builds the path for the route registered under name, substituting each
+// :param segment with params[param] and a trailing * with params["*"]. Values
+// are path-escaped; slashes in the wildcard value are kept as separators. It
+// returns an error for an unknown name, a missing parameter, or a value that
+// fails the parameter's constraint.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	r.mu.RLock()
+	pattern, ok := r.names[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("quokka: unknown route name %q", name)
+	}
+	parts := splitPath(pattern)
+	for i, seg := range parts {
+		switch {
+		case seg == "*":
+			v, ok := params["*"]
+			if !ok {
+				return "", fmt.Errorf("quokka: route %q: missing wildcard parameter", name)
+			}
+			sub := strings.Split(strings.Trim(v, "/"), "/")
+			for j, s := range sub {
+				sub[j] = url.PathEscape(s)
+			}
+			parts[i] = strings.Join(sub, "/")
+		case strings.HasPrefix(seg, ":"):
+			key, expr := parseParam(seg)
+			v, ok := params[key]
+			if !ok || v == "" {
+				return "", fmt.Errorf("quokka: route %q: missing parameter %q", name, key)
+			}
+			if expr != "" {
+				if matched, _ := regexp.MatchString("^(?:"+expr+")$", v); !matched {
+					return "", fmt.Errorf("quokka: route %q: parameter %q does not match %s", name, key, expr)
+				}
+			}
+			parts[i] = url.PathEscape(v)
+		}
+	}
+	u := "/" + strings.Join(parts, "/")
+	if len(parts) > 0 && strings.HasSuffix(pattern, "/") {
+		u += "/"
+	}
+	return u, nil
+}
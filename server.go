@@ -21,39 +21,162 @@ import (
 	"crypto/tls"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Server wraps http.Server with graceful shutdown and health endpoints.
+// Additional listeners added with AddListener share its lifecycle.
 type Server struct {
 	HTTP   *http.Server
 	Logger *slog.Logger
+
+	// Health holds the checks served at the liveness and readiness paths on
+	// every listener when ServerConfig.Health is set; otherwise it is nil.
+	// Both endpoints answer 200 while no checks are registered.
+	Health *Health
+
+	// ShutdownTimeout bounds the graceful shutdown Start performs on a
+	// shutdown signal or listener failure, including OnShutdown hooks.
+	ShutdownTimeout time.Duration
+
+	cfgErr    error
+	extra     []*http.Server
+	mu        sync.Mutex
+	hooks     []func(context.Context) error
+	hooksOnce sync.Once
+	hooksErr  error
 }
 
 // ServerConfig holds optional settings for NewServer.
-// Zero values fall back to sensible defaults (addr :8080, read 15s, write 30s, idle 120s, header 5s).
+// Zero values fall back to sensible defaults (addr :8080, read 15s, write 30s, idle 120s, header 5s,
+// shutdown 30s).
 type ServerConfig struct {
 	Addr              string
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
 	IdleTimeout       time.Duration
 	ReadHeaderTimeout time.Duration
+	ShutdownTimeout   time.Duration
 	TLSConfig         *tls.Config
+
+	// HTTP2 tunes HTTP/2 serving. Zero fields fall back to hardened defaults
+	// (100 concurrent streams per connection, write byte timeout equal to
+	// WriteTimeout); the value is copied, not modified. Set CountError to
+	// count HTTP/2 protocol errors, such as those caused by abusive stream
+	// resets, in a metric. The stdlib limits handlers started by reset streams
+	// to MaxConcurrentStreams per connection (CVE-2023-44487).
+	HTTP2 *http.HTTP2Config
+
+	// H2C serves HTTP/2 over plaintext (prior knowledge or "Upgrade: h2c"),
+	// for deployments that terminate TLS at a load balancer but speak HTTP/2
+	// to the app. The HTTP2 settings apply. It cannot be combined with
+	// TLSConfig; Start returns an error if both are set.
+	H2C bool
+
+	// AutoTLS obtains certificates from Let's Encrypt for the listed
+	// domains, serving TLS on Addr (default ":443") and the HTTP-01
+	// challenge on AutoTLS.HTTPAddr. It cannot be combined with TLSConfig;
+	// Start returns an error if both are set.
+	AutoTLS *AutoTLSConfig
+
+	// Health enables liveness and readiness endpoints, answered before
+	// requests reach the handler. Nil disables them.
+	Health *HealthConfig
+}
+
+// HealthConfig configures the health endpoints served by Server.
+type HealthConfig struct {
+	// LivenessPath is the path of the liveness endpoint. Default: "/livez".
+	LivenessPath string
+
+	// ReadinessPath is the path of the readiness endpoint. Default: "/readyz".
+	ReadinessPath string
+
+	// Timeout bounds each endpoint's run of its checks. Default: 5s.
+	Timeout time.Duration
+}
+
+// AutoTLSConfig configures automatic certificates via ACME.
+type AutoTLSConfig struct {
+	// Domains lists the host names certificates may be requested for.
+	// Requests for any other host are refused during the TLS handshake.
+	Domains []string
+
+	// CacheDir stores issued certificates and the account key across
+	// restarts, avoiding ACME rate limits. Default: "autocert".
+	CacheDir string
+
+	// HTTPAddr is where the HTTP-01 challenge handler listens; other
+	// requests on it are redirected to HTTPS. Default: ":80".
+	HTTPAddr string
+}
+
+// defaultMaxConcurrentStreams bounds concurrent HTTP/2 streams per connection.
+const defaultMaxConcurrentStreams = 100
+
+// ListenerConfig describes an additional listener for Server.AddListener.
+type ListenerConfig struct {
+	// Addr is the TCP address to listen on (e.g. ":80").
+	Addr string
+
+	// TLSConfig enables HTTPS on this listener when set.
+	TLSConfig *tls.Config
+
+	// RedirectToHTTPS, when true, answers every request with a 308 redirect
+	// to the same host and URI over https instead of serving the handler.
+	RedirectToHTTPS bool
+
+	// RedirectPort is the port used in redirect targets. Empty or "443"
+	// omits the port.
+	RedirectPort string
 }
 
 // NewServer creates a Server with the given config, handler, and logger.
-// A nil logger defaults to slog.Default.
+// A nil logger defaults to slog.Default. When cfg.Health is set, GET and HEAD
+// requests for its liveness and readiness paths are answered by Health before
+// reaching handler.
 func NewServer(cfg ServerConfig, handler http.Handler, logger *slog.Logger) *Server {
-	if logger == nil {
-		logger = slog.Default()
+	logger = loggerOrDefault(logger)
+	var cfgErr error
+	switch {
+	case cfg.H2C && (cfg.TLSConfig != nil || cfg.AutoTLS != nil):
+		cfgErr = errors.New("quokka: H2C cannot be combined with TLSConfig or AutoTLS")
+	case cfg.AutoTLS != nil && cfg.TLSConfig != nil:
+		cfgErr = errors.New("quokka: AutoTLS cannot be combined with TLSConfig")
+	}
+	var manager *autocert.Manager
+	if cfg.AutoTLS != nil && cfg.TLSConfig == nil {
+		manager = autoTLSManager(*cfg.AutoTLS)
+		cfg.TLSConfig = SecureTLSConfig()
+		cfg.TLSConfig.GetCertificate = manager.GetCertificate
+		cfg.TLSConfig.NextProtos = manager.TLSConfig().NextProtos
+		if cfg.Addr == "" {
+			cfg.Addr = ":443"
+		}
 	}
 	if cfg.Addr == "" {
 		cfg.Addr = ":8080"
 	}
+	var health *Health
+	if cfg.Health != nil {
+		health = &Health{
+			Timeout:       cfg.Health.Timeout,
+			livenessPath:  defaultString(cfg.Health.LivenessPath, "/livez"),
+			readinessPath: defaultString(cfg.Health.ReadinessPath, "/readyz"),
+			logger:        logger,
+		}
+		handler = health.wrap(handler)
+	}
 	hs := &http.Server{
 		Addr:              cfg.Addr,
 		Handler:           handler,
@@ -63,7 +186,79 @@ func NewServer(cfg ServerConfig, handler http.Handler, logger *slog.Logger) *Ser
 		ReadHeaderTimeout: defaultDur(cfg.ReadHeaderTimeout, 5*time.Second),
 		TLSConfig:         cfg.TLSConfig,
 	}
-	return &Server{HTTP: hs, Logger: logger}
+	hs.HTTP2 = http2Config(cfg.HTTP2, hs.WriteTimeout)
+	if cfg.H2C {
+		hs.Handler = h2c.NewHandler(hs.Handler, &http2.Server{
+			MaxConcurrentStreams: uint32(hs.HTTP2.MaxConcurrentStreams), // #nosec G115 -- stream limits are small
+			MaxReadFrameSize:     uint32(hs.HTTP2.MaxReadFrameSize),     // #nosec G115 -- frame sizes are capped at 16 MiB
+			IdleTimeout:          hs.IdleTimeout,
+			WriteByteTimeout:     hs.HTTP2.WriteByteTimeout,
+			CountError:           hs.HTTP2.CountError,
+		})
+	}
+	s := &Server{
+		HTTP:            hs,
+		Logger:          logger,
+		Health:          health,
+		ShutdownTimeout: defaultDur(cfg.ShutdownTimeout, 30*time.Second),
+		cfgErr:          cfgErr,
+	}
+	if manager != nil {
+		s.extra = append(s.extra, s.listener(defaultString(cfg.AutoTLS.HTTPAddr, ":80"), manager.HTTPHandler(nil), nil))
+	}
+	return s
+}
+
+// autoTLSManager returns an autocert manager for cfg that accepts the Let's
+// Encrypt terms of service.
+func autoTLSManager(cfg AutoTLSConfig) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Cache:      autocert.DirCache(defaultString(cfg.CacheDir, "autocert")),
+	}
+}
+
+// http2Config returns a copy of cfg with hardened defaults applied.
+func http2Config(cfg *http.HTTP2Config, writeTimeout time.Duration) *http.HTTP2Config {
+	var c http.HTTP2Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.MaxConcurrentStreams == 0 {
+		c.MaxConcurrentStreams = defaultMaxConcurrentStreams
+	}
+	if c.WriteByteTimeout == 0 {
+		c.WriteByteTimeout = writeTimeout
+	}
+	return &c
+}
+
+// SecureTLSConfig returns a hardened *tls.Config for ServerConfig.TLSConfig
+// or ListenerConfig.TLSConfig: TLS 1.2 minimum, only AEAD cipher suites with
+// forward secrecy for TLS 1.2 (TLS 1.3 suites are fixed by Go), hybrid
+// post-quantum and modern elliptic curves, and session tickets enabled.
+// Add Certificates or GetCertificate before use. Each call returns a new
+// config that may be modified freely.
+func SecureTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{
+			tls.X25519MLKEM768,
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+		SessionTicketsDisabled: false,
+	}
 }
 
 func defaultDur(v, def time.Duration) time.Duration {
@@ -73,25 +268,182 @@ func defaultDur(v, def time.Duration) time.Duration {
 	return v
 }
 
-// Start runs the server and listens for shutdown signals.
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// AddListener registers an additional listener that serves the same handler
+// with the same timeouts and HTTP/2 settings as the primary server, e.g. plain HTTP on :80
+// redirecting to HTTPS on :443. All listeners start with Start and stop
+// together on Shutdown or a shutdown signal. Call before Start.
+func (s *Server) AddListener(cfg ListenerConfig) {
+	handler := s.HTTP.Handler
+	if cfg.RedirectToHTTPS {
+		handler = httpsRedirect(cfg.RedirectPort)
+	}
+	s.extra = append(s.extra, s.listener(cfg.Addr, handler, cfg.TLSConfig))
+}
+
+// listener returns an http.Server for addr sharing the primary server's
+// timeouts and HTTP/2 settings.
+func (s *Server) listener(addr string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       s.HTTP.ReadTimeout,
+		WriteTimeout:      s.HTTP.WriteTimeout,
+		IdleTimeout:       s.HTTP.IdleTimeout,
+		ReadHeaderTimeout: s.HTTP.ReadHeaderTimeout,
+		TLSConfig:         tlsConfig,
+		HTTP2:             s.HTTP.HTTP2,
+	}
+}
+
+func httpsRedirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// servers returns the primary server followed by any additional listeners.
+func (s *Server) servers() []*http.Server {
+	return append([]*http.Server{s.HTTP}, s.extra...)
+}
+
+// Start runs the server and any additional listeners and listens for
+// shutdown signals. It blocks until every listener has stopped and, after a
+// signal, until the graceful shutdown has finished. If one listener fails,
+// the others are shut down. After a graceful shutdown it returns
+// http.ErrServerClosed.
 func (s *Server) Start() error {
+	return s.run(nil)
+}
+
+// Serve is like Start but runs the primary server on l instead of binding
+// HTTP.Addr, e.g. a listener inherited through systemd socket activation or
+// one opened on ":0" in tests. TLS is served on l when TLSConfig is set.
+// Additional listeners bind their own addresses. l is closed when Serve
+// returns.
+func (s *Server) Serve(l net.Listener) error {
+	defer func() { _ = l.Close() }()
+	return s.run(l)
+}
+
+// run starts every listener, serving the primary server on l when l is not
+// nil, and handles shutdown signals until all of them have stopped.
+func (s *Server) run(l net.Listener) error {
+	all := s.servers()
+	logger := loggerOrDefault(s.Logger)
+	if s.cfgErr != nil {
+		return s.cfgErr
+	}
+	for _, hs := range all {
+		if hs.TLSConfig != nil && len(hs.TLSConfig.Certificates) == 0 && hs.TLSConfig.GetCertificate == nil {
+			return errors.New("quokka: TLSConfig has no certificates and no GetCertificate function")
+		}
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	stop := make(chan struct{})
+	signalDone := make(chan struct{})
 	go func() {
-		ch := make(chan os.Signal, 1)
-		signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-		sig := <-ch
-		s.Logger.Info("shutdown signal received", slog.String("signal", sig.String()))
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		if err := s.HTTP.Shutdown(ctx); err != nil {
-			s.Logger.Error("shutdown error", slog.Any("err", err))
+		defer close(signalDone)
+		select {
+		case sig := <-sigCh:
+			logger.Info("shutdown signal received", slog.String("signal", sig.String()))
+			ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+			if err := s.Shutdown(ctx); err != nil {
+				logger.Error("shutdown error", slog.Any("err", err))
+			}
+		case <-stop:
 		}
 	}()
-	s.Logger.Info("server starting", slog.String("addr", s.HTTP.Addr))
-	if s.HTTP.TLSConfig != nil {
-		if len(s.HTTP.TLSConfig.Certificates) == 0 && s.HTTP.TLSConfig.GetCertificate == nil {
-			return errors.New("quokka: TLSConfig has no certificates and no GetCertificate function")
+	defer func() {
+		close(stop)
+		<-signalDone
+	}()
+
+	errCh := make(chan error, len(all))
+	for _, hs := range all {
+		if l != nil && hs == s.HTTP {
+			logger.Info("server starting", slog.String("addr", l.Addr().String()))
+			go func() {
+				if hs.TLSConfig != nil {
+					errCh <- hs.ServeTLS(l, "", "")
+					return
+				}
+				errCh <- hs.Serve(l)
+			}()
+			continue
+		}
+		logger.Info("server starting", slog.String("addr", hs.Addr))
+		go func(hs *http.Server) {
+			if hs.TLSConfig != nil {
+				errCh <- hs.ListenAndServeTLS("", "")
+				return
+			}
+			errCh <- hs.ListenAndServe()
+		}(hs)
+	}
+
+	var first error
+	for range all {
+		err := <-errCh
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && first == nil {
+			first = err
+			ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			_ = s.Shutdown(ctx)
+			cancel()
+		}
+	}
+	if first != nil {
+		return first
+	}
+	return http.ErrServerClosed
+}
+
+// OnShutdown registers fn to run during Shutdown after all listeners have
+// stopped, e.g. to close database pools or flush buffers. Hooks run once, in
+// registration order, with the Shutdown context.
+func (s *Server) OnShutdown(fn func(context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, fn)
+}
+
+// Shutdown gracefully stops the server and all additional listeners,
+// waiting for in-flight requests until ctx is done, then runs the OnShutdown
+// hooks. Errors from listeners and hooks are joined.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, hs := range s.servers() {
+		if err := hs.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
 		}
-		return s.HTTP.ListenAndServeTLS("", "")
 	}
-	return s.HTTP.ListenAndServe()
+	s.hooksOnce.Do(func() {
+		s.mu.Lock()
+		hooks := s.hooks
+		s.mu.Unlock()
+		var hookErrs []error
+		for _, fn := range hooks {
+			if err := fn(ctx); err != nil {
+				hookErrs = append(hookErrs, err)
+			}
+		}
+		s.hooksErr = errors.Join(hookErrs...)
+	})
+	return errors.Join(append(errs, s.hooksErr)...)
 }
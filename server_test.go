@@ -17,17 +17,33 @@
 package quokka_test
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"golang.org/x/net/http2"
 
 	q "github.com/jrgalyan/quokka"
 )
 
 var _ = Describe("Server", func() {
+	freeAddr := func() string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := l.Addr().String()
+		Expect(l.Close()).To(Succeed())
+		return addr
+	}
+
 	It("applies defaults when zero values provided", func() {
 		r := http.NewServeMux()
 		s := q.NewServer(q.ServerConfig{}, r, nil)
@@ -36,6 +52,84 @@ var _ = Describe("Server", func() {
 		Expect(s.HTTP.WriteTimeout).To(Equal(30 * time.Second))
 		Expect(s.HTTP.IdleTimeout).To(Equal(120 * time.Second))
 		Expect(s.HTTP.TLSConfig).To(BeNil())
+		Expect(s.ShutdownTimeout).To(Equal(30 * time.Second))
+		Expect(q.NewServer(q.ServerConfig{ShutdownTimeout: 5 * time.Second}, r, nil).ShutdownTimeout).To(Equal(5 * time.Second))
+	})
+
+	It("runs OnShutdown hooks once after the listeners stop and joins their errors", func() {
+		addr := freeAddr()
+		s := q.NewServer(q.ServerConfig{Addr: addr}, http.NewServeMux(), nil)
+		var ran []string
+		s.OnShutdown(func(context.Context) error {
+			_, err := net.Dial("tcp", addr)
+			Expect(err).To(HaveOccurred(), "listener still accepting during hook")
+			ran = append(ran, "flush")
+			return nil
+		})
+		hookErr := errors.New("pool close failed")
+		s.OnShutdown(func(context.Context) error { ran = append(ran, "db"); return hookErr })
+
+		done := make(chan error, 1)
+		go func() { done <- s.Start() }()
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				_ = conn.Close()
+			}
+			return err
+		}).Should(Succeed())
+
+		Expect(s.Shutdown(context.Background())).To(MatchError(hookErr))
+		Eventually(done).Should(Receive(MatchError(http.ErrServerClosed)))
+		Expect(ran).To(Equal([]string{"flush", "db"}))
+
+		Expect(s.Shutdown(context.Background())).To(MatchError(hookErr))
+		Expect(ran).To(HaveLen(2))
+	})
+
+	It("uses ShutdownTimeout when Start shuts down after a listener failure", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+
+		s := q.NewServer(q.ServerConfig{Addr: freeAddr(), ShutdownTimeout: 3 * time.Second}, http.NewServeMux(), nil)
+		s.AddListener(q.ListenerConfig{Addr: l.Addr().String()})
+		remaining := make(chan time.Duration, 1)
+		s.OnShutdown(func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			Expect(ok).To(BeTrue())
+			remaining <- time.Until(deadline)
+			return nil
+		})
+
+		Expect(s.Start()).NotTo(MatchError(http.ErrServerClosed))
+		Eventually(remaining).Should(Receive(BeNumerically("~", 3*time.Second, 500*time.Millisecond)))
+	})
+
+	It("falls back to slog.Default when the logger is nil", func() {
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		DeferCleanup(slog.SetDefault, prev)
+
+		s := q.NewServer(q.ServerConfig{Addr: freeAddr()}, http.NewServeMux(), nil)
+		Expect(s.Logger).NotTo(BeNil())
+
+		s.Logger = nil
+		done := make(chan error, 1)
+		go func() { done <- s.Start() }()
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", s.HTTP.Addr)
+			if err == nil {
+				_ = conn.Close()
+			}
+			return err
+		}).Should(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(s.Shutdown(ctx)).To(Succeed())
+		Eventually(done).Should(Receive(MatchError(http.ErrServerClosed)))
+		Expect(buf.String()).To(ContainSubstring("server starting"))
 	})
 
 	It("uses provided TLS config when set", func() {
@@ -80,9 +174,258 @@ var _ = Describe("Server", func() {
 		Expect(err.Error()).To(ContainSubstring("no certificates"))
 	})
 
+	It("returns a hardened TLS config from SecureTLSConfig", func() {
+		cfg := q.SecureTLSConfig()
+		Expect(cfg.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(cfg.SessionTicketsDisabled).To(BeFalse())
+		Expect(cfg.CurvePreferences).To(ContainElement(tls.X25519))
+
+		insecure := map[uint16]bool{}
+		for _, cs := range tls.InsecureCipherSuites() {
+			insecure[cs.ID] = true
+		}
+		Expect(cfg.CipherSuites).NotTo(BeEmpty())
+		for _, id := range cfg.CipherSuites {
+			Expect(insecure[id]).To(BeFalse(), tls.CipherSuiteName(id))
+			Expect(tls.CipherSuiteName(id)).To(HavePrefix("TLS_ECDHE_"))
+			Expect(tls.CipherSuiteName(id)).To(Or(ContainSubstring("GCM"), ContainSubstring("CHACHA20")))
+		}
+
+		// Each call returns an independent config.
+		cfg.MinVersion = tls.VersionTLS13
+		Expect(q.SecureTLSConfig().MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+	})
+
+	It("applies hardened HTTP/2 defaults without modifying the caller's config", func() {
+		s := q.NewServer(q.ServerConfig{}, http.NewServeMux(), nil)
+		Expect(s.HTTP.HTTP2).NotTo(BeNil())
+		Expect(s.HTTP.HTTP2.MaxConcurrentStreams).To(Equal(100))
+		Expect(s.HTTP.HTTP2.WriteByteTimeout).To(Equal(30 * time.Second))
+
+		h2 := &http.HTTP2Config{MaxConcurrentStreams: 8, CountError: func(string) {}}
+		s = q.NewServer(q.ServerConfig{HTTP2: h2}, http.NewServeMux(), nil)
+		Expect(s.HTTP.HTTP2.MaxConcurrentStreams).To(Equal(8))
+		Expect(s.HTTP.HTTP2.CountError).NotTo(BeNil())
+		Expect(h2.WriteByteTimeout).To(BeZero())
+	})
+
+	It("advertises the configured HTTP/2 stream limit", func() {
+		ts := httptest.NewUnstartedServer(nil)
+		ts.StartTLS()
+		certs := ts.TLS.Certificates
+		ts.Close()
+
+		addr := freeAddr()
+		s := q.NewServer(q.ServerConfig{
+			Addr:      addr,
+			TLSConfig: &tls.Config{Certificates: certs, MinVersion: tls.VersionTLS12},
+			HTTP2:     &http.HTTP2Config{MaxConcurrentStreams: 7},
+		}, http.NewServeMux(), nil)
+		done := make(chan error, 1)
+		go func() { done <- s.Start() }()
+		DeferCleanup(func() {
+			Expect(s.Shutdown(context.Background())).To(Succeed())
+			Eventually(done).Should(Receive())
+		})
+
+		var conn *tls.Conn
+		Eventually(func() (err error) {
+			conn, err = tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2"}}) // #nosec G402 -- test certificate
+			return err
+		}).Should(Succeed())
+		defer conn.Close()
+		Expect(conn.ConnectionState().NegotiatedProtocol).To(Equal("h2"))
+
+		_, err := io.WriteString(conn, http2.ClientPreface)
+		Expect(err).NotTo(HaveOccurred())
+		fr := http2.NewFramer(conn, conn)
+		Expect(fr.WriteSettings()).To(Succeed())
+		f, err := fr.ReadFrame()
+		Expect(err).NotTo(HaveOccurred())
+		sf, ok := f.(*http2.SettingsFrame)
+		Expect(ok).To(BeTrue())
+		v, ok := sf.Value(http2.SettingMaxConcurrentStreams)
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(uint32(7)))
+	})
+
+	It("serves HTTP/2 over plaintext when H2C is set", func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.Proto)
+		})
+		addr := freeAddr()
+		s := q.NewServer(q.ServerConfig{Addr: addr, H2C: true}, mux, nil)
+		done := make(chan error, 1)
+		go func() { done <- s.Start() }()
+		DeferCleanup(func() {
+			Expect(s.Shutdown(context.Background())).To(Succeed())
+			Eventually(done).Should(Receive())
+		})
+
+		client := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}}
+		var resp *http.Response
+		Eventually(func() (err error) {
+			resp, err = client.Get("http://" + addr + "/hello")
+			return err
+		}).Should(Succeed())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.ProtoMajor).To(Equal(2))
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(Equal("HTTP/2.0"))
+	})
+
+	It("refuses to start with both H2C and TLSConfig", func() {
+		s := q.NewServer(q.ServerConfig{Addr: freeAddr(), H2C: true, TLSConfig: q.SecureTLSConfig()}, http.NewServeMux(), nil)
+		Expect(s.Start()).To(MatchError(ContainSubstring("H2C")))
+	})
+
+	It("serves on a provided listener and shuts down gracefully", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) { _, _ = io.WriteString(w, "pong") })
+		s := q.NewServer(q.ServerConfig{Addr: "unused:0"}, mux, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		done := make(chan error, 1)
+		go func() { done <- s.Serve(l) }()
+
+		resp, err := http.Get("http://" + l.Addr().String() + "/ping")
+		Expect(err).NotTo(HaveOccurred())
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(body)).To(Equal("pong"))
+
+		Expect(s.Shutdown(context.Background())).To(Succeed())
+		Eventually(done).Should(Receive(MatchError(http.ErrServerClosed)))
+		_, err = net.Dial("tcp", l.Addr().String())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("wires autocert into the TLS config when AutoTLS is set", func() {
+		s := q.NewServer(q.ServerConfig{AutoTLS: &q.AutoTLSConfig{
+			Domains:  []string{"example.com"},
+			CacheDir: GinkgoT().TempDir(),
+		}}, http.NewServeMux(), nil)
+		Expect(s.HTTP.Addr).To(Equal(":443"))
+		Expect(s.HTTP.TLSConfig).NotTo(BeNil())
+		Expect(s.HTTP.TLSConfig.GetCertificate).NotTo(BeNil())
+		Expect(s.HTTP.TLSConfig.NextProtos).To(ContainElement("acme-tls/1"))
+		Expect(s.HTTP.TLSConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+
+		_, err := s.HTTP.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to start with both AutoTLS and TLSConfig", func() {
+		s := q.NewServer(q.ServerConfig{
+			Addr:      freeAddr(),
+			TLSConfig: q.SecureTLSConfig(),
+			AutoTLS:   &q.AutoTLSConfig{Domains: []string{"example.com"}},
+		}, http.NewServeMux(), nil)
+		Expect(s.Start()).To(MatchError(ContainSubstring("AutoTLS")))
+	})
+
 	It("creates logger when nil is provided", func() {
 		r := http.NewServeMux()
 		s := q.NewServer(q.ServerConfig{}, r, nil)
 		Expect(s.Logger).NotTo(BeNil())
 	})
+
+	Describe("multiple listeners", func() {
+		It("serves HTTPS and redirects HTTP, then shuts down together", func() {
+			ts := httptest.NewUnstartedServer(nil)
+			ts.StartTLS()
+			certs := ts.TLS.Certificates
+			client := ts.Client()
+			ts.Close()
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/hi", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("hello")) })
+
+			httpsAddr, httpAddr := freeAddr(), freeAddr()
+			_, httpsPort, _ := net.SplitHostPort(httpsAddr)
+			s := q.NewServer(q.ServerConfig{Addr: httpsAddr, TLSConfig: &tls.Config{Certificates: certs, MinVersion: tls.VersionTLS12}}, mux, nil)
+			s.AddListener(q.ListenerConfig{Addr: httpAddr, RedirectToHTTPS: true, RedirectPort: httpsPort})
+
+			done := make(chan error, 1)
+			go func() { done <- s.Start() }()
+
+			Eventually(func() error {
+				resp, err := client.Get("https://" + httpsAddr + "/hi")
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				return err
+			}).Should(Succeed())
+
+			resp, err := client.Get("http://" + httpAddr + "/hi?x=1")
+			Expect(err).NotTo(HaveOccurred())
+			_ = resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusPermanentRedirect))
+			Expect(resp.Header.Get("Location")).To(Equal("https://127.0.0.1:" + httpsPort + "/hi?x=1"))
+
+			resp, err = client.Get(resp.Header.Get("Location"))
+			Expect(err).NotTo(HaveOccurred())
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			Expect(string(body)).To(Equal("hello"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			Expect(s.Shutdown(ctx)).To(Succeed())
+			Eventually(done).Should(Receive(MatchError(http.ErrServerClosed)))
+
+			_, err = client.Get("https://" + httpsAddr + "/hi")
+			Expect(err).To(HaveOccurred())
+			_, err = client.Get("http://" + httpAddr + "/hi")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("serves the handler on a plain additional listener", func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+			primary, secondary := freeAddr(), freeAddr()
+			s := q.NewServer(q.ServerConfig{Addr: primary}, mux, nil)
+			s.AddListener(q.ListenerConfig{Addr: secondary})
+
+			done := make(chan error, 1)
+			go func() { done <- s.Start() }()
+			for _, addr := range []string{primary, secondary} {
+				Eventually(func() (int, error) {
+					resp, err := http.Get("http://" + addr + "/ok")
+					if err != nil {
+						return 0, err
+					}
+					_ = resp.Body.Close()
+					return resp.StatusCode, nil
+				}).Should(Equal(http.StatusNoContent))
+			}
+
+			Expect(s.Shutdown(context.Background())).To(Succeed())
+			Eventually(done).Should(Receive(MatchError(http.ErrServerClosed)))
+		})
+
+		It("returns a listener error and stops the others", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer l.Close()
+
+			s := q.NewServer(q.ServerConfig{Addr: freeAddr()}, http.NewServeMux(), nil)
+			s.AddListener(q.ListenerConfig{Addr: l.Addr().String()})
+			done := make(chan error, 1)
+			go func() { done <- s.Start() }()
+			var startErr error
+			Eventually(done).Should(Receive(&startErr))
+			Expect(startErr).NotTo(MatchError(http.ErrServerClosed))
+		})
+	})
 })
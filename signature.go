@@ -0,0 +1,228 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NonceStore records nonces seen by the Signature middleware. Implementations
+// must be safe for concurrent use.
+type NonceStore interface {
+	// Seen atomically records nonce for ttl and reports whether it was
+	// already present.
+	Seen(nonce string, ttl time.Duration) bool
+}
+
+// SignatureConfig configures the Signature middleware.
+type SignatureConfig struct {
+	// Secret is the shared HMAC-SHA256 key (required).
+	Secret []byte
+
+	// Header carries the hex-encoded signature, optionally prefixed with
+	// "sha256=". Default: "X-Signature".
+	Header string
+
+	// MaxSkew enables timestamp checking: requests must carry a Unix-seconds
+	// timestamp in TimestampHeader no further than MaxSkew from the server
+	// clock. Default: 0 (disabled).
+	MaxSkew time.Duration
+
+	// TimestampHeader carries the request timestamp. Default: "X-Timestamp".
+	TimestampHeader string
+
+	// NonceStore enables replay protection: requests must carry a nonce in
+	// NonceHeader that has not been seen within NonceTTL. Without MaxSkew the
+	// protection is only time-limited: a captured request can be replayed
+	// once its nonce is forgotten. Set MaxSkew so that NonceTTL outlasts the
+	// window in which the request is accepted. Default: nil (disabled).
+	NonceStore NonceStore
+
+	// NonceHeader carries the request nonce. Default: "X-Nonce".
+	NonceHeader string
+
+	// NonceTTL is how long nonces are remembered. Default: twice MaxSkew, or
+	// 10 minutes when MaxSkew is disabled.
+	NonceTTL time.Duration
+
+	// MaxBodySize is the largest body, in bytes, that is read for
+	// verification. Default: 1 MB.
+	MaxBodySize int
+}
+
+// Signature creates a middleware that verifies HMAC-SHA256 signed requests.
+// The signed message is the request body, prefixed by "<timestamp>." when
+// MaxSkew is set and then by "<nonce>." when NonceStore is set, so both values
+// are covered by the signature. A bad or missing signature, a timestamp
+// outside the skew window, or a reused nonce responds 401, as does a timestamp
+// or nonce containing "."; an oversized body responds 413. Nonces are recorded only after the signature verifies.
+func Signature(cfg SignatureConfig) Middleware {
+	if cfg.Header == "" {
+		cfg.Header = "X-Signature"
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = "X-Timestamp"
+	}
+	if cfg.NonceHeader == "" {
+		cfg.NonceHeader = "X-Nonce"
+	}
+	if cfg.NonceTTL <= 0 {
+		cfg.NonceTTL = 2 * cfg.MaxSkew
+		if cfg.NonceTTL <= 0 {
+			cfg.NonceTTL = 10 * time.Minute
+		}
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	return func(next Handler) Handler {
+		return func(c *Context) {
+			sig, err := hex.DecodeString(strings.TrimPrefix(c.R.Header.Get(cfg.Header), "sha256="))
+			if err != nil || len(sig) == 0 {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "missing or malformed signature"})
+				return
+			}
+
+			// The fields are joined with ".", so a "." inside one would let a
+			// captured request be re-signed by shifting the boundary between
+			// nonce and body.
+			mac := hmac.New(sha256.New, cfg.Secret)
+			ts := c.R.Header.Get(cfg.TimestampHeader)
+			if cfg.MaxSkew > 0 {
+				if strings.Contains(ts, ".") {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "missing or malformed timestamp"})
+					return
+				}
+				mac.Write([]byte(ts + "."))
+			}
+			nonce := c.R.Header.Get(cfg.NonceHeader)
+			if cfg.NonceStore != nil {
+				if strings.Contains(nonce, ".") {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "malformed nonce"})
+					return
+				}
+				mac.Write([]byte(nonce + "."))
+			}
+			if c.R.Body != nil && c.R.Body != http.NoBody {
+				body, err := io.ReadAll(io.LimitReader(c.R.Body, int64(cfg.MaxBodySize)+1))
+				_ = c.R.Body.Close()
+				if err != nil {
+					c.JSON(http.StatusBadRequest, ErrorResponse{Error: "bad request", Message: "failed to read body"})
+					return
+				}
+				if len(body) > cfg.MaxBodySize {
+					c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+					return
+				}
+				mac.Write(body)
+				c.R.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			if !hmac.Equal(sig, mac.Sum(nil)) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "invalid signature"})
+				return
+			}
+
+			if cfg.MaxSkew > 0 {
+				sec, err := strconv.ParseInt(ts, 10, 64)
+				if err != nil {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "missing or malformed timestamp"})
+					return
+				}
+				if skew := time.Since(time.Unix(sec, 0)); skew > cfg.MaxSkew || skew < -cfg.MaxSkew {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "request timestamp outside allowed window"})
+					return
+				}
+			}
+			if cfg.NonceStore != nil {
+				if nonce == "" {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "missing nonce"})
+					return
+				}
+				if cfg.NonceStore.Seen(nonce, cfg.NonceTTL) {
+					c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized", Message: "replayed request"})
+					return
+				}
+			}
+			next(c)
+		}
+	}
+}
+
+// MemoryNonceStore is an in-memory NonceStore bounded by entry count.
+// Expired nonces are dropped lazily. When the store is full of unexpired
+// nonces it fails closed, reporting every new nonce as seen, because
+// evicting a live nonce would let it be replayed.
+type MemoryNonceStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]time.Time
+	nextExpiry time.Time // earliest expiry after the last purge
+}
+
+// NewMemoryNonceStore creates a MemoryNonceStore holding at most maxEntries
+// nonces. A maxEntries of 0 or less defaults to 100000.
+func NewMemoryNonceStore(maxEntries int) *MemoryNonceStore {
+	if maxEntries <= 0 {
+		maxEntries = 100000
+	}
+	return &MemoryNonceStore{maxEntries: maxEntries, entries: make(map[string]time.Time)}
+}
+
+// Seen records nonce for ttl and reports whether an unexpired entry already
+// existed.
+func (s *MemoryNonceStore) Seen(nonce string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if exp, ok := s.entries[nonce]; ok && now.Before(exp) {
+		return true
+	}
+	if len(s.entries) >= s.maxEntries {
+		// Nothing can have expired before nextExpiry, so skip the scan.
+		if now.Before(s.nextExpiry) {
+			return true
+		}
+		s.nextExpiry = time.Time{}
+		for k, exp := range s.entries {
+			if !now.Before(exp) {
+				delete(s.entries, k)
+				continue
+			}
+			if s.nextExpiry.IsZero() || exp.Before(s.nextExpiry) {
+				s.nextExpiry = exp
+			}
+		}
+		if len(s.entries) >= s.maxEntries {
+			return true
+		}
+	}
+	exp := now.Add(ttl)
+	if exp.Before(s.nextExpiry) {
+		s.nextExpiry = exp
+	}
+	s.entries[nonce] = exp
+	return false
+}
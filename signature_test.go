@@ -0,0 +1,150 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("Signature Middleware", func() {
+	secret := []byte("shh")
+	sign := func(parts ...string) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(strings.Join(parts, "")))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	newRouter := func(cfg q.SignatureConfig) *q.Router {
+		cfg.Secret = secret
+		r := q.New()
+		r.Use(q.Signature(cfg))
+		r.POST("/hook", func(c *q.Context) {
+			b, _ := io.ReadAll(c.R.Body)
+			c.Text(http.StatusOK, string(b))
+		})
+		return r
+	}
+	post := func(r *q.Router, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	It("accepts a valid body signature and restores the body", func() {
+		rr := post(newRouter(q.SignatureConfig{}), "payload", map[string]string{"X-Signature": sign("payload")})
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(rr.Body.String()).To(Equal("payload"))
+	})
+
+	It("rejects a tampered body or missing signature with 401", func() {
+		r := newRouter(q.SignatureConfig{})
+		rr := post(r, "tampered", map[string]string{"X-Signature": sign("payload")})
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(ContainSubstring("invalid signature"))
+		Expect(post(r, "payload", nil).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects an expired timestamp", func() {
+		r := newRouter(q.SignatureConfig{MaxSkew: time.Minute})
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		Expect(post(r, "x", map[string]string{"X-Timestamp": now, "X-Signature": sign(now, ".", "x")}).Code).To(Equal(http.StatusOK))
+
+		old := strconv.FormatInt(time.Now().Add(-5*time.Minute).Unix(), 10)
+		rr := post(r, "x", map[string]string{"X-Timestamp": old, "X-Signature": sign(old, ".", "x")})
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(ContainSubstring("outside allowed window"))
+	})
+
+	It("covers the timestamp with the signature", func() {
+		r := newRouter(q.SignatureConfig{MaxSkew: time.Minute})
+		old := strconv.FormatInt(time.Now().Add(-5*time.Minute).Unix(), 10)
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		rr := post(r, "x", map[string]string{"X-Timestamp": now, "X-Signature": sign(old, ".", "x")})
+		Expect(rr.Body.String()).To(ContainSubstring("invalid signature"))
+	})
+
+	It("rejects a replayed nonce", func() {
+		r := newRouter(q.SignatureConfig{NonceStore: q.NewMemoryNonceStore(0)})
+		h := map[string]string{"X-Nonce": "n-1", "X-Signature": sign("n-1", ".", "x")}
+		Expect(post(r, "x", h).Code).To(Equal(http.StatusOK))
+		rr := post(r, "x", h)
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(ContainSubstring("replayed request"))
+
+		Expect(post(r, "x", map[string]string{"X-Signature": sign(".", "x")}).Body.String()).To(ContainSubstring("missing nonce"))
+	})
+
+	It("rejects a nonce that shifts the boundary into the body", func() {
+		r := newRouter(q.SignatureConfig{NonceStore: q.NewMemoryNonceStore(0)})
+		sig := sign("n1", ".", "x.rest")
+		Expect(post(r, "x.rest", map[string]string{"X-Nonce": "n1", "X-Signature": sig}).Code).To(Equal(http.StatusOK))
+		rr := post(r, "rest", map[string]string{"X-Nonce": "n1.x", "X-Signature": sig})
+		Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rr.Body.String()).To(ContainSubstring("malformed nonce"))
+	})
+
+	It("does not record nonces from requests with a bad signature", func() {
+		r := newRouter(q.SignatureConfig{NonceStore: q.NewMemoryNonceStore(0)})
+		Expect(post(r, "x", map[string]string{"X-Nonce": "n-2", "X-Signature": sign("bogus")}).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(r, "x", map[string]string{"X-Nonce": "n-2", "X-Signature": sign("n-2", ".", "x")}).Code).To(Equal(http.StatusOK))
+	})
+})
+
+var _ = Describe("MemoryNonceStore", func() {
+	It("forgets nonces after their TTL", func() {
+		s := q.NewMemoryNonceStore(0)
+		Expect(s.Seen("a", 20*time.Millisecond)).To(BeFalse())
+		Expect(s.Seen("a", 20*time.Millisecond)).To(BeTrue())
+		time.Sleep(30 * time.Millisecond)
+		Expect(s.Seen("a", 20*time.Millisecond)).To(BeFalse())
+	})
+
+	It("fails closed when full of unexpired nonces", func() {
+		s := q.NewMemoryNonceStore(2)
+		Expect(s.Seen("a", time.Minute)).To(BeFalse())
+		Expect(s.Seen("b", 2*time.Minute)).To(BeFalse())
+		Expect(s.Seen("c", time.Minute)).To(BeTrue())
+		Expect(s.Seen("a", time.Minute)).To(BeTrue())
+		Expect(s.Seen("b", time.Minute)).To(BeTrue())
+	})
+
+	It("makes room once nonces expire", func() {
+		s := q.NewMemoryNonceStore(2)
+		Expect(s.Seen("a", time.Minute)).To(BeFalse())
+		Expect(s.Seen("b", 20*time.Millisecond)).To(BeFalse())
+		Expect(s.Seen("c", time.Minute)).To(BeTrue())
+		time.Sleep(30 * time.Millisecond)
+		Expect(s.Seen("c", time.Minute)).To(BeFalse())
+		Expect(s.Seen("a", time.Minute)).To(BeTrue())
+	})
+})
@@ -0,0 +1,147 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// NDJSONWriter streams newline-delimited JSON records. Obtain one with
+// Context.NDJSON.
+type NDJSONWriter struct {
+	c   *Context
+	enc *json.Encoder
+	rc  *http.ResponseController
+}
+
+// NDJSON starts a streaming response with Content-Type application/x-ndjson
+// and the given status code. Each call to Encode on the returned writer emits
+// one JSON object on its own line and flushes it to the client.
+func (c *Context) NDJSON(code int) *NDJSONWriter {
+	if !c.wrote {
+		c.W.Header().Set("Content-Type", "application/x-ndjson")
+		c.W.Header().Del("Content-Length")
+		c.status = code
+		c.W.WriteHeader(code)
+		c.wrote = true
+	}
+	return &NDJSONWriter{c: c, enc: json.NewEncoder(c.W), rc: http.NewResponseController(c.W)}
+}
+
+// Encode writes v as a single JSON line and flushes it. It returns the
+// request context's error without writing once the client has gone away or
+// the request has timed out.
+func (w *NDJSONWriter) Encode(v any) error {
+	if err := w.c.R.Context().Err(); err != nil {
+		return err
+	}
+	if err := w.enc.Encode(v); err != nil {
+		return err
+	}
+	if err := w.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// errNoFlusher is returned by SSE when the response cannot be flushed.
+var errNoFlusher = errors.New("quokka: response writer does not support flushing")
+
+// errNotEventStream is returned by SSE when another response was started.
+var errNotEventStream = errors.New("quokka: response already started and is not an event stream")
+
+// SSE writes one Server-Sent Events message and flushes it. The first call
+// sets Content-Type text/event-stream and Cache-Control no-cache and sends a
+// 200 status. A string data is written as-is; any other value is marshaled to
+// JSON. Multi-line data is split across data: fields. An empty event omits
+// the event: field. SSE returns an error without writing when the writer
+// cannot flush or a non-SSE response was already started, and returns the
+// request context's error once it is done; the handler should return to close
+// the stream.
+func (c *Context) SSE(event string, data any) error {
+	if err := c.R.Context().Err(); err != nil {
+		return err
+	}
+	if c.wrote && !strings.HasPrefix(c.W.Header().Get("Content-Type"), "text/event-stream") {
+		return errNotEventStream
+	}
+	if !c.wrote && !canFlush(c.W) {
+		return errNoFlusher
+	}
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+	if !c.wrote {
+		h := c.W.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Del("Content-Length")
+		c.status = http.StatusOK
+		c.W.WriteHeader(http.StatusOK)
+		c.wrote = true
+	}
+	var sb strings.Builder
+	if event != "" {
+		sb.WriteString("event: " + strings.NewReplacer("\r", "", "\n", "").Replace(event) + "\n")
+	}
+	// A bare CR also ends a line in SSE, so normalize it too; otherwise data
+	// could smuggle in extra fields.
+	payload = strings.ReplaceAll(strings.ReplaceAll(payload, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(payload, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	if _, err := c.W.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	if err := http.NewResponseController(c.W).Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return errNoFlusher
+		}
+		return err
+	}
+	return nil
+}
+
+// canFlush reports whether http.ResponseController can flush w. It walks w's
+// Unwrap chain and asks the innermost writer, since the wrappers in this
+// package implement Flush even when the writer beneath them cannot.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	switch w.(type) {
+	case http.Flusher, interface{ FlushError() error }:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+ *    Copyright 2025 Jeff Galyan
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package quokka_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	q "github.com/jrgalyan/quokka"
+)
+
+var _ = Describe("NDJSON", func() {
+	type event struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	It("streams one JSON object per line", func() {
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			w := c.NDJSON(http.StatusOK)
+			for i, n := range []string{"a", "b", "c"} {
+				Expect(w.Encode(event{ID: i + 1, Name: n})).To(Succeed())
+			}
+		})
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/events")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+
+		var got []event
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			var e event
+			Expect(json.Unmarshal(sc.Bytes(), &e)).To(Succeed())
+			got = append(got, e)
+		}
+		Expect(sc.Err()).NotTo(HaveOccurred())
+		Expect(got).To(Equal([]event{{1, "a"}, {2, "b"}, {3, "c"}}))
+	})
+
+	It("flushes each record", func() {
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			Expect(c.NDJSON(http.StatusOK).Encode(event{ID: 1})).To(Succeed())
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(rr.Flushed).To(BeTrue())
+		Expect(rr.Body.String()).To(Equal(`{"id":1,"name":""}` + "\n"))
+	})
+
+	It("stops encoding once the request context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		var encErr error
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			w := c.NDJSON(http.StatusOK)
+			Expect(w.Encode(event{ID: 1})).To(Succeed())
+			cancel()
+			encErr = w.Encode(event{ID: 2})
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+		Expect(encErr).To(MatchError(context.Canceled))
+		Expect(rr.Body.String()).To(Equal(`{"id":1,"name":""}` + "\n"))
+	})
+})
+
+var _ = Describe("SSE", func() {
+	readEvents := func(body *bufio.Reader, n int) []string {
+		var events []string
+		var cur strings.Builder
+		for len(events) < n {
+			line, err := body.ReadString('\n')
+			Expect(err).NotTo(HaveOccurred())
+			if line == "\n" {
+				events = append(events, cur.String())
+				cur.Reset()
+				continue
+			}
+			cur.WriteString(line)
+		}
+		return events
+	}
+
+	It("streams several events with SSE framing", func() {
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			Expect(c.SSE("greeting", "hello")).To(Succeed())
+			Expect(c.SSE("update", map[string]int{"n": 1})).To(Succeed())
+			Expect(c.SSE("", "line1\nline2")).To(Succeed())
+		})
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/events")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		Expect(resp.Header.Get("Cache-Control")).To(Equal("no-cache"))
+		Expect(resp.Header.Get("Connection")).To(BeEmpty())
+
+		events := readEvents(bufio.NewReader(resp.Body), 3)
+		Expect(events).To(Equal([]string{
+			"event: greeting\ndata: hello\n",
+			"event: update\ndata: {\"n\":1}\n",
+			"data: line1\ndata: line2\n",
+		}))
+	})
+
+	It("delivers events promptly through the Gzip middleware", func() {
+		release := make(chan struct{})
+		r := q.New()
+		r.Use(q.Gzip(q.GzipConfig{}))
+		r.GET("/events", func(c *q.Context) {
+			Expect(c.SSE("tick", "1")).To(Succeed())
+			<-release
+		})
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		defer close(release)
+
+		resp, err := http.Get(srv.URL + "/events")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		events := readEvents(bufio.NewReader(resp.Body), 1)
+		Expect(events).To(Equal([]string{"event: tick\ndata: 1\n"}))
+	})
+
+	It("returns an error when the writer cannot flush", func() {
+		var sseErr error
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			sseErr = c.SSE("x", "y")
+			c.JSON(http.StatusInternalServerError, q.ErrorResponse{Error: "no streaming"})
+		})
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(struct{ http.ResponseWriter }{rr}, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(sseErr).To(HaveOccurred())
+		Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rr.Header().Get("Content-Type")).NotTo(Equal("text/event-stream"))
+	})
+
+	It("refuses to append events to a non-SSE response", func() {
+		var sseErr error
+		rr := httptest.NewRecorder()
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			c.Text(http.StatusOK, "plain")
+			sseErr = c.SSE("x", "y")
+		})
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(sseErr).To(HaveOccurred())
+		Expect(rr.Body.String()).To(Equal("plain"))
+	})
+
+	It("flushes through writers that only expose Unwrap", func() {
+		rr := httptest.NewRecorder()
+		r := q.New()
+		r.GET("/events", func(c *q.Context) { Expect(c.SSE("x", "y")).To(Succeed()) })
+		r.ServeHTTP(unwrapOnlyWriter{rr}, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(rr.Flushed).To(BeTrue())
+	})
+
+	It("keeps a bare CR in SSE data from adding fields", func() {
+		rr := httptest.NewRecorder()
+		r := q.New()
+		r.GET("/events", func(c *q.Context) {
+			Expect(c.SSE("", "x\revent: admin\rid: 9\r\ny")).To(Succeed())
+		})
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(rr.Body.String()).To(Equal("data: x\ndata: event: admin\ndata: id: 9\ndata: y\n\n"))
+	})
+})
+
+// unwrapOnlyWriter hides every optional interface of the wrapped writer
+// except through Unwrap.
+type unwrapOnlyWriter struct{ w http.ResponseWriter }
+
+func (u unwrapOnlyWriter) Header() http.Header         { return u.w.Header() }
+func (u unwrapOnlyWriter) Write(b []byte) (int, error) { return u.w.Write(b) }
+func (u unwrapOnlyWriter) WriteHeader(code int)        { u.w.WriteHeader(code) }
+func (u unwrapOnlyWriter) Unwrap() http.ResponseWriter { return u.w }